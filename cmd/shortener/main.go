@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -12,16 +15,31 @@ import (
 	"github.com/gleb-korostelev/short-url.git/internal/cache"
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/jobs"
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
+	"github.com/gleb-korostelev/short-url.git/internal/middleware"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
+	"github.com/gleb-korostelev/short-url.git/internal/service/grpchandler"
 	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
 	"github.com/gleb-korostelev/short-url.git/internal/service/router"
 	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/cached"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/deadline"
 	"github.com/gleb-korostelev/short-url.git/internal/storage/filecache"
 	"github.com/gleb-korostelev/short-url.git/internal/storage/inmemory"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/instrumented"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/kvstore"
 	"github.com/gleb-korostelev/short-url.git/internal/storage/repository"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/screened"
+	"github.com/gleb-korostelev/short-url.git/internal/throttle"
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
 	"github.com/gleb-korostelev/short-url.git/internal/worker"
 	"github.com/gleb-korostelev/short-url.git/tools/logger"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -36,19 +54,76 @@ func main() {
 	fmt.Printf("Build commit: %s\n", buildCommit)
 
 	config.ConfigInit()
+
+	// Positional arguments after the flags select a subcommand instead of starting the server.
+	if args := flag.Args(); len(args) > 0 {
+		if err := runCommand(args); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log, _ := zap.NewProduction()
+	reg := metrics.NewRegistry()
+
+	tracer, err := tracingInit()
+	if err != nil {
+		logger.Errorf("Failed to initialize tracing: %v", err)
+		return
+	}
+	if tracer != nil {
+		tracing.SetTracer(tracer)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), config.TraceExportTimeout)
+			defer cancel()
+			if err := tracer.Shutdown(ctx); err != nil {
+				logger.Errorf("Failed to flush spans: %v", err)
+			}
+		}()
+	}
 
-	store, err := storageInit()
+	store, queue, err := storageInit()
 	if err != nil {
 		return
 	}
+	defer queue.Close()
+	store = deadline.NewDeadlineStorage(store, config.StorageReadTimeout, config.StorageWriteTimeout)
+	store = instrumented.NewInstrumentedStorage(store, storageBackend(), reg)
+	store, err = cacheInit(store)
+	if err != nil {
+		logger.Errorf("Failed to initialize cache: %v", err)
+		store.Close()
+		return
+	}
 	defer store.Close()
+	urlPolicy, err := policyInit()
+	if err != nil {
+		logger.Errorf("Failed to initialize URL policy: %v", err)
+		return
+	}
+	store = screened.NewScreenedStorage(store, urlPolicy)
 
+	// The deleter is shut down after the pool, so that jobs still running can flush their deletions.
+	deleter := worker.NewBatchDeleter(store, config.DeleteBufferSize, config.DeleteBatchSize, config.DeleteFlushInterval)
+	defer deleter.Shutdown()
 	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
 	defer workerPool.Shutdown()
-	svc := handler.NewAPIService(store, workerPool)
+	workerPool.RegisterMetrics(reg)
+	clickRecorder := worker.NewClickRecorder(workerPool, store, config.ClickBufferSize, config.ClickBatchSize, config.ClickFlushInterval)
+	deletionJobs := worker.NewDeletionJobs(workerPool, queue, deleter, config.JobPollInterval,
+		config.JobMaxAttempts, config.JobRetryBaseDelay, config.JobRetryMaxDelay)
+	// The HTTP and gRPC APIs share the wrong password attempts of every protected link.
+	passwords := throttle.NewLimiter(config.PasswordMaxAttempts, config.PasswordAttemptWindow)
+	svc := handler.NewAPIService(store, workerPool, handler.WithClickRecorder(clickRecorder), handler.WithDeletionJobs(deletionJobs),
+		handler.WithPolicy(urlPolicy), handler.WithPasswordLimiter(passwords))
 
-	r := router.RouterInit(svc, log)
+	limits, err := rateLimitsInit()
+	if err != nil {
+		logger.Errorf("Failed to parse rate limits: %v", err)
+		return
+	}
+	r := router.RouterInit(svc, log, limits, reg)
 
 	// go func() {
 	// 	logger.Infof("Starting pprof server on :6060")
@@ -64,6 +139,24 @@ func main() {
 
 	g, gCtx := errgroup.WithContext(ctx)
 
+	sweeper := worker.NewExpirySweeper(workerPool, store, config.ExpirySweepInterval)
+	g.Go(func() error {
+		sweeper.Run(gCtx)
+		return nil
+	})
+	g.Go(func() error {
+		clickRecorder.Run(gCtx)
+		return nil
+	})
+	g.Go(func() error {
+		deletionJobs.Run(gCtx)
+		return nil
+	})
+	g.Go(func() error {
+		urlPolicy.Run(gCtx)
+		return nil
+	})
+
 	if config.EnableHTTPS {
 		logger.Infof("Starting HTTPS server on %s\n", config.ServerAddr)
 		g.Go(func() error { return server.ListenAndServeTLS(config.CertFilePath, config.KeyFilePath) })
@@ -80,27 +173,223 @@ func main() {
 			return server.Shutdown(context.Background())
 		})
 	}
+	var grpcOpts []grpc.ServerOption
+	if config.EnableHTTPS {
+		creds, err := credentials.NewServerTLSFromFile(config.CertFilePath, config.KeyFilePath)
+		if err != nil {
+			logger.Errorf("Failed to load gRPC TLS credentials: %v", err)
+			return
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(creds))
+	}
+	grpcService := grpchandler.NewShortenerService(store, workerPool, grpchandler.WithDeletionJobs(deletionJobs),
+		grpchandler.WithPasswordLimiter(passwords))
+	grpcServer := grpchandler.NewServer(grpcService, grpcOpts...)
+	listener, err := net.Listen("tcp", config.GRPCServerAddr)
+	if err != nil {
+		logger.Errorf("Failed to listen on %s: %v", config.GRPCServerAddr, err)
+		return
+	}
+	logger.Infof("Starting gRPC server on %s\n", config.GRPCServerAddr)
+	g.Go(func() error { return grpcServer.Serve(listener) })
+	g.Go(func() error {
+		<-gCtx.Done()
+		grpcServer.GracefulStop()
+		return nil
+	})
+
 	if err := g.Wait(); err != nil {
 		logger.Infof("Exit with: %v\n", err)
 	}
 }
 
-func storageInit() (storage.Storage, error) {
-	if config.DBDSN != "" {
+// runCommand dispatches a subcommand given as positional arguments.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// storageBackend names the storage backend selected by the configuration: the database if a DSN
+// is set, otherwise the SQLite database, the key-value storage or the file if their path is set,
+// otherwise memory.
+func storageBackend() string {
+	switch {
+	case config.DBDSN != "":
+		return "postgres"
+	case config.SQLitePath != "":
+		return "sqlite"
+	case config.KVFilePath != "":
+		return "kv"
+	case config.BaseFilePath != "":
+		return "file"
+	default:
+		return "memory"
+	}
+}
+
+// storageInit creates the storage backend selected by storageBackend along with the queue of
+// deletion jobs kept next to it: in the database, in a journal beside the storage or snapshot file,
+// or in memory.
+func storageInit() (storage.Storage, jobs.Queue, error) {
+	switch storageBackend() {
+	case "postgres":
 		database, err := dbimpl.InitDB()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		store := repository.NewDBStorage(database)
 		logger.Infof("Using database storage")
-		return store, nil
-	} else if config.BaseFilePath != "" {
-		store := filecache.NewFileStorage(config.BaseFilePath)
+		return store, jobs.NewDBQueue(database, config.JobLease), nil
+	case "sqlite":
+		queue, err := jobs.OpenJournalQueue(config.SQLitePath+config.JobJournalSuffix, config.JobLease)
+		if err != nil {
+			logger.Errorf("Failed to open job journal: %v", err)
+			return nil, nil, err
+		}
+		database, err := dbimpl.InitSQLite(config.SQLitePath)
+		if err != nil {
+			logger.Errorf("Failed to open SQLite storage: %v", err)
+			queue.Close()
+			return nil, nil, err
+		}
+		store := repository.NewDBStorage(database)
+		logger.Infof("Using SQLite storage with file %s", config.SQLitePath)
+		return store, queue, nil
+	case "kv":
+		queue, err := jobs.OpenJournalQueue(config.KVFilePath+config.JobJournalSuffix, config.JobLease)
+		if err != nil {
+			logger.Errorf("Failed to open job journal: %v", err)
+			return nil, nil, err
+		}
+		store, err := kvstore.NewKVStorage(config.KVFilePath)
+		if err != nil {
+			logger.Errorf("Failed to open key-value storage: %v", err)
+			queue.Close()
+			return nil, nil, err
+		}
+		logger.Infof("Using key-value storage with file %s", config.KVFilePath)
+		return store, queue, nil
+	case "file":
+		queue, err := jobs.OpenJournalQueue(config.BaseFilePath+config.JobJournalSuffix, config.JobLease)
+		if err != nil {
+			logger.Errorf("Failed to open job journal: %v", err)
+			return nil, nil, err
+		}
+		store, err := filecache.NewFileStorage(config.BaseFilePath, config.FileSync)
+		if err != nil {
+			logger.Errorf("Failed to open file storage: %v", err)
+			queue.Close()
+			return nil, nil, err
+		}
 		logger.Infof("Using file storage with base file path %s", config.BaseFilePath)
+		return store, queue, nil
+	default:
+		if config.MemorySnapshot == "" {
+			store := inmemory.NewMemoryStorage(make(map[string]models.URLData))
+			logger.Infof("Using inmemory storage")
+			return store, jobs.NewMemoryQueue(config.JobLease), nil
+		}
+		queue, err := jobs.OpenJournalQueue(config.MemorySnapshot+config.JobJournalSuffix, config.JobLease)
+		if err != nil {
+			logger.Errorf("Failed to open job journal: %v", err)
+			return nil, nil, err
+		}
+		store, err := inmemory.OpenMemoryStorage(config.MemorySnapshot, config.SnapshotInterval, config.JournalWindow)
+		if err != nil {
+			logger.Errorf("Failed to open in-memory storage snapshot: %v", err)
+			queue.Close()
+			return nil, nil, err
+		}
+		logger.Infof("Using inmemory storage with snapshot %s", config.MemorySnapshot)
+		return store, queue, nil
+	}
+}
+
+// policyInit creates the URL policy with the entries of config.PolicyFile in config.PolicyMode.
+func policyInit() (*policy.Engine, error) {
+	if config.PolicyAction != config.PolicyActionBlock && config.PolicyAction != config.PolicyActionWarn {
+		return nil, fmt.Errorf("%w: action %q", config.ErrUnknownPolicyMode, config.PolicyAction)
+	}
+	engine, err := policy.NewEngine(config.PolicyFile, config.PolicyMode, config.PolicyReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	if config.PolicyFile != "" {
+		logger.Infof("Using URL %s with %d entries from %s", config.PolicyMode, len(engine.Entries()), config.PolicyFile)
+	}
+	return engine, nil
+}
+
+// cacheInit wraps store with the redirect cache selected by config.CacheType.
+func cacheInit(store storage.Storage) (storage.Storage, error) {
+	switch config.CacheType {
+	case "":
 		return store, nil
-	} else {
-		store := inmemory.NewMemoryStorage(cache.Cache)
-		logger.Infof("Using inmemory storage")
-		return store, nil
+	case "lru":
+		logger.Infof("Using in-process redirect cache")
+		return cached.NewCachedStorage(store, cache.NewLRUCache(config.LRUCacheSize), config.CacheTTL, config.NegativeCacheTTL), nil
+	case "redis":
+		if config.RedisAddr == "" {
+			return nil, errors.New("redis cache requires a Redis address")
+		}
+		logger.Infof("Using Redis redirect cache at %s", config.RedisAddr)
+		urlCache := cache.NewRedisCache(config.RedisAddr, config.RedisPoolSize, config.RedisTimeout, config.RedisRetryInterval)
+		return cached.NewCachedStorage(store, urlCache, config.CacheTTL, config.NegativeCacheTTL), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", config.ErrUnknownCacheType, config.CacheType)
+	}
+}
+
+// tracingInit creates the tracer exporting spans with the exporter selected by config.TraceExporter.
+// It returns a nil tracer if tracing is disabled.
+func tracingInit() (*tracing.Tracer, error) {
+	var exporter tracing.Exporter
+	switch config.TraceExporter {
+	case "":
+		return nil, nil
+	case "otlp":
+		if config.TraceEndpoint == "" {
+			return nil, errors.New("otlp exporter requires a traces URL")
+		}
+		logger.Infof("Exporting spans to %s", config.TraceEndpoint)
+		exporter = tracing.NewOTLPExporter(config.TraceEndpoint, config.TraceServiceName)
+	case "file":
+		if config.TraceEndpoint == "" {
+			return nil, errors.New("file exporter requires an output path")
+		}
+		fileExporter, err := tracing.NewFileExporter(config.TraceEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("Writing spans to %s", config.TraceEndpoint)
+		exporter = fileExporter
+	default:
+		return nil, fmt.Errorf("%w: %q", config.ErrUnknownTraceExporter, config.TraceExporter)
+	}
+	return tracing.NewTracer(exporter, config.TraceBufferSize, config.TraceBatchSize,
+		config.TraceFlushInterval, config.TraceExportTimeout), nil
+}
+
+// rateLimitsInit parses the rate limits of the HTTP route groups from the configuration.
+func rateLimitsInit() (router.RateLimits, error) {
+	var limits router.RateLimits
+	var err error
+	if limits.Create, err = middleware.ParseRateLimit(config.CreateRateLimit); err != nil {
+		return limits, err
+	}
+	if limits.Redirect, err = middleware.ParseRateLimit(config.RedirectRateLimit); err != nil {
+		return limits, err
+	}
+	if limits.Delete, err = middleware.ParseRateLimit(config.DeleteRateLimit); err != nil {
+		return limits, err
 	}
+	return limits, nil
 }
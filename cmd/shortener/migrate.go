@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/db/migrations"
+)
+
+// migrateUsage describes the arguments of the migrate subcommand.
+const migrateUsage = "usage: shortener [flags] migrate up|down|status"
+
+// runMigrate implements the migrate subcommand against the database given by config.DBDSN,
+// or else the SQLite database given by config.SQLitePath.
+//
+//   - up applies every pending migration.
+//   - down reverts the most recently applied migration.
+//   - status lists the known migrations and when they were applied.
+func runMigrate(args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	var database db.DB
+	var err error
+	switch storageBackend() {
+	case "postgres":
+		database, err = dbimpl.Connect()
+	case "sqlite":
+		database, err = dbimpl.OpenSQLite(config.SQLitePath)
+	default:
+		return errors.New("neither a database connection string nor a SQLite file is set")
+	}
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	migrator, err := migrations.NewMigrator(database)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s).\n", applied)
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted migration %d_%s.\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gleb-korostelev/short-url.git/internal/transfer"
+)
+
+// runExport implements the export subcommand: it writes every URL record of the configured
+// storage to a JSON Lines file.
+//
+// The output must be a file because the application logs to standard output.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "", "path of the JSON Lines file to write")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return errors.New("usage: shortener [flags] export -o file")
+	}
+
+	store, queue, err := storageInit()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	defer queue.Close()
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	count, err := transfer.Export(context.Background(), store, file)
+	if err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d record(s) to %s.\n", count, *output)
+	return nil
+}
+
+// runImport implements the import subcommand: it stores the URL records of a JSON Lines file
+// in the configured storage and prints a summary of the records skipped because of conflicts.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	input := fs.String("i", "-", "path of the JSON Lines file to read, - for standard input")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	store, queue, err := storageInit()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	defer queue.Close()
+
+	summary, err := transfer.Import(context.Background(), store, r, *dryRun)
+	printImportSummary(summary, *dryRun)
+	return err
+}
+
+// printImportSummary prints the outcome of an import and every conflict it ran into.
+func printImportSummary(summary transfer.Summary, dryRun bool) {
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("Read %d record(s). %s %d, skipped %d conflict(s).\n",
+		summary.Read, verb, summary.Imported, len(summary.Conflicts))
+	for _, conflict := range summary.Conflicts {
+		fmt.Printf("  line %d: %s -> %s: %v\n", conflict.Line, conflict.ShortURL, conflict.OriginalURL, conflict.Reason)
+	}
+}
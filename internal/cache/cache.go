@@ -1,11 +1,3 @@
-// Package cache provides in-memory storage mechanisms for URL shortening and management.
+// Package cache provides the URLCache implementations used to keep redirect lookups away from
+// the storage: an in-process LRU cache and a Redis-backed cache shared between instances.
 package cache
-
-import "github.com/gleb-korostelev/short-url.git/internal/models"
-
-// Cache is an in-memory storage map that holds the short to original URL mappings.
-// The map keys are the shortened URL strings, and the values are URLData instances
-// which contain detailed information about the URLs.
-var (
-	Cache = make(map[string]models.URLData)
-)
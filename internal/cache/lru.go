@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// lruCache is an in-process URLCache that evicts the least recently used entry once it is full.
+type lruCache struct {
+	capacity int                      // capacity is the maximum number of entries.
+	items    map[string]*list.Element // items indexes the entries of order by key.
+	order    *list.List               // order holds *lruEntry values, most recently used first.
+	mu       sync.Mutex               // mu protects items and order.
+}
+
+// lruEntry is a single cached value.
+type lruEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// NewLRUCache creates an in-process URLCache holding at most capacity entries.
+func NewLRUCache(capacity int) URLCache {
+	return &lruCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value stored under key if it has not expired, marking it as recently used.
+func (c *lruCache) Get(ctx context.Context, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return "", false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return "", false, nil
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+// Set stores value under key for ttl, evicting the least recently used entry if the cache is full.
+func (c *lruCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	if c.order.Len() >= c.capacity {
+		if oldest := c.order.Back(); oldest != nil {
+			c.remove(oldest)
+		}
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	return nil
+}
+
+// Delete removes the given keys.
+func (c *lruCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := c.items[key]; ok {
+			c.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Close is a no-operation; the entries are released with the cache.
+func (c *lruCache) Close() error {
+	return nil
+}
+
+// remove drops an entry. The caller must hold c.mu.
+func (c *lruCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
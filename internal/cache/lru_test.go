@@ -0,0 +1,39 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRUCache(2)
+
+	assert.NoError(t, c.Set(ctx, "a", "1", time.Minute))
+	assert.NoError(t, c.Set(ctx, "b", "2", time.Minute))
+
+	// Reading "a" makes "b" the least recently used entry.
+	value, found, err := c.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "1", value)
+
+	assert.NoError(t, c.Set(ctx, "c", "3", time.Minute))
+	_, found, _ = c.Get(ctx, "b")
+	assert.False(t, found, "least recently used entry should be evicted")
+	_, found, _ = c.Get(ctx, "a")
+	assert.True(t, found)
+
+	assert.NoError(t, c.Delete(ctx, "a", "missing"))
+	_, found, _ = c.Get(ctx, "a")
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(ctx, "short", "lived", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, found, _ = c.Get(ctx, "short")
+	assert.False(t, found, "expired entry should not be returned")
+}
@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// redisCache is a URLCache backed by a Redis-compatible server spoken to over the RESP protocol.
+// Connections are kept in a fixed-size pool and dropped after any I/O error. After the server
+// fails to respond, commands fail immediately until the retry interval has passed, so that an
+// unreachable server does not add its timeout to every lookup.
+type redisCache struct {
+	addr          string          // addr is the host:port of the server.
+	timeout       time.Duration   // timeout bounds dialing and every command that has no earlier context deadline.
+	retryInterval time.Duration   // retryInterval is how long the server is not contacted after a failure.
+	pool          chan *redisConn // pool holds idle connections.
+	downUntil     atomic.Int64    // downUntil is the Unix time in nanoseconds before which the server is not contacted.
+}
+
+// redisConn is a connection to the server with a buffered reader for replies.
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply sent by the server.
+type redisError string
+
+// Error returns the message sent by the server.
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+var (
+	// errUnexpectedReply indicates a reply of a type the command never returns.
+	errUnexpectedReply = errors.New("redis: unexpected reply")
+
+	// errUnavailable indicates a command skipped because the server recently failed to respond.
+	errUnavailable = errors.New("redis: server unavailable")
+)
+
+// NewRedisCache creates a URLCache that stores entries on the Redis server at addr,
+// keeping up to poolSize idle connections. No connection is made until the first command.
+// After a failure the server is not contacted again for retryInterval.
+func NewRedisCache(addr string, poolSize int, timeout, retryInterval time.Duration) URLCache {
+	return &redisCache{
+		addr:          addr,
+		timeout:       timeout,
+		retryInterval: retryInterval,
+		pool:          make(chan *redisConn, poolSize),
+	}
+}
+
+// Get returns the value stored under key using the GET command.
+func (c *redisCache) Get(ctx context.Context, key string) (string, bool, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return "", false, err
+	}
+	switch value := reply.(type) {
+	case nil:
+		return "", false, nil
+	case []byte:
+		return string(value), true, nil
+	default:
+		return "", false, errUnexpectedReply
+	}
+}
+
+// Set stores value under key using the SET command with a millisecond expiry.
+func (c *redisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := c.do(ctx, "SET", key, value, "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+// Delete removes the given keys using the DEL command.
+func (c *redisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Close closes the idle connections.
+func (c *redisCache) Close() error {
+	for {
+		select {
+		case rc := <-c.pool:
+			rc.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends a command and reads its reply. Error replies are returned as redisError and
+// leave the connection usable; any other failure closes the connection and, unless ctx
+// was done, suspends the commands for the retry interval.
+func (c *redisCache) do(ctx context.Context, args ...string) (interface{}, error) {
+	if time.Now().UnixNano() < c.downUntil.Load() {
+		return nil, errUnavailable
+	}
+	reply, err := c.roundTrip(ctx, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) && ctx.Err() == nil {
+		c.downUntil.Store(time.Now().Add(c.retryInterval).UnixNano())
+	}
+	return reply, err
+}
+
+// roundTrip sends a command on a pooled connection and reads its reply.
+func (c *redisCache) roundTrip(ctx context.Context, args []string) (interface{}, error) {
+	rc, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := rc.conn.SetDeadline(deadline); err != nil {
+		rc.conn.Close()
+		return nil, err
+	}
+
+	if err := writeCommand(rc.conn, args); err != nil {
+		rc.conn.Close()
+		return nil, err
+	}
+	reply, err := readReply(rc.reader)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		rc.conn.Close()
+		return nil, err
+	}
+	c.put(rc)
+	return reply, err
+}
+
+// get takes an idle connection from the pool or dials a new one.
+func (c *redisCache) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case rc := <-c.pool:
+		return rc, nil
+	default:
+	}
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &redisConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// put returns a connection to the pool, closing it if the pool is full.
+func (c *redisCache) put(rc *redisConn) {
+	select {
+	case c.pool <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+// writeCommand encodes a command as a RESP array of bulk strings.
+func writeCommand(w io.Writer, args []string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readReply decodes a single RESP reply. Simple strings are returned as string, integers as int64,
+// bulk strings as []byte, null bulk strings and arrays as nil and arrays as []interface{}.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errUnexpectedReply
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			item, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnexpectedReply, line)
+	}
+}
+
+// readLine reads a CRLF-terminated line without the terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("%w: malformed line", errUnexpectedReply)
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,232 @@
+package cache_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is a local stand-in for a Redis server supporting GET, SET with PX and DEL.
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &fakeRedis{
+		listener: listener,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	go srv.serve()
+	t.Cleanup(func() { listener.Close() })
+	return srv
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := s.values[args[1]]
+		if expiresAt, set := s.expires[args[1]]; set && !time.Now().Before(expiresAt) {
+			ok = false
+		}
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		s.values[args[1]] = args[2]
+		delete(s.expires, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.values[key]; ok {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	srv := newFakeRedis(t)
+	c := cache.NewRedisCache(srv.addr(), 2, time.Second, time.Second)
+	defer c.Close()
+
+	_, found, err := c.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(ctx, "key", "value with spaces\r\n", time.Minute))
+	value, found, err := c.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value with spaces\r\n", value)
+
+	assert.NoError(t, c.Delete(ctx, "key", "missing"))
+	_, found, err = c.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set(ctx, "short", "lived", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, found, err = c.Get(ctx, "short")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestRedisCacheUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	c := cache.NewRedisCache(addr, 1, 100*time.Millisecond, time.Second)
+	defer c.Close()
+
+	_, _, err = c.Get(context.Background(), "key")
+	assert.Error(t, err)
+}
+
+func TestRedisCacheBacksOffAfterFailure(t *testing.T) {
+	// The server accepts connections but never replies, so every command runs into the timeout.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx := context.Background()
+	c := cache.NewRedisCache(listener.Addr().String(), 1, 100*time.Millisecond, 200*time.Millisecond)
+	defer c.Close()
+
+	_, _, err = c.Get(ctx, "key")
+	assert.Error(t, err)
+
+	start := time.Now()
+	_, _, err = c.Get(ctx, "key")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	time.Sleep(200 * time.Millisecond)
+	start = time.Now()
+	_, _, err = c.Get(ctx, "key")
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRedisCacheRecoversAfterRetryInterval(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx := context.Background()
+	c := cache.NewRedisCache(addr, 1, 100*time.Millisecond, 50*time.Millisecond)
+	defer c.Close()
+
+	_, _, err = c.Get(ctx, "key")
+	assert.Error(t, err)
+
+	listener, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	srv := &fakeRedis{listener: listener, values: make(map[string]string), expires: make(map[string]time.Time)}
+	go srv.serve()
+	defer listener.Close()
+
+	_, _, err = c.Get(ctx, "key")
+	assert.Error(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, c.Set(ctx, "key", "value", time.Minute))
+}
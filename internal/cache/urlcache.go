@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// URLCache is a string key-value cache whose entries expire after a per-entry lifetime.
+// It keeps hot lookups such as redirects away from the storage. Implementations must be
+// safe for concurrent use; errors mean the cache is unavailable, not that a key is missing.
+type URLCache interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) (string, bool, error)
+
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+
+	// Delete removes the given keys; missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+
+	// Close releases the resources held by the cache.
+	Close() error
+}
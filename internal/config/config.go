@@ -8,6 +8,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/tools/logger"
 )
@@ -23,6 +24,9 @@ const (
 	// DefaultServerAddress specifies the default address for the HTTP server.
 	DefaultServerAddress = "localhost:8080"
 
+	// DefaultGRPCServerAddress specifies the default address for the gRPC server.
+	DefaultGRPCServerAddress = "localhost:3200"
+
 	// DefaultBaseURL is the default base URL for shortened URLs.
 	DefaultBaseURL = "http://localhost:8080"
 
@@ -35,11 +39,239 @@ const (
 	// MaxConcurrentUpdates defines the maximum number of concurrent update operations.
 	MaxConcurrentUpdates = 10
 
+	// WorkerShutdownTimeout is how long the worker pool waits for running tasks on shutdown
+	// before cancelling their context.
+	WorkerShutdownTimeout = 10 * time.Second
+
+	// DefaultStorageReadTimeout is the default deadline of storage operations that only read, such as redirect lookups.
+	DefaultStorageReadTimeout = 2 * time.Second
+
+	// DefaultStorageWriteTimeout is the default deadline of storage operations that write, such as saving a URL.
+	DefaultStorageWriteTimeout = 5 * time.Second
+
+	// DefaultURLSchemes lists the schemes of the URLs that can be shortened by default.
+	DefaultURLSchemes = "http,https"
+
+	// FileSyncAlways makes the file storage fsync its log after every write.
+	FileSyncAlways = "always"
+
+	// FileSyncPeriodic makes the file storage fsync its log every FileSyncInterval if it was written to.
+	FileSyncPeriodic = "periodic"
+
+	// FileSyncNever leaves flushing the log of the file storage to the operating system.
+	FileSyncNever = "never"
+
+	// FileSyncInterval defines how often the log of the file storage is fsynced in FileSyncPeriodic mode.
+	FileSyncInterval = time.Second
+
+	// FileCompactInterval defines how often the log of the file storage is checked for compaction.
+	FileCompactInterval = time.Minute
+
+	// FileCompactMinStale is the number of superseded records in the log of the file storage above which
+	// it is compacted, provided they also outnumber the current records.
+	FileCompactMinStale = 1000
+
+	// DefaultSnapshotInterval is the default time between two snapshots of the in-memory storage.
+	DefaultSnapshotInterval = 5 * time.Minute
+
+	// DefaultJournalWindow is the default time a change of the in-memory storage may stay in the
+	// journal buffer before it is fsynced, that is the most a crash can lose.
+	DefaultJournalWindow = time.Second
+
+	// KVOpenTimeout is how long opening the key-value storage waits for another process to release its file.
+	KVOpenTimeout = time.Second
+
+	// SnapshotJournalSuffix is appended to the snapshot path of the in-memory storage to name its journal.
+	SnapshotJournalSuffix = ".wal"
+
 	//Certificate file path
 	CertFilePath = "./internal/certs/server.crt"
 
 	// Certificate key file path
 	KeyFilePath = "./internal/certs/server.key"
+
+	// AliasMinLength is the minimum length of a user-supplied custom alias.
+	AliasMinLength = 3
+
+	// AliasMaxLength is the maximum length of a user-supplied custom alias.
+	AliasMaxLength = 64
+
+	// MaxURLLength is the longest URL, in bytes after normalization, that can be shortened.
+	MaxURLLength = 2048
+
+	// URLErrorEmpty is the error code of a URL that is empty.
+	URLErrorEmpty = "empty_url"
+
+	// URLErrorTooLong is the error code of a URL longer than MaxURLLength.
+	URLErrorTooLong = "url_too_long"
+
+	// URLErrorMalformed is the error code of a URL that cannot be parsed or is not absolute.
+	URLErrorMalformed = "malformed_url"
+
+	// URLErrorScheme is the error code of a URL whose scheme is not in URLSchemes.
+	URLErrorScheme = "scheme_not_allowed"
+
+	// URLErrorHost is the error code of a URL whose host is missing or not a valid domain name.
+	URLErrorHost = "invalid_host"
+
+	// URLErrorPort is the error code of a URL whose port is not a number between 1 and 65535.
+	URLErrorPort = "invalid_port"
+
+	// URLErrorBlocked is the error code of a URL rejected by the URL policy.
+	URLErrorBlocked = "url_blocked"
+
+	// PolicyModeBlocklist makes the URL policy reject the URLs matching one of its entries.
+	PolicyModeBlocklist = "blocklist"
+
+	// PolicyModeAllowlist makes the URL policy reject the URLs matching none of its entries.
+	PolicyModeAllowlist = "allowlist"
+
+	// PolicyActionBlock answers redirects to a URL rejected by the URL policy with HTTP 451 Unavailable For Legal Reasons.
+	PolicyActionBlock = "block"
+
+	// PolicyActionWarn answers redirects to a URL rejected by the URL policy with a warning page linking to the URL.
+	PolicyActionWarn = "warn"
+
+	// PolicyReloadInterval defines how often the URL policy file is checked for changes.
+	PolicyReloadInterval = 5 * time.Second
+
+	// AliasExtraChars lists the characters allowed in custom aliases in addition to Letters.
+	AliasExtraChars = "-_"
+
+	// ExpirySweepInterval defines how often expired links are marked as deleted in the background.
+	ExpirySweepInterval = time.Minute
+
+	// ClickBufferSize is the number of redirect events buffered before new events are dropped.
+	ClickBufferSize = 1024
+
+	// ClickBatchSize is the number of buffered redirect events that triggers a flush to storage.
+	ClickBatchSize = 100
+
+	// ClickFlushInterval is the longest time a redirect event stays buffered before it is flushed.
+	ClickFlushInterval = time.Second
+
+	// DeleteBufferSize is the number of deletion requests buffered before new requests wait for a flush.
+	DeleteBufferSize = 256
+
+	// DeleteBatchSize is the number of buffered URL deletions that triggers a bulk update of the storage.
+	DeleteBatchSize = 500
+
+	// DeleteFlushInterval is the longest time a URL deletion stays buffered before it is flushed.
+	DeleteFlushInterval = 50 * time.Millisecond
+
+	// CacheTTL is how long a resolved original URL stays in the redirect cache.
+	// It also bounds how long an expiring link keeps redirecting after its expiration.
+	CacheTTL = time.Minute
+
+	// NegativeCacheTTL is how long a failed lookup of an unknown, deleted or expired link stays in the redirect cache.
+	NegativeCacheTTL = 10 * time.Second
+
+	// LRUCacheSize is the number of entries held by the in-process redirect cache.
+	LRUCacheSize = 10000
+
+	// RedisPoolSize is the number of idle connections kept to the Redis server.
+	RedisPoolSize = 10
+
+	// RedisTimeout bounds dialing and each command sent to the Redis server.
+	RedisTimeout = 500 * time.Millisecond
+
+	// RedisRetryInterval is how long the Redis server is not contacted after it failed to respond.
+	RedisRetryInterval = 5 * time.Second
+
+	// DefaultQRSize is the default width and height of a QR code image in pixels.
+	DefaultQRSize = 256
+
+	// MaxQRSize is the largest accepted width and height of a QR code image in pixels.
+	MaxQRSize = 4096
+
+	// DefaultQRMargin is the default width of the quiet zone around a QR code, in modules.
+	DefaultQRMargin = 4
+
+	// MaxQRMargin is the largest accepted width of the quiet zone around a QR code, in modules.
+	MaxQRMargin = 32
+
+	// PasswordMaxLength is the longest accepted link password in bytes, the input limit of bcrypt.
+	PasswordMaxLength = 72
+
+	// LinkPasswordHeader is the request header that carries the password of a protected link.
+	LinkPasswordHeader = "X-Link-Password"
+
+	// PasswordMaxAttempts is the number of wrong passwords accepted for a short URL within PasswordAttemptWindow.
+	PasswordMaxAttempts = 5
+
+	// PasswordAttemptWindow is the period over which wrong passwords for a short URL are counted.
+	// Once PasswordMaxAttempts is reached, further attempts are refused until the period ends.
+	PasswordAttemptWindow = time.Minute
+
+	// DefaultCreateRateLimit is the default rate limit of the routes that create short URLs.
+	DefaultCreateRateLimit = "60/m"
+
+	// DefaultRedirectRateLimit is the default rate limit of the redirect routes.
+	DefaultRedirectRateLimit = "600/m"
+
+	// DefaultDeleteRateLimit is the default rate limit of the route that deletes short URLs.
+	DefaultDeleteRateLimit = "30/m"
+
+	// TraceServiceName is the service name reported with exported spans.
+	TraceServiceName = "shortener"
+
+	// TraceBufferSize is the number of ended spans buffered for export; spans ended while it is full are dropped.
+	TraceBufferSize = 2048
+
+	// TraceBatchSize is the number of buffered spans that triggers an export.
+	TraceBatchSize = 256
+
+	// TraceFlushInterval is the longest time an ended span is buffered before it is exported.
+	TraceFlushInterval = 5 * time.Second
+
+	// TraceExportTimeout bounds a single export of spans.
+	TraceExportTimeout = 10 * time.Second
+
+	// JobPollInterval is how often the job queue is checked for jobs that are due, such as retries.
+	JobPollInterval = time.Second
+
+	// JobLease is how long a started job is reserved for the instance running it. A job still
+	// running after its lease, for example because the instance crashed, is started again.
+	JobLease = 5 * time.Minute
+
+	// JobMaxAttempts is the number of attempts after which a failing job is dead-lettered.
+	JobMaxAttempts = 8
+
+	// JobRetryBaseDelay is the delay before the first retry of a failed job; it doubles with every attempt.
+	JobRetryBaseDelay = time.Second
+
+	// JobRetryMaxDelay caps the delay between two attempts of a failed job.
+	JobRetryMaxDelay = 5 * time.Minute
+
+	// JobRetention is how long finished and dead-lettered jobs are kept for status queries.
+	JobRetention = 24 * time.Hour
+
+	// JobJournalSuffix is appended to the file storage path to name the journal of the job queue.
+	JobJournalSuffix = ".jobs"
+
+	// JobStatusPending marks a job waiting for its first attempt or a retry.
+	JobStatusPending = "pending"
+
+	// JobStatusRunning marks a job being executed.
+	JobStatusRunning = "running"
+
+	// JobStatusDone marks a job that succeeded.
+	JobStatusDone = "done"
+
+	// JobStatusDead marks a job that failed JobMaxAttempts times and will not be retried.
+	JobStatusDead = "dead"
+
+	// BatchItemCreated marks a batch item whose URL was shortened by the request.
+	BatchItemCreated = "created"
+
+	// BatchItemConflict marks a batch item whose URL was already shortened; its existing short URL is returned.
+	BatchItemConflict = "conflict"
+
+	// BatchItemError marks a batch item that was not saved.
+	BatchItemError = "error"
+
+	// StatsDateLayout is the layout of the per-day keys in URL statistics.
+	StatsDateLayout = "2006-01-02"
 )
 
 type contextKey string
@@ -63,17 +295,125 @@ var (
 
 	// ErrGone indicates an error when a link has been marked as deleted.
 	ErrGone = errors.New("this link is gone")
+
+	// ErrExpired indicates an error when a link has passed its expiration time.
+	ErrExpired = errors.New("this link has expired")
+
+	// ErrInvalidExpiry indicates an error when a requested expiration is malformed or already in the past.
+	ErrInvalidExpiry = errors.New("expiration is invalid")
+
+	// ErrForbidden indicates an error when a user accesses a link owned by another user.
+	ErrForbidden = errors.New("link belongs to another user")
+
+	// ErrAliasTaken indicates an error when a requested custom alias is already used by another link.
+	ErrAliasTaken = errors.New("custom alias is already taken")
+
+	// ErrInvalidAlias indicates an error when a requested custom alias is malformed or reserved.
+	ErrInvalidAlias = errors.New("custom alias is invalid")
+
+	// ErrInvalidPassword indicates an error when a requested link password is empty or too long.
+	ErrInvalidPassword = errors.New("password is invalid")
+
+	// ErrPasswordRequired indicates an error when a link is protected and must be resolved with its password.
+	ErrPasswordRequired = errors.New("this link is password protected")
+
+	// ErrWrongPassword indicates an error when the password given for a protected link does not match.
+	ErrWrongPassword = errors.New("wrong password")
+
+	// ErrInvalidRateLimit indicates an error when a configured rate limit is not in <requests>/<period> form.
+	ErrInvalidRateLimit = errors.New("rate limit is invalid")
+
+	// ErrUnknownCacheType indicates an error when the configured redirect cache type is not supported.
+	ErrUnknownCacheType = errors.New("unknown cache type")
+
+	// ErrUnknownTraceExporter indicates an error when the configured span exporter is not supported.
+	ErrUnknownTraceExporter = errors.New("unknown trace exporter")
+
+	// ErrQRDataTooLong indicates an error when the data does not fit in the largest QR code.
+	ErrQRDataTooLong = errors.New("data is too long for a QR code")
+
+	// ErrInvalidECLevel indicates an error when a QR code error correction level is not one of L, M, Q or H.
+	ErrInvalidECLevel = errors.New("error correction level must be one of L, M, Q or H")
+
+	// ErrInvalidQRParams indicates an error when the requested QR code size, margin or format is unacceptable.
+	ErrInvalidQRParams = errors.New("invalid QR code parameters")
+
+	// ErrInvalidURL indicates an error when a URL to be shortened is malformed or not supported.
+	ErrInvalidURL = errors.New("URL is invalid")
+
+	// ErrBatchAborted indicates an error when an item of an atomic batch is not saved because another item failed.
+	ErrBatchAborted = errors.New("batch was not saved because another item failed")
+
+	// ErrURLBlocked indicates an error when a URL is rejected by the URL policy.
+	ErrURLBlocked = errors.New("URL is blocked by policy")
+
+	// ErrInvalidPolicyEntry indicates an error when a URL policy entry is neither a domain, a wildcard suffix nor a regular expression.
+	ErrInvalidPolicyEntry = errors.New("policy entry is invalid")
+
+	// ErrPolicyEntryExists indicates an error when an entry is added to the URL policy twice.
+	ErrPolicyEntryExists = errors.New("policy entry already exists")
+
+	// ErrPolicyEntryNotFound indicates an error when an entry missing from the URL policy is removed.
+	ErrPolicyEntryNotFound = errors.New("policy entry doesn't exist")
+
+	// ErrUnknownPolicyMode indicates an error when the configured URL policy mode or action is not supported.
+	ErrUnknownPolicyMode = errors.New("unknown policy mode")
+
+	// ErrUnknownFileSync indicates an error when the configured fsync mode of the file storage is not supported.
+	ErrUnknownFileSync = errors.New("unknown file sync mode")
+
+	// ErrCorruptLog indicates an error when a record of the file storage log, other than a torn last one, cannot be read.
+	ErrCorruptLog = errors.New("file storage log is corrupt")
+
+	// ErrCorruptSnapshot indicates an error when the snapshot or a journal entry of the in-memory storage,
+	// other than a torn last one, cannot be read.
+	ErrCorruptSnapshot = errors.New("in-memory storage snapshot is corrupt")
+
+	// ErrDeleterClosed indicates an error when URLs are deleted through a batch deleter that was shut down.
+	ErrDeleterClosed = errors.New("batch deleter is shut down")
+
+	// ReservedAliases lists short codes that would shadow service routes and cannot be used as custom aliases.
+	ReservedAliases = []string{"ping", "api", "metrics"}
 )
 
 // Configuration variables are settable via command-line flags or environment variables.
 var (
-	ServerAddr   string                   // ServerAddr is the address where the HTTP server will run.
-	BaseURL      string                   // BaseURL is the base address for resulting shortened URLs.
-	BaseFilePath string                   // BaseFilePath is the file path where URLs are stored when file mode is used.
-	DBDSN        string                   // DBDSN is the Data Source Name for the database connection.
-	JwtKeySecret = "very-very-secret-key" // JwtKeySecret is the secret key for signing JWTs.
-	EnableHTTPS  bool                     // EnableHTTPS flag
-	ConfigPath   string                   // Path to the config JSON file
+	ServerAddr     string                   // ServerAddr is the address where the HTTP server will run.
+	GRPCServerAddr string                   // GRPCServerAddr is the address where the gRPC server will run.
+	BaseURL        string                   // BaseURL is the base address for resulting shortened URLs.
+	BaseFilePath   string                   // BaseFilePath is the file path where URLs are stored when file mode is used.
+	FileSync       = FileSyncPeriodic       // FileSync is how the file storage fsyncs its log: FileSyncAlways, FileSyncPeriodic or FileSyncNever.
+	SQLitePath     string                   // SQLitePath is the SQLite database file; empty disables the SQLite storage.
+	KVFilePath     string                   // KVFilePath is the file of the embedded key-value storage; empty disables it.
+	MemorySnapshot string                   // MemorySnapshot is the snapshot file of the in-memory storage; empty disables persistence.
+	DBDSN          string                   // DBDSN is the Data Source Name for the database connection.
+	JwtKeySecret   = "very-very-secret-key" // JwtKeySecret is the secret key for signing JWTs.
+	EnableHTTPS    bool                     // EnableHTTPS flag
+	ConfigPath     string                   // Path to the config JSON file
+	CacheType      string                   // CacheType selects the redirect cache: "lru", "redis" or empty for none.
+	RedisAddr      string                   // RedisAddr is the address of the Redis server used when CacheType is "redis".
+	TrustedSubnet  string                   // TrustedSubnet is the CIDR allowed to read internal statistics; empty denies everyone.
+	TrustedProxy   string                   // TrustedProxy is the CIDR of the reverse proxies whose forwarding headers are honoured; empty honours none.
+
+	CreateRateLimit   string // CreateRateLimit limits the requests creating short URLs per user and per IP, e.g. "60/m".
+	RedirectRateLimit string // RedirectRateLimit limits the redirect requests per user and per IP.
+	DeleteRateLimit   string // DeleteRateLimit limits the requests deleting short URLs per user and per IP.
+
+	TraceExporter string // TraceExporter selects the span exporter: "otlp", "file" or empty to disable tracing.
+	TraceEndpoint string // TraceEndpoint is the OTLP/HTTP traces URL, or the output path of the file exporter.
+
+	StorageReadTimeout  time.Duration // StorageReadTimeout bounds each reading storage operation; zero disables the deadline.
+	StorageWriteTimeout time.Duration // StorageWriteTimeout bounds each writing storage operation; zero disables the deadline.
+
+	SnapshotInterval time.Duration // SnapshotInterval is the time between two snapshots of the in-memory storage; zero snapshots on Close only.
+	JournalWindow    time.Duration // JournalWindow is how long a change of the in-memory storage may stay unsynced; zero fsyncs every change.
+
+	URLSchemes        = DefaultURLSchemes // URLSchemes is the comma-separated list of schemes of the URLs that can be shortened.
+	StripURLFragments bool                // StripURLFragments drops the fragment of URLs before they are shortened.
+
+	PolicyFile   string                // PolicyFile is the file of URL policy entries, reloaded when it changes; empty keeps the entries in memory.
+	PolicyMode   = PolicyModeBlocklist // PolicyMode is PolicyModeBlocklist or PolicyModeAllowlist.
+	PolicyAction = PolicyActionBlock   // PolicyAction is how redirects to rejected URLs are answered: PolicyActionBlock or PolicyActionWarn.
 )
 
 // ConfigInit initializes the application's configuration by parsing command-line flags
@@ -81,10 +421,33 @@ var (
 // with any user-specified options.
 func ConfigInit() {
 	flag.StringVar(&ServerAddr, "a", DefaultServerAddress, "address to run HTTP server on")
+	flag.StringVar(&GRPCServerAddr, "g", DefaultGRPCServerAddress, "address to run gRPC server on")
 	flag.StringVar(&BaseURL, "b", DefaultBaseURL, "base address for the resulting shortened URLs")
 	flag.StringVar(&BaseFilePath, "f", DefaultFilePath, "base file path to save URLs")
+	flag.StringVar(&FileSync, "file-sync", FileSyncPeriodic, "fsync of the file storage: always, periodic or never")
+	flag.StringVar(&SQLitePath, "sqlite", "", "SQLite database file of the storage, used unless a database DSN is set")
+	flag.StringVar(&KVFilePath, "kv", "", "file of the embedded key-value storage, used unless a database is set")
+	flag.StringVar(&MemorySnapshot, "memory-snapshot", "", "snapshot file of the in-memory storage, empty to keep URLs in memory only")
+	flag.DurationVar(&SnapshotInterval, "snapshot-interval", DefaultSnapshotInterval, "time between two snapshots of the in-memory storage, 0 to snapshot on shutdown only")
+	flag.DurationVar(&JournalWindow, "journal-window", DefaultJournalWindow, "most time a change of the in-memory storage may stay unsynced, 0 to fsync every change")
 	flag.StringVar(&DBDSN, "d", "", "database connection string")
 	flag.BoolVar(&EnableHTTPS, "s", false, "Enable HTTPS")
+	flag.StringVar(&CacheType, "cache", "", "redirect cache: lru, redis or empty to disable")
+	flag.StringVar(&RedisAddr, "redis", "", "address of the Redis server for the redis cache")
+	flag.StringVar(&TrustedSubnet, "t", "", "trusted subnet in CIDR notation for internal statistics")
+	flag.StringVar(&TrustedProxy, "trusted-proxy", "", "subnet in CIDR notation of the reverse proxies whose X-Real-IP and X-Forwarded-For headers are honoured")
+	flag.StringVar(&CreateRateLimit, "rate-create", DefaultCreateRateLimit, "rate limit of URL creation as <requests>/<period>, 0 to disable")
+	flag.StringVar(&RedirectRateLimit, "rate-redirect", DefaultRedirectRateLimit, "rate limit of redirects as <requests>/<period>, 0 to disable")
+	flag.StringVar(&DeleteRateLimit, "rate-delete", DefaultDeleteRateLimit, "rate limit of URL deletion as <requests>/<period>, 0 to disable")
+	flag.StringVar(&TraceExporter, "trace", "", "span exporter: otlp, file or empty to disable tracing")
+	flag.StringVar(&TraceEndpoint, "trace-endpoint", "", "OTLP/HTTP traces URL or output file of the span exporter")
+	flag.DurationVar(&StorageReadTimeout, "read-timeout", DefaultStorageReadTimeout, "deadline of reading storage operations, 0 to disable")
+	flag.DurationVar(&StorageWriteTimeout, "write-timeout", DefaultStorageWriteTimeout, "deadline of writing storage operations, 0 to disable")
+	flag.StringVar(&URLSchemes, "url-schemes", DefaultURLSchemes, "comma-separated schemes of the URLs that can be shortened")
+	flag.BoolVar(&StripURLFragments, "strip-fragments", false, "drop the fragment of URLs before shortening them")
+	flag.StringVar(&PolicyFile, "policy-file", "", "file of URL policy entries: domains, *.suffixes and /regexes/")
+	flag.StringVar(&PolicyMode, "policy-mode", PolicyModeBlocklist, "URL policy mode: blocklist or allowlist")
+	flag.StringVar(&PolicyAction, "policy-action", PolicyActionBlock, "answer to redirects to rejected URLs: block (HTTP 451) or warn")
 	flag.StringVar(&ConfigPath, "config", "", "Path to config file")
 	flag.StringVar(&ConfigPath, "c", "", "Path to config file")
 
@@ -99,12 +462,37 @@ func ConfigInit() {
 
 	// Override default values with environment variables if they exist.
 	ServerAddr = GetEnv("SERVER_ADDRESS", ServerAddr)
+	GRPCServerAddr = GetEnv("GRPC_SERVER_ADDRESS", GRPCServerAddr)
 	BaseURL = GetEnv("BASE_URL", BaseURL)
 	BaseFilePath = GetEnv("FILE_STORAGE_PATH", BaseFilePath)
+	FileSync = GetEnv("FILE_STORAGE_SYNC", FileSync)
+	SQLitePath = GetEnv("SQLITE_STORAGE_PATH", SQLitePath)
+	KVFilePath = GetEnv("KV_STORAGE_PATH", KVFilePath)
+	MemorySnapshot = GetEnv("MEMORY_SNAPSHOT_PATH", MemorySnapshot)
+	SnapshotInterval = GetEnvDuration("MEMORY_SNAPSHOT_INTERVAL", SnapshotInterval)
+	JournalWindow = GetEnvDuration("MEMORY_JOURNAL_WINDOW", JournalWindow)
 	DBDSN = GetEnv("DATABASE_DSN", DBDSN)
+	TrustedSubnet = GetEnv("TRUSTED_SUBNET", TrustedSubnet)
+	TrustedProxy = GetEnv("TRUSTED_PROXY", TrustedProxy)
+	CacheType = GetEnv("CACHE_TYPE", CacheType)
+	RedisAddr = GetEnv("REDIS_ADDRESS", RedisAddr)
+	CreateRateLimit = GetEnv("RATE_LIMIT_CREATE", CreateRateLimit)
+	RedirectRateLimit = GetEnv("RATE_LIMIT_REDIRECT", RedirectRateLimit)
+	DeleteRateLimit = GetEnv("RATE_LIMIT_DELETE", DeleteRateLimit)
+	TraceExporter = GetEnv("TRACE_EXPORTER", TraceExporter)
+	TraceEndpoint = GetEnv("TRACE_ENDPOINT", TraceEndpoint)
+	StorageReadTimeout = GetEnvDuration("STORAGE_READ_TIMEOUT", StorageReadTimeout)
+	StorageWriteTimeout = GetEnvDuration("STORAGE_WRITE_TIMEOUT", StorageWriteTimeout)
+	URLSchemes = GetEnv("URL_SCHEMES", URLSchemes)
+	PolicyFile = GetEnv("URL_POLICY_FILE", PolicyFile)
+	PolicyMode = GetEnv("URL_POLICY_MODE", PolicyMode)
+	PolicyAction = GetEnv("URL_POLICY_ACTION", PolicyAction)
 	if os.Getenv("ENABLE_HTTPS") == "true" {
 		EnableHTTPS = true
 	}
+	if os.Getenv("STRIP_URL_FRAGMENTS") == "true" {
+		StripURLFragments = true
+	}
 }
 
 // This function loads settings from config if it's not loaded from flags
@@ -118,18 +506,87 @@ func loadConf(path string) {
 		if ServerAddr == DefaultServerAddress {
 			ServerAddr = cfg.ServerAddr
 		}
+		if GRPCServerAddr == DefaultGRPCServerAddress && cfg.GRPCServerAddr != "" {
+			GRPCServerAddr = cfg.GRPCServerAddr
+		}
 		if BaseURL == DefaultBaseURL {
 			BaseURL = cfg.BaseURL
 		}
 		if BaseFilePath == DefaultFilePath {
 			BaseFilePath = cfg.BaseFilePath
 		}
+		if FileSync == FileSyncPeriodic && cfg.FileSync != "" {
+			FileSync = cfg.FileSync
+		}
+		if SQLitePath == "" {
+			SQLitePath = cfg.SQLitePath
+		}
+		if KVFilePath == "" {
+			KVFilePath = cfg.KVFilePath
+		}
+		if MemorySnapshot == "" {
+			MemorySnapshot = cfg.MemorySnapshot
+		}
+		if SnapshotInterval == DefaultSnapshotInterval && cfg.SnapshotInterval != "" {
+			SnapshotInterval = parseConfDuration("memory_snapshot_interval", cfg.SnapshotInterval)
+		}
+		if JournalWindow == DefaultJournalWindow && cfg.JournalWindow != "" {
+			JournalWindow = parseConfDuration("memory_journal_window", cfg.JournalWindow)
+		}
 		if DBDSN == "" {
 			DBDSN = cfg.DBDSN
 		}
 		if !EnableHTTPS {
 			EnableHTTPS = cfg.EnableHTTPS
 		}
+		if TrustedSubnet == "" {
+			TrustedSubnet = cfg.TrustedSubnet
+		}
+		if TrustedProxy == "" {
+			TrustedProxy = cfg.TrustedProxy
+		}
+		if CacheType == "" {
+			CacheType = cfg.CacheType
+		}
+		if RedisAddr == "" {
+			RedisAddr = cfg.RedisAddr
+		}
+		if CreateRateLimit == DefaultCreateRateLimit && cfg.CreateRateLimit != "" {
+			CreateRateLimit = cfg.CreateRateLimit
+		}
+		if RedirectRateLimit == DefaultRedirectRateLimit && cfg.RedirectRateLimit != "" {
+			RedirectRateLimit = cfg.RedirectRateLimit
+		}
+		if DeleteRateLimit == DefaultDeleteRateLimit && cfg.DeleteRateLimit != "" {
+			DeleteRateLimit = cfg.DeleteRateLimit
+		}
+		if TraceExporter == "" {
+			TraceExporter = cfg.TraceExporter
+		}
+		if TraceEndpoint == "" {
+			TraceEndpoint = cfg.TraceEndpoint
+		}
+		if StorageReadTimeout == DefaultStorageReadTimeout && cfg.StorageReadTimeout != "" {
+			StorageReadTimeout = parseConfDuration("storage_read_timeout", cfg.StorageReadTimeout)
+		}
+		if StorageWriteTimeout == DefaultStorageWriteTimeout && cfg.StorageWriteTimeout != "" {
+			StorageWriteTimeout = parseConfDuration("storage_write_timeout", cfg.StorageWriteTimeout)
+		}
+		if URLSchemes == DefaultURLSchemes && cfg.URLSchemes != "" {
+			URLSchemes = cfg.URLSchemes
+		}
+		if !StripURLFragments {
+			StripURLFragments = cfg.StripURLFragments
+		}
+		if PolicyFile == "" {
+			PolicyFile = cfg.PolicyFile
+		}
+		if PolicyMode == PolicyModeBlocklist && cfg.PolicyMode != "" {
+			PolicyMode = cfg.PolicyMode
+		}
+		if PolicyAction == PolicyActionBlock && cfg.PolicyAction != "" {
+			PolicyAction = cfg.PolicyAction
+		}
 	}
 }
 
@@ -141,3 +598,29 @@ func GetEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// GetEnvDuration retrieves a duration such as "1.5s" from an environment variable or returns
+// a fallback value if it is not set. A value that cannot be parsed is ignored with an error logged.
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Errorf("Ignoring %s: %v", key, err)
+		return fallback
+	}
+	fmt.Println(key, "set to", value) // Log the environment variable being used.
+	return d
+}
+
+// parseConfDuration parses a duration from the config file, exiting if it is malformed.
+func parseConfDuration(key, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Errorf("Failed to parse %s in config file: %v\n", key, err)
+		os.Exit(1)
+	}
+	return d
+}
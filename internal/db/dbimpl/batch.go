@@ -0,0 +1,137 @@
+package dbimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+)
+
+// WithTx runs fn in a transaction that is committed if fn succeeds and rolled back otherwise.
+func WithTx(ctx context.Context, db db.DB, fn func(tx db.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetStoredShortURLs returns the short URLs of those of originalURLs that are stored, keyed by
+// original URL: active holds those that are not marked as deleted and deleted the others.
+func GetStoredShortURLs(ctx context.Context, tx db.Querier, originalURLs []string) (active, deleted map[string]string, err error) {
+	sql := `
+	SELECT original_url, short_url, is_deleted FROM shortened_urls
+	WHERE ` + anyOf(tx.Dialect(), "original_url", 1)
+	rows, err := tx.Query(ctx, sql, originalURLs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	active = make(map[string]string)
+	deleted = make(map[string]string)
+	for rows.Next() {
+		var originalURL, shortURL string
+		var isDeleted bool
+		if err := rows.Scan(&originalURL, &shortURL, &isDeleted); err != nil {
+			return nil, nil, err
+		}
+		if isDeleted {
+			deleted[originalURL] = shortURL
+		} else {
+			active[originalURL] = shortURL
+		}
+	}
+	return active, deleted, rows.Err()
+}
+
+// GetTakenShortURLs returns which of shortURLs are already stored, deleted or not.
+func GetTakenShortURLs(ctx context.Context, tx db.Querier, shortURLs []string) (map[string]bool, error) {
+	sql := `SELECT short_url FROM shortened_urls WHERE ` + anyOf(tx.Dialect(), "short_url", 1)
+	rows, err := tx.Query(ctx, sql, shortURLs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var shortURL string
+		if err := rows.Scan(&shortURL); err != nil {
+			return nil, err
+		}
+		taken[shortURL] = true
+	}
+	return taken, rows.Err()
+}
+
+// CreateShortURLs inserts several shortened URLs in a single statement. As with CreateShortURL, an
+// original URL that is stored but marked as deleted is restored for the new owner, under the short URL
+// of its record, which is its previous one unless the user requested an alias. It returns the short
+// URL of every inserted or restored original URL, keyed by original URL; original URLs that are
+// stored and not deleted are left unchanged and missing from the result.
+// If a short URL is already taken, nothing is inserted and config.ErrAliasTaken is returned.
+func CreateShortURLs(ctx context.Context, tx db.Querier, records []models.URLData) (map[string]string, error) {
+	userIDs := make([]string, len(records))
+	shortURLs := make([]string, len(records))
+	originalURLs := make([]string, len(records))
+	expiresAt := make([]*time.Time, len(records))
+	passwordHashes := make([]string, len(records))
+	for i, record := range records {
+		userIDs[i] = record.UUID.String()
+		shortURLs[i] = record.ShortURL
+		originalURLs[i] = record.OriginalURL
+		expiresAt[i] = record.ExpiresAt
+		passwordHashes[i] = record.PasswordHash
+	}
+
+	columns := []string{"user_id", "short_url", "original_url", "expires_at", "password_hash"}
+	// WHERE TRUE keeps SQLite from parsing ON CONFLICT as the join constraint of the SELECT.
+	sql := `
+	INSERT INTO shortened_urls (user_id, short_url, original_url, is_deleted, expires_at, password_hash)
+	SELECT user_id, short_url, original_url, FALSE, expires_at, password_hash
+	FROM ` + unnest(tx.Dialect(), "t", columns, []string{"uuid", "text", "text", "timestamptz", "text"}) + `
+	WHERE TRUE
+	ON CONFLICT (original_url)
+	DO UPDATE SET
+		user_id = EXCLUDED.user_id,
+		short_url = EXCLUDED.short_url,
+		is_deleted = FALSE,
+		expires_at = EXCLUDED.expires_at,
+		password_hash = EXCLUDED.password_hash
+	WHERE shortened_urls.is_deleted = TRUE
+	RETURNING original_url, short_url
+	`
+	rows, err := tx.Query(ctx, sql, userIDs, shortURLs, originalURLs, expiresAt, passwordHashes)
+	if err != nil {
+		// SQLite runs the statement when it is sent, PostgreSQL when the rows are read.
+		if uniqueViolation(err) == shortURLColumn {
+			return nil, config.ErrAliasTaken
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	created := make(map[string]string, len(records))
+	for rows.Next() {
+		var originalURL, shortURL string
+		if err := rows.Scan(&originalURL, &shortURL); err != nil {
+			return nil, err
+		}
+		created[originalURL] = shortURL
+	}
+	if err := rows.Err(); err != nil {
+		if uniqueViolation(err) == shortURLColumn {
+			return nil, config.ErrAliasTaken
+		}
+		return nil, err
+	}
+	return created, nil
+}
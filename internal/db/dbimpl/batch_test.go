@@ -0,0 +1,90 @@
+package dbimpl_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShortURLs(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, database db.DB) {
+		ctx := context.Background()
+		owner := uuid.MustParse(ownerID)
+		createShortURL(t, database, otherID, "aaa", "https://example.com/a")
+		createShortURL(t, database, otherID, "bbb", "https://example.com/b")
+		createShortURL(t, database, otherID, "ccc", "https://example.com/c")
+		_, err := dbimpl.MarkDeletedBatch(ctx, database, []models.URLDeletion{
+			{UserID: otherID, ShortURL: "bbb"},
+			{UserID: otherID, ShortURL: "ccc"},
+		})
+		require.NoError(t, err)
+
+		var created map[string]string
+		err = dbimpl.WithTx(ctx, database, func(tx db.Tx) error {
+			var err error
+			created, err = dbimpl.CreateShortURLs(ctx, tx, []models.URLData{
+				{UUID: owner, ShortURL: "new", OriginalURL: "https://example.com/new"},
+				{UUID: owner, ShortURL: "aaa2", OriginalURL: "https://example.com/a"},
+				{UUID: owner, ShortURL: "bbb", OriginalURL: "https://example.com/b"},
+				{UUID: owner, ShortURL: "moved", OriginalURL: "https://example.com/c"},
+			})
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"https://example.com/new": "new",
+			"https://example.com/b":   "bbb",
+			"https://example.com/c":   "moved",
+		}, created)
+
+		tests := []struct {
+			shortURL    string
+			originalURL string
+			owner       string
+			expectedErr error
+		}{
+			{shortURL: "new", originalURL: "https://example.com/new", owner: ownerID},
+			{shortURL: "aaa", originalURL: "https://example.com/a", owner: otherID},
+			{shortURL: "aaa2", expectedErr: config.ErrNotFound},
+			{shortURL: "bbb", originalURL: "https://example.com/b", owner: ownerID},
+			{shortURL: "moved", originalURL: "https://example.com/c", owner: ownerID},
+			{shortURL: "ccc", expectedErr: config.ErrNotFound},
+		}
+		for _, tt := range tests {
+			originalURL, err := dbimpl.GetOriginalURL(ctx, database, tt.shortURL)
+			assert.ErrorIs(t, err, tt.expectedErr, tt.shortURL)
+			assert.Equal(t, tt.originalURL, originalURL, tt.shortURL)
+			if tt.expectedErr == nil {
+				owner, err := dbimpl.GetURLOwner(ctx, database, tt.shortURL)
+				require.NoError(t, err)
+				assert.Equal(t, tt.owner, owner, tt.shortURL)
+			}
+		}
+	})
+}
+
+func TestCreateShortURLsAliasTaken(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, database db.DB) {
+		ctx := context.Background()
+		createShortURL(t, database, otherID, "taken", "https://example.com/a")
+
+		err := dbimpl.WithTx(ctx, database, func(tx db.Tx) error {
+			_, err := dbimpl.CreateShortURLs(ctx, tx, []models.URLData{
+				{UUID: uuid.MustParse(ownerID), ShortURL: "free", OriginalURL: "https://example.com/free"},
+				{UUID: uuid.MustParse(ownerID), ShortURL: "taken", OriginalURL: "https://example.com/b"},
+			})
+			return err
+		})
+		assert.ErrorIs(t, err, config.ErrAliasTaken)
+
+		_, err = dbimpl.GetOriginalURL(ctx, database, "free")
+		assert.ErrorIs(t, err, config.ErrNotFound)
+	})
+}
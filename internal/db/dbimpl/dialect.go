@@ -0,0 +1,97 @@
+package dbimpl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// shortURLColumn is the column of shortened_urls holding the short URL, which is UNIQUE.
+	shortURLColumn = "short_url"
+
+	// originalURLColumn is the column of shortened_urls holding the original URL, which is UNIQUE.
+	originalURLColumn = "original_url"
+)
+
+// constraintColumns maps the names PostgreSQL assigns to the UNIQUE constraints of shortened_urls
+// to their column.
+var constraintColumns = map[string]string{
+	shortURLConstraint:    shortURLColumn,
+	originalURLConstraint: originalURLColumn,
+}
+
+// uniqueViolationError reports a statement violating the UNIQUE constraint of a column, for the
+// databases whose errors name no constraint.
+type uniqueViolationError struct {
+	column string // column is the column whose constraint is violated.
+	err    error  // err is the error of the driver.
+}
+
+// Error returns the error of the driver.
+func (e *uniqueViolationError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error of the driver.
+func (e *uniqueViolationError) Unwrap() error {
+	return e.err
+}
+
+// uniqueViolation returns the column whose UNIQUE constraint err reports a violation of, or ""
+// if err reports no such violation.
+func uniqueViolation(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return constraintColumns[pgErr.ConstraintName]
+	}
+	var violation *uniqueViolationError
+	if errors.As(err, &violation) {
+		return violation.column
+	}
+	return ""
+}
+
+// anyOf returns the condition that column equals one of the elements of the array given as
+// parameter n.
+func anyOf(dialect db.Dialect, column string, n int) string {
+	if dialect == db.SQLite {
+		return fmt.Sprintf("%s IN (SELECT value FROM json_each($%d))", column, n)
+	}
+	return fmt.Sprintf("%s = ANY($%d)", column, n)
+}
+
+// unnest returns a table named alias with one row per element of the arrays given as parameters
+// $1, $2, ..., one for each column. types are the PostgreSQL types of the elements, which SQLite,
+// receiving the arrays as JSON, does not need.
+func unnest(dialect db.Dialect, alias string, columns, types []string) string {
+	if dialect == db.SQLite {
+		selected := make([]string, len(columns))
+		joined := make([]string, len(columns))
+		for i, column := range columns {
+			selected[i] = fmt.Sprintf("a%d.value AS %s", i+1, column)
+			joined[i] = fmt.Sprintf("json_each($%d) AS a%d", i+1, i+1)
+			if i > 0 {
+				joined[i] = fmt.Sprintf("JOIN %s ON a%d.key = a1.key", joined[i], i+1)
+			}
+		}
+		return fmt.Sprintf("(SELECT %s FROM %s) AS %s",
+			strings.Join(selected, ", "), strings.Join(joined, " "), alias)
+	}
+	params := make([]string, len(columns))
+	for i, typ := range types {
+		params[i] = fmt.Sprintf("$%d::%s[]", i+1, typ)
+	}
+	return fmt.Sprintf("unnest(%s) AS %s(%s)", strings.Join(params, ", "), alias, strings.Join(columns, ", "))
+}
+
+// utcDay returns the UTC day of the timestamp column, formatted as YYYY-MM-DD.
+func utcDay(dialect db.Dialect, column string) string {
+	if dialect == db.SQLite {
+		return fmt.Sprintf("date(%s)", column)
+	}
+	return fmt.Sprintf("to_char(%s AT TIME ZONE 'UTC', 'YYYY-MM-DD')", column)
+}
@@ -0,0 +1,100 @@
+package dbimpl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// jobColumns lists the columns of the jobs table in the order scanned by scanJob.
+const jobColumns = `id, user_id, short_urls, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+// scanJob reads a job from a row selecting jobColumns.
+func scanJob(row pgx.Row) (models.Job, error) {
+	var job models.Job
+	err := row.Scan(&job.ID, &job.UserID, &job.ShortURLs, &job.Status, &job.Attempts,
+		&job.LastError, &job.NextAttemptAt, &job.CreatedAt, &job.UpdatedAt)
+	return job, err
+}
+
+// InsertJob stores a new job.
+func InsertJob(ctx context.Context, db db.DB, job models.Job) error {
+	sql := `
+	INSERT INTO jobs (` + jobColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := db.Exec(ctx, sql, job.ID, job.UserID, job.ShortURLs, job.Status, job.Attempts,
+		job.LastError, job.NextAttemptAt, job.CreatedAt, job.UpdatedAt)
+	return err
+}
+
+// ClaimJob starts the job that has been due the longest: a pending job whose next attempt is due,
+// or a running job whose lease has run out. The job is marked as running, its attempts are counted
+// and it is leased until now plus lease. Concurrent claims never return the same job.
+// It returns false if no job is due.
+func ClaimJob(ctx context.Context, db db.DB, now time.Time, lease time.Duration) (models.Job, bool, error) {
+	sql := `
+	UPDATE jobs SET status = $1, attempts = attempts + 1, next_attempt_at = $2, updated_at = $3
+	WHERE id = (
+		SELECT id FROM jobs
+		WHERE status IN ($4, $1) AND next_attempt_at <= $3
+		ORDER BY next_attempt_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + jobColumns
+	job, err := scanJob(db.QueryRow(ctx, sql, config.JobStatusRunning, now.Add(lease), now, config.JobStatusPending))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Job{}, false, nil
+		}
+		return models.Job{}, false, err
+	}
+	return job, true, nil
+}
+
+// UpdateJobStatus records the outcome of an attempt of a job: its new status, the error of the
+// attempt and the moment of its next attempt. It returns config.ErrNotFound if the job does not exist.
+func UpdateJobStatus(ctx context.Context, db db.DB, id, status, lastError string, nextAttemptAt, now time.Time) error {
+	sql := `
+	UPDATE jobs SET status = $2, last_error = $3, next_attempt_at = $4, updated_at = $5
+	WHERE id = $1
+	`
+	cmdTag, err := db.Exec(ctx, sql, id, status, lastError, nextAttemptAt, now)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return config.ErrNotFound
+	}
+	return nil
+}
+
+// GetJob retrieves a job by its ID. It returns config.ErrNotFound if the job does not exist.
+func GetJob(ctx context.Context, db db.DB, id string) (models.Job, error) {
+	sql := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+	job, err := scanJob(db.QueryRow(ctx, sql, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Job{}, config.ErrNotFound
+		}
+		return models.Job{}, err
+	}
+	return job, nil
+}
+
+// DeleteFinishedJobs removes the done and dead jobs last updated before the given moment.
+// It returns the number of removed jobs.
+func DeleteFinishedJobs(ctx context.Context, db db.DB, before time.Time) (int64, error) {
+	sql := `DELETE FROM jobs WHERE status IN ($1, $2) AND updated_at < $3`
+	cmdTag, err := db.Exec(ctx, sql, config.JobStatusDone, config.JobStatusDead, before)
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
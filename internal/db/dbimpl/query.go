@@ -4,75 +4,129 @@ package dbimpl
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/db"
 	"github.com/gleb-korostelev/short-url.git/internal/models"
 	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/jackc/pgx/v5"
 )
 
-// InitializeTables creates the necessary database tables if they do not already exist.
-// This function is typically called at application startup.
-func InitializeTables(db db.DB) error {
-	createTableSQL := `
-    CREATE TABLE IF NOT EXISTS shortened_urls (
-        id SERIAL PRIMARY KEY,
-		user_id UUID NOT NULL,
-        short_url VARCHAR(255) UNIQUE NOT NULL,
-        original_url VARCHAR(255) NOT NULL UNIQUE,
-        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-		is_deleted BOOLEAN DEFAULT FALSE
-    );`
-	_, err := db.Exec(context.Background(), createTableSQL)
-	return err
-}
+const (
+	// uniqueViolationCode is the PostgreSQL SQLSTATE for unique constraint violations.
+	uniqueViolationCode = "23505"
 
-// CreateShortURL inserts a new shortened URL into the database.
-// It handles conflicts by updating existing entries where the original URL is already present but marked as deleted.
-func CreateShortURL(db db.DB, uuid, shortURL, originalURL string) error {
+	// shortURLConstraint is the name PostgreSQL assigns to the UNIQUE constraint on short_url.
+	shortURLConstraint = "shortened_urls_short_url_key"
+
+	// originalURLConstraint is the name PostgreSQL assigns to the UNIQUE constraint on original_url.
+	originalURLConstraint = "shortened_urls_original_url_key"
+)
+
+// CreateShortURL inserts a new shortened URL into the database and returns the short URL it is stored under.
+// It handles conflicts by updating existing entries where the original URL is already present but marked as deleted:
+// such an entry moves to shortURL if aliased reports that shortURL is an alias requested by the user, and otherwise
+// keeps its short URL, which is returned instead of shortURL.
+// If the original URL is present and not deleted, config.ErrExists is returned.
+// If the short URL itself is already taken, config.ErrAliasTaken is returned.
+// A nil expiresAt stores a link that never expires and an empty passwordHash a link that is not protected.
+func CreateShortURL(ctx context.Context, db db.DB, uuid, shortURL, originalURL string, expiresAt *time.Time, passwordHash string, aliased bool) (string, error) {
 	sql := `
-    INSERT INTO shortened_urls (user_id, short_url, original_url, is_deleted)
-    VALUES ($1, $2, $3, FALSE)
+    INSERT INTO shortened_urls (user_id, short_url, original_url, is_deleted, expires_at, password_hash)
+    VALUES ($1, $2, $3, FALSE, $4, $5)
     ON CONFLICT (original_url)
     DO UPDATE SET 
         user_id = EXCLUDED.user_id,
-        is_deleted = FALSE
+        short_url = CASE WHEN $6 THEN EXCLUDED.short_url ELSE shortened_urls.short_url END,
+        is_deleted = FALSE,
+        expires_at = EXCLUDED.expires_at,
+        password_hash = EXCLUDED.password_hash
     WHERE shortened_urls.is_deleted = TRUE
+    RETURNING short_url
 `
-	cmdTag, err := db.Exec(context.Background(), sql, uuid, shortURL, originalURL)
+	var stored string
+	err := db.QueryRow(ctx, sql, uuid, shortURL, originalURL, expiresAt, passwordHash, aliased).Scan(&stored)
 	if err != nil {
-		return err
-	}
-	if cmdTag.RowsAffected() == 0 {
-		return config.ErrExists
+		if uniqueViolation(err) == shortURLColumn {
+			return "", config.ErrAliasTaken
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", config.ErrExists
+		}
+		return "", err
 	}
-	return nil
+	return stored, nil
 }
 
 // GetOriginalURL retrieves the original URL from a shortened URL.
-// It returns an error if the URL is marked as deleted or if the shortened URL does not exist.
-func GetOriginalURL(db db.DB, shortURL string) (string, error) {
-	var originalURL string
-	var isDeleted bool
-	sql := `SELECT original_url, is_deleted FROM shortened_urls WHERE short_url = $1`
-	err := db.QueryRow(context.Background(), sql, shortURL).Scan(&originalURL, &isDeleted)
+// It returns config.ErrGone if the URL is marked as deleted, config.ErrExpired if it has expired,
+// config.ErrPasswordRequired if it is password protected and config.ErrNotFound if the shortened URL does not exist.
+func GetOriginalURL(ctx context.Context, db db.DB, shortURL string) (string, error) {
+	originalURL, passwordHash, err := GetProtectedURL(ctx, db, shortURL)
 	if err != nil {
 		return "", err
 	}
-	if isDeleted {
-		return "", config.ErrGone
+	if passwordHash != "" {
+		return "", config.ErrPasswordRequired
 	}
 	return originalURL, nil
 }
 
+// GetExpiringURL retrieves the original URL of a shortened URL, as GetOriginalURL does, together with
+// its expiration time, nil if it never expires.
+func GetExpiringURL(ctx context.Context, db db.DB, shortURL string) (string, *time.Time, error) {
+	data, err := lookupURL(ctx, db, shortURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if data.PasswordHash != "" {
+		return "", nil, config.ErrPasswordRequired
+	}
+	return data.OriginalURL, data.ExpiresAt, nil
+}
+
+// GetProtectedURL retrieves the original URL and the password hash of a shortened URL.
+// The hash is empty if the URL is not password protected. Deleted, expired and unknown
+// shortened URLs are reported as by GetOriginalURL.
+func GetProtectedURL(ctx context.Context, db db.DB, shortURL string) (string, string, error) {
+	data, err := lookupURL(ctx, db, shortURL)
+	if err != nil {
+		return "", "", err
+	}
+	return data.OriginalURL, data.PasswordHash, nil
+}
+
+// lookupURL retrieves the original URL, expiration time and password hash of a shortened URL that is
+// neither deleted nor expired.
+func lookupURL(ctx context.Context, db db.DB, shortURL string) (models.URLData, error) {
+	var data models.URLData
+	sql := `SELECT original_url, is_deleted, expires_at, password_hash FROM shortened_urls WHERE short_url = $1`
+	err := db.QueryRow(ctx, sql, shortURL).Scan(&data.OriginalURL, &data.DeletedFlag, &data.ExpiresAt, &data.PasswordHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.URLData{}, config.ErrNotFound
+		}
+		return models.URLData{}, err
+	}
+	if data.DeletedFlag {
+		return models.URLData{}, config.ErrGone
+	}
+	if data.Expired(time.Now()) {
+		return models.URLData{}, config.ErrExpired
+	}
+	return data, nil
+}
+
 // GetOriginalURLsByUserID retrieves all active (not deleted) original URLs for a given user ID.
 // It prepends the base URL to each short URL before returning the list.
-func GetOriginalURLsByUserID(db db.DB, userID, baseURL string) ([]models.UserURLs, error) {
+func GetOriginalURLsByUserID(ctx context.Context, db db.DB, userID, baseURL string) ([]models.UserURLs, error) {
 	sql := `
 	SELECT short_url, original_url FROM shortened_urls
 	WHERE user_id = $1 AND is_deleted = FALSE
 	`
-	rows, err := db.Query(context.Background(), sql, userID)
+	rows, err := db.Query(ctx, sql, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -97,33 +151,201 @@ func GetOriginalURLsByUserID(db db.DB, userID, baseURL string) ([]models.UserURL
 
 // GetShortURLByOriginalURL retrieves the shortened URL for a given original URL.
 // It returns an error if the original URL does not exist in the database.
-func GetShortURLByOriginalURL(db db.DB, originalURL string) (string, error) {
+func GetShortURLByOriginalURL(ctx context.Context, db db.DB, originalURL string) (string, error) {
 	var shortURL string
 	sql := `SELECT short_url FROM shortened_urls WHERE original_url = $1`
-	err := db.QueryRow(context.Background(), sql, originalURL).Scan(&shortURL)
+	err := db.QueryRow(ctx, sql, originalURL).Scan(&shortURL)
 	if err != nil {
 		return "", err
 	}
 	return shortURL, nil
 }
 
-// MarkDeleted marks a list of shortened URLs as deleted for a specific user.
-// This function runs asynchronously and logs the result of the operation.
-func MarkDeleted(db db.DB, userID string, shortURLs []string) {
-	go func() {
-		sql := `
-		UPDATE shortened_urls SET is_deleted = TRUE
-		WHERE user_id = $1 AND short_url = ANY($2)
-		`
-		cmdTag, err := db.Exec(context.Background(), sql, userID, shortURLs)
-		if err != nil {
-			logger.Errorf("Error marking URLs as deleted: %v\n", err)
-			return
+// MarkDeleted marks a list of shortened URLs as deleted for a specific user and logs the result of the operation.
+// It runs within ctx, so callers wanting it to run in the background submit it to the worker pool.
+func MarkDeleted(ctx context.Context, db db.DB, userID string, shortURLs []string) error {
+	sql := `
+	UPDATE shortened_urls SET is_deleted = TRUE
+	WHERE user_id = $1 AND ` + anyOf(db.Dialect(), "short_url", 2)
+	cmdTag, err := db.Exec(ctx, sql, userID, shortURLs)
+	if err != nil {
+		logger.Errorf("Error marking URLs as deleted: %v\n", err)
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		logger.Info("No URLs were marked as deleted.")
+	} else {
+		logger.Infof("%d URLs were marked as deleted.\n", cmdTag.RowsAffected())
+	}
+	return nil
+}
+
+// MarkDeletedBatch marks the shortened URLs of several users as deleted in a single statement.
+// It returns the number of rows that were marked.
+func MarkDeletedBatch(ctx context.Context, db db.DB, deletions []models.URLDeletion) (int64, error) {
+	userIDs := make([]string, len(deletions))
+	shortURLs := make([]string, len(deletions))
+	for i, deletion := range deletions {
+		userIDs[i] = deletion.UserID
+		shortURLs[i] = deletion.ShortURL
+	}
+
+	sql := `
+	UPDATE shortened_urls AS s SET is_deleted = TRUE
+	FROM ` + unnest(db.Dialect(), "d", []string{"user_id", "short_url"}, []string{"uuid", "text"}) + `
+	WHERE s.user_id = d.user_id AND s.short_url = d.short_url
+	`
+	cmdTag, err := db.Exec(ctx, sql, userIDs, shortURLs)
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// MarkExpiredDeleted marks every active shortened URL whose expiration time has passed as deleted.
+// It returns the number of rows that were marked.
+func MarkExpiredDeleted(ctx context.Context, db db.DB) (int64, error) {
+	sql := `
+	UPDATE shortened_urls SET is_deleted = TRUE
+	WHERE is_deleted = FALSE AND expires_at IS NOT NULL AND expires_at <= $1
+	`
+	cmdTag, err := db.Exec(ctx, sql, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// InsertClicks stores a batch of redirect events in a single statement.
+func InsertClicks(ctx context.Context, db db.DB, clicks []models.Click) error {
+	shortURLs := make([]string, len(clicks))
+	clickedAt := make([]time.Time, len(clicks))
+	referrers := make([]string, len(clicks))
+	userAgents := make([]string, len(clicks))
+	ipHashes := make([]string, len(clicks))
+	for i, click := range clicks {
+		shortURLs[i] = click.ShortURL
+		clickedAt[i] = click.ClickedAt
+		referrers[i] = click.Referrer
+		userAgents[i] = click.UserAgent
+		ipHashes[i] = click.IPHash
+	}
+
+	columns := []string{"short_url", "clicked_at", "referrer", "user_agent", "ip_hash"}
+	sql := `
+	INSERT INTO url_clicks (short_url, clicked_at, referrer, user_agent, ip_hash)
+	SELECT short_url, clicked_at, referrer, user_agent, ip_hash
+	FROM ` + unnest(db.Dialect(), "t", columns, []string{"text", "timestamptz", "text", "text", "text"})
+	_, err := db.Exec(ctx, sql, shortURLs, clickedAt, referrers, userAgents, ipHashes)
+	return err
+}
+
+// GetURLOwner retrieves the ID of the user that created a shortened URL.
+// It returns config.ErrNotFound if the shortened URL does not exist.
+func GetURLOwner(ctx context.Context, db db.DB, shortURL string) (string, error) {
+	var userID string
+	sql := `SELECT user_id FROM shortened_urls WHERE short_url = $1`
+	err := db.QueryRow(ctx, sql, shortURL).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", config.ErrNotFound
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+// GetDailyClicks retrieves the number of redirect events per UTC day for a shortened URL, oldest day first.
+func GetDailyClicks(ctx context.Context, db db.DB, shortURL string) ([]models.DailyClicks, error) {
+	sql := `
+	SELECT ` + utcDay(db.Dialect(), "clicked_at") + ` AS day, COUNT(*)
+	FROM url_clicks
+	WHERE short_url = $1
+	GROUP BY day
+	ORDER BY day
+	`
+	rows, err := db.Query(ctx, sql, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	daily := []models.DailyClicks{}
+	for rows.Next() {
+		var data models.DailyClicks
+		if err := rows.Scan(&data.Date, &data.Clicks); err != nil {
+			return nil, err
+		}
+		daily = append(daily, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return daily, nil
+}
+
+// CountURLs returns the number of shortened URLs that are not marked as deleted.
+func CountURLs(ctx context.Context, db db.DB) (int, error) {
+	var count int
+	sql := `SELECT COUNT(*) FROM shortened_urls WHERE is_deleted = FALSE`
+	err := db.QueryRow(ctx, sql).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountUsers returns the number of distinct users owning at least one shortened URL that is not marked as deleted.
+func CountUsers(ctx context.Context, db db.DB) (int, error) {
+	var count int
+	sql := `SELECT COUNT(DISTINCT user_id) FROM shortened_urls WHERE is_deleted = FALSE`
+	err := db.QueryRow(ctx, sql).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ForEachURL calls fn for every shortened URL row, including deleted and expired ones.
+// Rows are streamed, so the whole table is never held in memory.
+func ForEachURL(ctx context.Context, db db.DB, fn func(models.URLData) error) error {
+	sql := `SELECT user_id, short_url, original_url, is_deleted, expires_at, password_hash FROM shortened_urls ORDER BY id`
+	rows, err := db.Query(ctx, sql)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data models.URLData
+		if err := rows.Scan(&data.UUID, &data.ShortURL, &data.OriginalURL, &data.DeletedFlag, &data.ExpiresAt, &data.PasswordHash); err != nil {
+			return err
 		}
-		if cmdTag.RowsAffected() == 0 {
-			logger.Info("No URLs were marked as deleted.")
-		} else {
-			logger.Infof("%d URLs were marked as deleted.\n", cmdTag.RowsAffected())
+		if err := fn(data); err != nil {
+			return err
 		}
-	}()
+	}
+	return rows.Err()
+}
+
+// InsertURL inserts a shortened URL row as is, preserving its owner, deleted flag, expiration and password hash.
+// It returns config.ErrAliasTaken if the short URL is taken and config.ErrExists if the original URL is.
+func InsertURL(ctx context.Context, db db.DB, data models.URLData) error {
+	sql := `
+	INSERT INTO shortened_urls (user_id, short_url, original_url, is_deleted, expires_at, password_hash)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := db.Exec(ctx, sql, data.UUID, data.ShortURL, data.OriginalURL, data.DeletedFlag, data.ExpiresAt, data.PasswordHash)
+	if err != nil {
+		switch uniqueViolation(err) {
+		case shortURLColumn:
+			return config.ErrAliasTaken
+		case originalURLColumn:
+			return config.ErrExists
+		}
+		return err
+	}
+	return nil
 }
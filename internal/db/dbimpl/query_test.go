@@ -0,0 +1,86 @@
+package dbimpl_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ownerID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	otherID = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+)
+
+// forEachDatabase runs test against an empty SQLite database and, if TEST_DATABASE_DSN is set,
+// against the PostgreSQL database it names after emptying its tables.
+func forEachDatabase(t *testing.T, test func(t *testing.T, database db.DB)) {
+	t.Run("SQLite", func(t *testing.T) {
+		database, err := dbimpl.InitSQLite(filepath.Join(t.TempDir(), "shortener.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { database.Close() })
+		test(t, database)
+	})
+
+	t.Run("PostgreSQL", func(t *testing.T) {
+		dsn := os.Getenv("TEST_DATABASE_DSN")
+		if dsn == "" {
+			t.Skip("TEST_DATABASE_DSN is not set")
+		}
+		config.DBDSN = dsn
+		database, err := dbimpl.InitDB()
+		require.NoError(t, err)
+		t.Cleanup(func() { database.Close() })
+		_, err = database.Exec(context.Background(), "TRUNCATE shortened_urls, url_clicks")
+		require.NoError(t, err)
+		test(t, database)
+	})
+}
+
+// createShortURL stores originalURL under shortURL for userID.
+func createShortURL(t *testing.T, database db.DB, userID, shortURL, originalURL string) {
+	_, err := dbimpl.CreateShortURL(context.Background(), database, userID, shortURL, originalURL, nil, "", false)
+	require.NoError(t, err)
+}
+
+func TestMarkDeletedBatch(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, database db.DB) {
+		ctx := context.Background()
+		createShortURL(t, database, ownerID, "aaa", "https://example.com/a")
+		createShortURL(t, database, ownerID, "bbb", "https://example.com/b")
+		createShortURL(t, database, otherID, "ccc", "https://example.com/c")
+
+		marked, err := dbimpl.MarkDeletedBatch(ctx, database, []models.URLDeletion{
+			{UserID: ownerID, ShortURL: "aaa"},
+			{UserID: otherID, ShortURL: "bbb"},
+			{UserID: otherID, ShortURL: "ccc"},
+			{UserID: ownerID, ShortURL: "unknown"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), marked)
+
+		tests := []struct {
+			shortURL    string
+			expectedErr error
+		}{
+			{shortURL: "aaa", expectedErr: config.ErrGone},
+			{shortURL: "bbb", expectedErr: nil},
+			{shortURL: "ccc", expectedErr: config.ErrGone},
+		}
+		for _, tt := range tests {
+			_, err := dbimpl.GetOriginalURL(ctx, database, tt.shortURL)
+			assert.ErrorIs(t, err, tt.expectedErr, tt.shortURL)
+		}
+
+		marked, err = dbimpl.MarkDeletedBatch(ctx, database, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), marked)
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/db/migrations"
 	"github.com/gleb-korostelev/short-url.git/tools/logger"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -17,23 +18,62 @@ type Database struct {
 }
 
 // InitDB initializes and returns a new instance of Database.
-// It establishes a connection pool using the DSN provided in the configuration,
-// logs the connection status, and initializes necessary database tables.
+// It establishes a connection pool using the DSN provided in the configuration
+// and applies the pending schema migrations. The returned database traces the statements it runs.
 func InitDB() (db.DB, error) {
+	conn, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	data := NewTracedDB(conn)
+
+	migrator, err := migrations.NewMigrator(data)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		logger.Infof("Failed to apply migrations: %v", err)
+		data.Close()
+		return nil, err
+	}
+	return data, nil
+}
+
+// Connect establishes a connection pool using the DSN provided in the configuration
+// without touching the schema.
+func Connect() (*Database, error) {
 	connection, err := pgxpool.New(context.Background(), config.DBDSN)
 	if err != nil {
 		logger.Infof("Unable to connect to database: %v", err)
 		return nil, err
 	}
 	logger.Infof("Connected to database.")
-	data := &Database{Conn: connection}
+	return &Database{Conn: connection}, nil
+}
+
+// Dialect returns db.Postgres.
+func (database *Database) Dialect() db.Dialect {
+	return db.Postgres
+}
 
-	err = InitializeTables(data)
+// Begin starts a transaction on a connection of the pool.
+func (db *Database) Begin(ctx context.Context) (db.Tx, error) {
+	tx, err := db.Conn.Begin(ctx)
 	if err != nil {
-		logger.Infof("Failed to initialize tables: %v", err)
 		return nil, err
 	}
-	return data, nil
+	return pgTx{Tx: tx}, nil
+}
+
+// pgTx is a transaction of the pool, which runs PostgreSQL statements.
+type pgTx struct {
+	pgx.Tx
+}
+
+// Dialect returns db.Postgres.
+func (tx pgTx) Dialect() db.Dialect {
+	return db.Postgres
 }
 
 // GetConn retrieves the database connection pool.
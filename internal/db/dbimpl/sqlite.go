@@ -0,0 +1,331 @@
+package dbimpl
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/db/migrations"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteOptions are the connection parameters of the SQLite databases. A writer waits up to five
+// seconds for another to finish instead of failing, readers are not blocked by writers thanks to
+// the write-ahead log, and a transaction takes the write lock when it begins, so that two
+// transactions never deadlock upgrading their read locks.
+const sqliteOptions = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// sqliteTimeLayout is the layout of the times passed to SQLite. They are stored as text in UTC
+// with a fixed number of digits, so that comparing them as text compares them in time, and
+// the driver parses them back when they are read from a TIMESTAMP column.
+const sqliteTimeLayout = "2006-01-02 15:04:05.000000000-07:00"
+
+var (
+	// placeholderPattern matches the PostgreSQL placeholders of a statement.
+	placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+	// uniqueColumnPattern matches the column named by the message of a UNIQUE constraint
+	// violation, as in "UNIQUE constraint failed: shortened_urls.short_url".
+	uniqueColumnPattern = regexp.MustCompile(`UNIQUE constraint failed: \w+\.(\w+)`)
+)
+
+// InitSQLite opens the SQLite database file at path, creating it if needed, and applies the
+// pending schema migrations. The returned database traces the statements it runs.
+func InitSQLite(path string) (db.DB, error) {
+	conn, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	data := NewTracedDB(conn)
+
+	migrator, err := migrations.NewMigrator(data)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		logger.Infof("Failed to apply migrations: %v", err)
+		data.Close()
+		return nil, err
+	}
+	return data, nil
+}
+
+// OpenSQLite opens the SQLite database file at path, creating it if needed, without touching
+// the schema.
+func OpenSQLite(path string) (db.DB, error) {
+	conn, err := sql.Open("sqlite", "file:"+path+"?"+sqliteOptions)
+	if err != nil {
+		logger.Infof("Unable to open SQLite database: %v", err)
+		return nil, err
+	}
+	if err := conn.Ping(); err != nil {
+		logger.Infof("Unable to open SQLite database: %v", err)
+		conn.Close()
+		return nil, err
+	}
+	logger.Infof("Opened SQLite database %s.", path)
+	return &sqliteDB{sqliteQuerier: sqliteQuerier{conn: conn}, conn: conn}, nil
+}
+
+// sqlConn is the part of *sql.DB and *sql.Tx running statements.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// sqliteQuerier runs the statements written for db.Querier on a SQLite connection: it rewrites
+// the placeholders, converts the arguments SQLite has no type for and reports the results
+// the way pgx does.
+type sqliteQuerier struct {
+	conn sqlConn
+}
+
+// Dialect returns db.SQLite.
+func (q sqliteQuerier) Dialect() db.Dialect {
+	return db.SQLite
+}
+
+// Exec executes a SQL command and returns a tag made of the verb of the command and the number
+// of affected rows, such as "UPDATE 2".
+func (q sqliteQuerier) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	values, err := sqliteArgs(args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	result, err := q.conn.ExecContext(ctx, rebind(query), values...)
+	if err != nil {
+		return pgconn.CommandTag{}, sqliteError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	verb := strings.ToUpper(strings.Fields(query)[0])
+	return pgconn.NewCommandTag(fmt.Sprintf("%s %d", verb, affected)), nil
+}
+
+// Query executes a SQL query and returns its rows.
+func (q sqliteQuerier) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	values, err := sqliteArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.conn.QueryContext(ctx, rebind(query), values...)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	return &sqliteRows{rows: rows}, nil
+}
+
+// QueryRow executes a SQL query that is expected to return at most one row.
+// Scanning the row returns pgx.ErrNoRows if there is none.
+func (q sqliteQuerier) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	rows, err := q.Query(ctx, query, args...)
+	return &sqliteRow{rows: rows, err: err}
+}
+
+// sqliteDB is a db.DB stored in a SQLite database file.
+type sqliteDB struct {
+	sqliteQuerier
+	conn *sql.DB
+}
+
+// Close closes the database.
+func (s *sqliteDB) Close() error {
+	return s.conn.Close()
+}
+
+// Ping verifies that the database can be read.
+func (s *sqliteDB) Ping(ctx context.Context) error {
+	return s.conn.PingContext(ctx)
+}
+
+// Begin starts a transaction holding the write lock of the database.
+func (s *sqliteDB) Begin(ctx context.Context) (db.Tx, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, sqliteError(err)
+	}
+	return &sqliteTx{sqliteQuerier: sqliteQuerier{conn: tx}, tx: tx}, nil
+}
+
+// sqliteTx is a transaction of a SQLite database.
+type sqliteTx struct {
+	sqliteQuerier
+	tx *sql.Tx
+}
+
+// Commit commits the transaction.
+func (t *sqliteTx) Commit(context.Context) error {
+	return sqliteError(t.tx.Commit())
+}
+
+// Rollback rolls the transaction back.
+func (t *sqliteTx) Rollback(context.Context) error {
+	return t.tx.Rollback()
+}
+
+// sqliteRows adapts the rows of a SQLite query to pgx.Rows.
+type sqliteRows struct {
+	rows *sql.Rows
+}
+
+// Close closes the rows.
+func (r *sqliteRows) Close() {
+	r.rows.Close()
+}
+
+// Err returns the error met while reading the rows, if any.
+func (r *sqliteRows) Err() error {
+	return sqliteError(r.rows.Err())
+}
+
+// CommandTag returns an empty tag, since SQLite reports no outcome for queries.
+func (r *sqliteRows) CommandTag() pgconn.CommandTag {
+	return pgconn.CommandTag{}
+}
+
+// FieldDescriptions returns nil, since the columns of SQLite have no PostgreSQL description.
+func (r *sqliteRows) FieldDescriptions() []pgconn.FieldDescription {
+	return nil
+}
+
+// Next prepares the next row for reading, closing the rows after the last one.
+func (r *sqliteRows) Next() bool {
+	return r.rows.Next()
+}
+
+// Scan reads the values of the current row into dest.
+func (r *sqliteRows) Scan(dest ...any) error {
+	return r.rows.Scan(dest...)
+}
+
+// Values returns the values of the current row.
+func (r *sqliteRows) Values() ([]any, error) {
+	columns, err := r.rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// RawValues returns nil, since SQLite values have no PostgreSQL wire encoding.
+func (r *sqliteRows) RawValues() [][]byte {
+	return nil
+}
+
+// Conn returns nil, since the rows do not come from a pgx connection.
+func (r *sqliteRows) Conn() *pgx.Conn {
+	return nil
+}
+
+// sqliteRow is the single row of a query run by QueryRow.
+type sqliteRow struct {
+	rows pgx.Rows // rows are the rows of the query, nil if it failed.
+	err  error    // err is the error the query failed with.
+}
+
+// Scan reads the values of the row into dest. It returns pgx.ErrNoRows if there is no row.
+func (r *sqliteRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+// rebind replaces the PostgreSQL placeholders $1, $2, ... of query with the equivalent ?1, ?2, ...
+// of SQLite.
+func rebind(query string) string {
+	return placeholderPattern.ReplaceAllString(query, "?$1")
+}
+
+// sqliteArgs converts the arguments of a statement to values SQLite stores: times are formatted
+// with sqliteTimeLayout and arrays other than byte slices are encoded as JSON arrays, to be read
+// with json_each.
+func sqliteArgs(args []interface{}) ([]any, error) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		value, err := sqliteArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// sqliteArg converts a single argument as sqliteArgs does.
+func sqliteArg(arg any) (any, error) {
+	switch v := arg.(type) {
+	case time.Time:
+		return v.UTC().Format(sqliteTimeLayout), nil
+	case *time.Time:
+		if v == nil {
+			return nil, nil
+		}
+		return v.UTC().Format(sqliteTimeLayout), nil
+	}
+
+	value := reflect.ValueOf(arg)
+	if value.Kind() != reflect.Slice || value.Type().Elem().Kind() == reflect.Uint8 {
+		return arg, nil
+	}
+	elements := make([]any, value.Len())
+	for i := range elements {
+		element, err := sqliteArg(value.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = element
+	}
+	data, err := json.Marshal(elements)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// sqliteError translates a violation of a UNIQUE constraint into a *uniqueViolationError naming
+// the column, as PostgreSQL names the constraint. Other errors are returned as is.
+func sqliteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return err
+	}
+	match := uniqueColumnPattern.FindStringSubmatch(sqliteErr.Error())
+	if match == nil {
+		return err
+	}
+	return &uniqueViolationError{column: match[1], err: err}
+}
@@ -0,0 +1,176 @@
+package dbimpl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// tracedDB wraps a db.DB and records a client span for every statement it runs, including those
+// of the transactions it begins. The spans are children of the span carried by the context of the call.
+type tracedDB struct {
+	db.DB
+}
+
+// NewTracedDB creates a db.DB that traces the Ping, Exec, Query, QueryRow and Begin calls of next
+// and the statements, commit and rollback of the transactions it begins.
+func NewTracedDB(next db.DB) db.DB {
+	return &tracedDB{DB: next}
+}
+
+// startSpan starts a client span for a call to a database of the given dialect, recording the
+// statement if any.
+func startSpan(ctx context.Context, dialect db.Dialect, name, query string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, name, tracing.WithKind(tracing.KindClient),
+		tracing.WithAttributes("db.system", string(dialect)))
+	if query != "" {
+		span.SetAttribute("db.statement", strings.Join(strings.Fields(query), " "))
+	}
+	return ctx, span
+}
+
+// Ping traces Ping of the wrapped database.
+func (t *tracedDB) Ping(ctx context.Context) error {
+	ctx, span := startSpan(ctx, t.Dialect(), "db.Ping", "")
+	defer span.End()
+	err := t.DB.Ping(ctx)
+	span.RecordError(err)
+	return err
+}
+
+// Exec traces Exec of the wrapped database.
+func (t *tracedDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tracedExec(ctx, t.DB, "db.Exec", query, args)
+}
+
+// Query traces Query of the wrapped database.
+func (t *tracedDB) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return tracedQuery(ctx, t.DB, "db.Query", query, args)
+}
+
+// QueryRow traces QueryRow of the wrapped database.
+func (t *tracedDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return tracedQueryRow(ctx, t.DB, "db.QueryRow", query, args)
+}
+
+// Begin traces Begin of the wrapped database and returns a transaction whose calls are traced in turn.
+func (t *tracedDB) Begin(ctx context.Context) (db.Tx, error) {
+	ctx, span := startSpan(ctx, t.Dialect(), "db.Begin", "")
+	defer span.End()
+	tx, err := t.DB.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return &tracedTx{Tx: tx}, nil
+}
+
+// tracedTx wraps a db.Tx and records a client span for every statement it runs and for its
+// commit or rollback.
+type tracedTx struct {
+	db.Tx
+	done bool // done is set once the transaction is committed or rolled back.
+}
+
+// Exec traces Exec of the wrapped transaction.
+func (t *tracedTx) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tracedExec(ctx, t.Tx, "db.Tx.Exec", query, args)
+}
+
+// Query traces Query of the wrapped transaction.
+func (t *tracedTx) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return tracedQuery(ctx, t.Tx, "db.Tx.Query", query, args)
+}
+
+// QueryRow traces QueryRow of the wrapped transaction.
+func (t *tracedTx) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return tracedQueryRow(ctx, t.Tx, "db.Tx.QueryRow", query, args)
+}
+
+// Commit traces Commit of the wrapped transaction.
+func (t *tracedTx) Commit(ctx context.Context) error {
+	ctx, span := startSpan(ctx, t.Dialect(), "db.Tx.Commit", "")
+	defer span.End()
+	t.done = true
+	err := t.Tx.Commit(ctx)
+	span.RecordError(err)
+	return err
+}
+
+// Rollback traces Rollback of the wrapped transaction. The rollback deferred after a commit or an
+// earlier rollback is not traced, since it does nothing.
+func (t *tracedTx) Rollback(ctx context.Context) error {
+	if t.done {
+		return t.Tx.Rollback(ctx)
+	}
+	ctx, span := startSpan(ctx, t.Dialect(), "db.Tx.Rollback", "")
+	defer span.End()
+	t.done = true
+	err := t.Tx.Rollback(ctx)
+	span.RecordError(err)
+	return err
+}
+
+// tracedExec traces Exec of q in a span named name, recording the number of affected rows.
+func tracedExec(ctx context.Context, q db.Querier, name, query string, args []interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startSpan(ctx, q.Dialect(), name, query)
+	defer span.End()
+	tag, err := q.Exec(ctx, query, args...)
+	span.RecordError(err)
+	if err == nil {
+		span.SetAttribute("db.rows_affected", tag.RowsAffected())
+	}
+	return tag, err
+}
+
+// tracedQuery traces Query of q in a span named name. The span ends when the returned rows are
+// closed, so it covers reading the rows as well.
+func tracedQuery(ctx context.Context, q db.Querier, name, query string, args []interface{}) (pgx.Rows, error) {
+	ctx, span := startSpan(ctx, q.Dialect(), name, query)
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+// tracedQueryRow traces QueryRow of q in a span named name. The span ends when the row is scanned.
+func tracedQueryRow(ctx context.Context, q db.Querier, name, query string, args []interface{}) pgx.Row {
+	ctx, span := startSpan(ctx, q.Dialect(), name, query)
+	return &tracedRow{row: q.QueryRow(ctx, query, args...), span: span}
+}
+
+// tracedRows ends the span of a query when its rows are closed.
+type tracedRows struct {
+	pgx.Rows
+	span *tracing.Span
+}
+
+// Close closes the rows and ends the span, recording the error of the iteration if any.
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	r.span.RecordError(r.Rows.Err())
+	r.span.End()
+}
+
+// tracedRow ends the span of a single row query when the row is scanned.
+type tracedRow struct {
+	row  pgx.Row
+	span *tracing.Span
+}
+
+// Scan scans the row and ends the span. pgx.ErrNoRows is not recorded as a failure.
+func (r *tracedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err != pgx.ErrNoRows {
+		r.span.RecordError(err)
+	}
+	r.span.End()
+	return err
+}
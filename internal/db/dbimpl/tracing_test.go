@@ -0,0 +1,134 @@
+package dbimpl_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingExporter keeps the exported spans in memory.
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Close() error {
+	return nil
+}
+
+// fakeDB is a SQLite database beginning fakeTx transactions; its other methods are not expected
+// to be called.
+type fakeDB struct {
+	db.DB
+	tx *fakeTx
+}
+
+func (d *fakeDB) Dialect() db.Dialect {
+	return db.SQLite
+}
+
+func (d *fakeDB) Begin(context.Context) (db.Tx, error) {
+	return d.tx, nil
+}
+
+// fakeTx records whether it was committed or rolled back and affects one row per statement.
+type fakeTx struct {
+	db.Tx
+	committed, rolledBack bool
+}
+
+func (t *fakeTx) Dialect() db.Dialect {
+	return db.SQLite
+}
+
+func (t *fakeTx) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (t *fakeTx) Commit(context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *fakeTx) Rollback(context.Context) error {
+	if t.committed || t.rolledBack {
+		return errors.New("tx is closed")
+	}
+	t.rolledBack = true
+	return nil
+}
+
+func TestTracedWithTx(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name          string
+		fn            func(ctx context.Context, tx db.Tx) error
+		expectedErr   error
+		expectedSpans []string
+	}{
+		{
+			name: "Committed",
+			fn: func(ctx context.Context, tx db.Tx) error {
+				_, err := tx.Exec(ctx, "UPDATE shortened_urls SET is_deleted = TRUE")
+				return err
+			},
+			expectedSpans: []string{"db.Begin", "db.Tx.Exec", "db.Tx.Commit"},
+		},
+		{
+			name: "Rolled Back",
+			fn: func(ctx context.Context, tx db.Tx) error {
+				return errFailed
+			},
+			expectedErr:   errFailed,
+			expectedSpans: []string{"db.Begin", "db.Tx.Rollback"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &recordingExporter{}
+			tracer := tracing.NewTracer(exporter, 16, 16, time.Hour, time.Second)
+			tracing.SetTracer(tracer)
+			defer tracing.SetTracer(nil)
+
+			ctx, parent := tracing.Start(context.Background(), "parent")
+			tx := &fakeTx{}
+			err := dbimpl.WithTx(ctx, dbimpl.NewTracedDB(&fakeDB{tx: tx}), func(tx db.Tx) error {
+				return tt.fn(ctx, tx)
+			})
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedErr == nil, tx.committed)
+			parent.End()
+			require.NoError(t, tracer.Shutdown(context.Background()))
+
+			var names []string
+			for _, span := range exporter.spans {
+				if span.Name == "parent" {
+					continue
+				}
+				names = append(names, span.Name)
+				assert.Equal(t, parent.SpanContext().SpanID, span.ParentSpanID)
+				assert.Equal(t, tracing.KindClient, span.Kind)
+				assert.Equal(t, "sqlite", span.Attributes["db.system"])
+				assert.Empty(t, span.Error)
+			}
+			assert.ElementsMatch(t, tt.expectedSpans, names)
+		})
+	}
+}
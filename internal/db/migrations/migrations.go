@@ -0,0 +1,280 @@
+// Package migrations contains the versioned SQL schema of the database storage and the runner
+// that applies it. Migrations are embedded into the binary, one directory per SQL dialect,
+// applied in version order and recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+// advisoryLockID identifies the PostgreSQL advisory lock held while migrations run,
+// so that replicas starting at the same time apply them one after another.
+const advisoryLockID int64 = 0x73686f72746e6572
+
+//go:embed sql/postgres/*.sql sql/sqlite/*.sql
+var files embed.FS
+
+// dirs maps every supported dialect to the directory of files holding its migrations.
+var dirs = map[db.Dialect]string{
+	db.Postgres: "sql/postgres",
+	db.SQLite:   "sql/sqlite",
+}
+
+// fileNamePattern matches migration file names such as 0001_create_shortened_urls.up.sql.
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrNoMigrationApplied indicates that there is no applied migration to roll back.
+var ErrNoMigrationApplied = errors.New("no migration has been applied")
+
+// Migration is a single schema change with the SQL to apply and to revert it.
+type Migration struct {
+	Version int    // Version orders the migrations; it is the numeric prefix of the file name.
+	Name    string // Name is the descriptive part of the file name.
+	Up      string // Up is the SQL that applies the change.
+	Down    string // Down is the SQL that reverts the change.
+}
+
+// Status describes whether a migration has been applied to the database.
+type Status struct {
+	Migration
+	Applied   bool       // Applied reports whether the migration is recorded in schema_migrations.
+	AppliedAt *time.Time // AppliedAt is the moment the migration was applied, if it was.
+}
+
+// Load reads the embedded migrations of a dialect and returns them ordered by version.
+// Every migration must have an up file; the down file is optional.
+func Load(dialect db.Dialect) ([]Migration, error) {
+	dir, ok := dirs[dialect]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for SQL dialect %q", dialect)
+	}
+	return load(files, dir)
+}
+
+// load reads migrations from the directory dir of fsys.
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		body, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations on a database.
+type Migrator struct {
+	db         db.DB       // db is the database the migrations are applied to.
+	migrations []Migration // migrations are the known migrations ordered by version.
+}
+
+// NewMigrator creates a Migrator for the embedded migrations of the dialect of db.
+func NewMigrator(db db.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every migration that has not been applied yet, in version order.
+// All of them are applied in one transaction holding the migrations lock, so either the
+// schema is brought fully up to date or it is left unchanged.
+// It returns the number of migrations that were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(tx db.Tx) error {
+		done, err := appliedVersions(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if _, err := tx.Exec(ctx, migration.Up); err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			sql := `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+			if _, err := tx.Exec(ctx, sql, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			logger.Infof("Applied migration %d_%s", migration.Version, migration.Name)
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// Down reverts the most recently applied migration.
+// It returns the reverted migration, or ErrNoMigrationApplied if there is nothing to revert.
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	var reverted Migration
+	err := m.withLock(ctx, func(tx db.Tx) error {
+		var version int
+		sql := `SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1`
+		if err := tx.QueryRow(ctx, sql).Scan(&version); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoMigrationApplied
+			}
+			return err
+		}
+
+		migration, ok := m.find(version)
+		if !ok {
+			return fmt.Errorf("applied migration %d is unknown to this binary", version)
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+		}
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version); err != nil {
+			return err
+		}
+		logger.Infof("Reverted migration %d_%s", migration.Version, migration.Name)
+		reverted = migration
+		return nil
+	})
+	return reverted, err
+}
+
+// Status reports for every known migration whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(tx db.Tx) error {
+		done, err := appliedVersions(ctx, tx)
+		if err != nil {
+			return err
+		}
+		statuses = make([]Status, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := done[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// find returns the known migration with the given version.
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// withLock runs fn in a transaction that holds the migrations lock and has ensured that the
+// schema_migrations table exists. The transaction is committed if fn succeeds.
+// On PostgreSQL the lock is the advisory lock; a SQLite transaction holds the lock of the whole
+// database from its start.
+func (m *Migrator) withLock(ctx context.Context, fn func(tx db.Tx) error) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	appliedAtType := "TIMESTAMP"
+	if tx.Dialect() == db.Postgres {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, advisoryLockID); err != nil {
+			return err
+		}
+		appliedAtType = "TIMESTAMP WITH TIME ZONE"
+	}
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at ` + appliedAtType + ` NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	if _, err := tx.Exec(ctx, createTableSQL); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// appliedVersions returns the versions recorded in schema_migrations with the moment they were applied.
+func appliedVersions(ctx context.Context, tx db.Querier) (map[int]time.Time, error) {
+	rows, err := tx.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	for _, dialect := range []db.Dialect{db.Postgres, db.SQLite} {
+		t.Run(string(dialect), func(t *testing.T) {
+			migrations, err := Load(dialect)
+			assert.NoError(t, err)
+			if assert.NotEmpty(t, migrations) {
+				assert.Equal(t, 1, migrations[0].Version)
+				assert.Equal(t, "create_shortened_urls", migrations[0].Name)
+			}
+			for i, m := range migrations {
+				assert.NotEmpty(t, m.Up, "migration %d has no up SQL", m.Version)
+				assert.NotEmpty(t, m.Down, "migration %d has no down SQL", m.Version)
+				if i > 0 {
+					assert.Greater(t, m.Version, migrations[i-1].Version)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadUnknownDialect(t *testing.T) {
+	_, err := Load("oracle")
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       fstest.MapFS
+		expectedErr bool
+		expected    []Migration
+	}{
+		{
+			name: "Ordered By Version",
+			files: fstest.MapFS{
+				"sql/0010_second.up.sql":  {Data: []byte("B")},
+				"sql/0002_first.up.sql":   {Data: []byte("A")},
+				"sql/0002_first.down.sql": {Data: []byte("a")},
+			},
+			expected: []Migration{
+				{Version: 2, Name: "first", Up: "A", Down: "a"},
+				{Version: 10, Name: "second", Up: "B"},
+			},
+		},
+		{
+			name: "Missing Up File",
+			files: fstest.MapFS{
+				"sql/0001_first.down.sql": {Data: []byte("a")},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Conflicting Names",
+			files: fstest.MapFS{
+				"sql/0001_first.up.sql":   {Data: []byte("A")},
+				"sql/0001_other.up.sql":   {Data: []byte("B")},
+				"sql/0001_first.down.sql": {Data: []byte("a")},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Unexpected File Name",
+			files: fstest.MapFS{
+				"sql/first.sql": {Data: []byte("A")},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.files, "sql")
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, migrations)
+		})
+	}
+}
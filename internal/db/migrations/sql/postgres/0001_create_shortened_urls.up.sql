@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS shortened_urls (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    short_url VARCHAR(255) UNIQUE NOT NULL,
+    original_url VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    is_deleted BOOLEAN DEFAULT FALSE
+);
@@ -0,0 +1 @@
+ALTER TABLE shortened_urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1 @@
+ALTER TABLE shortened_urls ALTER COLUMN original_url TYPE VARCHAR(255);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS shortened_urls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    short_url TEXT UNIQUE NOT NULL,
+    original_url TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    is_deleted BOOLEAN DEFAULT FALSE,
+    expires_at TIMESTAMP,
+    password_hash TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS url_clicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url TEXT NOT NULL,
+    clicked_at TIMESTAMP NOT NULL,
+    referrer TEXT,
+    user_agent TEXT,
+    ip_hash TEXT
+);
+CREATE INDEX IF NOT EXISTS url_clicks_short_url_idx ON url_clicks (short_url, clicked_at);
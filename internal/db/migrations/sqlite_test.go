@@ -0,0 +1,47 @@
+package migrations_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/db/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigratorSQLite(t *testing.T) {
+	ctx := context.Background()
+	data, err := dbimpl.OpenSQLite(filepath.Join(t.TempDir(), "urls.sqlite"))
+	require.NoError(t, err)
+	defer data.Close()
+	migrator, err := migrations.NewMigrator(data)
+	require.NoError(t, err)
+	known, err := migrations.Load(data.Dialect())
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(known), applied)
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(known))
+	for _, status := range statuses {
+		assert.True(t, status.Applied, "migration %d is not applied", status.Version)
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	for i := len(known) - 1; i >= 0; i-- {
+		reverted, err := migrator.Down(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, known[i].Version, reverted.Version)
+	}
+	_, err = migrator.Down(ctx)
+	assert.ErrorIs(t, err, migrations.ErrNoMigrationApplied)
+}
@@ -1,5 +1,6 @@
 // Package db provides the database interface and utilities for handling
-// connections and operations with PostgreSQL using the pgx library.
+// connections and operations with PostgreSQL using the pgx library, or with SQLite
+// through an adapter speaking the same interface.
 package db
 
 import (
@@ -7,18 +8,25 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// DB defines the interface for database operations that any DB implementation must satisfy.
-// It provides methods for executing queries and commands, and for managing the database connection.
-type DB interface {
-	// Close terminates the database connection.
-	Close() error
+// Dialect names the SQL dialect of a database, for the statements that cannot be written the
+// same way for every database.
+type Dialect string
 
-	// Ping tests the connectivity with the database.
-	// It returns an error if the database is not reachable.
-	Ping(ctx context.Context) error
+const (
+	// Postgres is the dialect of PostgreSQL.
+	Postgres Dialect = "postgresql"
+
+	// SQLite is the dialect of SQLite.
+	SQLite Dialect = "sqlite"
+)
+
+// Querier runs SQL statements, either directly on the database or within a transaction.
+// Statements use PostgreSQL placeholders ($1, $2, ...) whatever the dialect.
+type Querier interface {
+	// Dialect returns the SQL dialect of the database the statements run on.
+	Dialect() Dialect
 
 	// Exec executes a SQL query without returning any rows.
 	// The args are for any placeholder parameters in the query.
@@ -34,8 +42,33 @@ type DB interface {
 	// The args are for any placeholder parameters in the query.
 	// QueryRow returns a Row, which is a lazy loader for fetching the single row.
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+}
+
+// DB defines the interface for database operations that any DB implementation must satisfy.
+// It provides methods for executing queries and commands, and for managing the database connection.
+type DB interface {
+	Querier
+
+	// Close terminates the database connection.
+	Close() error
+
+	// Ping tests the connectivity with the database.
+	// It returns an error if the database is not reachable.
+	Ping(ctx context.Context) error
+
+	// Begin starts a transaction, whose statements run on a single connection until it is
+	// committed or rolled back.
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// Tx is a transaction started by DB.Begin.
+type Tx interface {
+	Querier
+
+	// Commit commits the transaction.
+	Commit(ctx context.Context) error
 
-	// GetConn returns a connection pool object which can be used to execute multiple queries
-	// with a single database connection. It is useful for transactions.
-	GetConn(ctx context.Context) *pgxpool.Pool
+	// Rollback rolls the transaction back. Rolling back a transaction that has already been
+	// committed or rolled back does nothing but return an error, so Rollback may be deferred.
+	Rollback(ctx context.Context) error
 }
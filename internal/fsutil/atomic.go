@@ -0,0 +1,71 @@
+// Package fsutil provides the file handling shared by the file-backed components: replacing a file
+// atomically and replaying a file of lines appended one at a time, such as a journal.
+package fsutil
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// WriteFile atomically replaces the file at path with the content written by write. The content is
+// written to a temporary file in the same directory, fsynced and renamed over the old file, and the
+// directory is fsynced in turn, so that a crash leaves either the old file or the new one.
+// The new file keeps the mode of the file it replaces, or gets perm if there is none.
+func WriteFile(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	if err := write(writer); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if err := SyncDir(filepath.Dir(path)); err != nil {
+		logger.Errorf("Error syncing directory of %s: %v", path, err)
+	}
+	return nil
+}
+
+// SyncDir fsyncs a directory, so that a file created in or renamed into it survives a crash.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
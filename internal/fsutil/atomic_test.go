@@ -0,0 +1,84 @@
+package fsutil_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/fsutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeString returns a write function writing s.
+func writeString(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		existingMode os.FileMode
+		write        func(w io.Writer) error
+		expectedData string
+		expectedMode os.FileMode
+		expectedErr  bool
+	}{
+		{
+			name:         "New File",
+			write:        writeString("new\n"),
+			expectedData: "new\n",
+			expectedMode: 0644,
+		},
+		{
+			name:         "Keeps Mode Of Replaced File",
+			existingMode: 0600,
+			write:        writeString("new\n"),
+			expectedData: "new\n",
+			expectedMode: 0600,
+		},
+		{
+			name:         "Failed Write Leaves File Unchanged",
+			existingMode: 0640,
+			write:        func(w io.Writer) error { return errors.New("write failed") },
+			expectedData: "old\n",
+			expectedMode: 0640,
+			expectedErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "file")
+			if tt.existingMode != 0 {
+				require.NoError(t, os.WriteFile(path, []byte("old\n"), tt.existingMode))
+				require.NoError(t, os.Chmod(path, tt.existingMode))
+			}
+
+			err := fsutil.WriteFile(path, 0644, tt.write)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedData, string(data))
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMode, info.Mode().Perm())
+
+			// No temporary file is left behind.
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package fsutil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// ReplayLines calls fn with every complete line of the file at path, in order, numbered from 1.
+// Blank lines are skipped and a missing file is treated as empty. It returns the length of the
+// complete lines, which is where the next line is to be appended.
+//
+// Lines are appended one at a time, so only the last one can be cut short by a crash. Such a torn
+// last line recorded a change that was never acknowledged: it is truncated away rather than passed
+// to fn. Any error returned by fn, such as for a line that cannot be decoded, stops the replay.
+func ReplayLines(path string, fn func(line int, data []byte) error) (int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var size int64 // size is the length of the complete lines read so far.
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				logger.Errorf("Truncating torn last line %d of %s", line, path)
+				if err := os.Truncate(path, size); err != nil {
+					return 0, err
+				}
+			}
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += int64(len(data))
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if err := fn(line, data); err != nil {
+			return 0, err
+		}
+	}
+}
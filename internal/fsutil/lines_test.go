@@ -0,0 +1,83 @@
+package fsutil_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/fsutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayLines(t *testing.T) {
+	errBadLine := errors.New("bad line")
+	tests := []struct {
+		name          string
+		content       string
+		expectedLines []string
+		expectedSize  int64
+		expectedFile  string
+		expectedErr   error
+	}{
+		{
+			name:          "Complete Lines",
+			content:       "a\n\nb\n",
+			expectedLines: []string{"1:a\n", "3:b\n"},
+			expectedSize:  5,
+			expectedFile:  "a\n\nb\n",
+		},
+		{
+			name:          "Torn Last Line Is Truncated",
+			content:       "a\nb\n{\"torn",
+			expectedLines: []string{"1:a\n", "2:b\n"},
+			expectedSize:  4,
+			expectedFile:  "a\nb\n",
+		},
+		{
+			name:          "Bad Line Stops The Replay",
+			content:       "a\nbad\nb\n",
+			expectedLines: []string{"1:a\n"},
+			expectedFile:  "a\nbad\nb\n",
+			expectedErr:   errBadLine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "journal")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			var lines []string
+			size, err := fsutil.ReplayLines(path, func(line int, data []byte) error {
+				if string(data) == "bad\n" {
+					return errBadLine
+				}
+				lines = append(lines, strconv.Itoa(line)+":"+string(data))
+				return nil
+			})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedSize, size)
+			}
+			assert.Equal(t, tt.expectedLines, lines)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedFile, string(data))
+		})
+	}
+}
+
+func TestReplayLinesMissingFile(t *testing.T) {
+	size, err := fsutil.ReplayLines(filepath.Join(t.TempDir(), "missing"), func(int, []byte) error {
+		t.Fatal("unexpected line")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Zero(t, size)
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/db"
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+)
+
+// dbQueue keeps the jobs in the jobs table of the database. Several instances of the service
+// may drain the same table, since claims skip the rows locked by other claims.
+type dbQueue struct {
+	data  db.DB         // data is the database holding the jobs table.
+	lease time.Duration // lease is how long a claimed job is reserved.
+}
+
+// NewDBQueue creates a queue stored in the jobs table of data, leasing claimed jobs for lease.
+// The database is owned by the caller and is not closed by Close.
+func NewDBQueue(data db.DB, lease time.Duration) Queue {
+	return &dbQueue{data: data, lease: lease}
+}
+
+// Enqueue inserts a new job.
+func (q *dbQueue) Enqueue(ctx context.Context, job models.Job) error {
+	return dbimpl.InsertJob(ctx, q.data, job)
+}
+
+// Claim leases the job that has been due the longest.
+func (q *dbQueue) Claim(ctx context.Context, now time.Time) (models.Job, bool, error) {
+	return dbimpl.ClaimJob(ctx, q.data, now, q.lease)
+}
+
+// Complete marks a job as done.
+func (q *dbQueue) Complete(ctx context.Context, id string) error {
+	now := time.Now()
+	return dbimpl.UpdateJobStatus(ctx, q.data, id, config.JobStatusDone, "", now, now)
+}
+
+// Retry makes a failed job due again at next.
+func (q *dbQueue) Retry(ctx context.Context, id, lastError string, next time.Time) error {
+	return dbimpl.UpdateJobStatus(ctx, q.data, id, config.JobStatusPending, lastError, next, time.Now())
+}
+
+// Bury dead-letters a failed job.
+func (q *dbQueue) Bury(ctx context.Context, id, lastError string) error {
+	now := time.Now()
+	return dbimpl.UpdateJobStatus(ctx, q.data, id, config.JobStatusDead, lastError, now, now)
+}
+
+// Get retrieves a job by its ID.
+func (q *dbQueue) Get(ctx context.Context, id string) (models.Job, error) {
+	return dbimpl.GetJob(ctx, q.data, id)
+}
+
+// Prune deletes the finished jobs last updated before the given moment.
+func (q *dbQueue) Prune(ctx context.Context, before time.Time) (int, error) {
+	removed, err := dbimpl.DeleteFinishedJobs(ctx, q.data, before)
+	return int(removed), err
+}
+
+// Close does nothing, since the database is owned by the caller.
+func (q *dbQueue) Close() error {
+	return nil
+}
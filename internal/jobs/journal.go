@@ -0,0 +1,219 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/fsutil"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+)
+
+// journalQueue keeps the jobs in memory and, unless it is memory only, records every change of a
+// job by appending the whole job as a JSON line to a journal file. Replaying the journal in order
+// restores the latest state of every job; Prune rewrites it without the removed jobs.
+type journalQueue struct {
+	mu    sync.Mutex            // mu protects jobs and file.
+	jobs  map[string]models.Job // jobs holds the latest state of every job by ID.
+	lease time.Duration         // lease is how long a claimed job is reserved.
+	path  string                // path is the journal file; empty keeps the jobs in memory only.
+	file  *os.File              // file is the journal opened for appending, nil if memory only.
+}
+
+// NewMemoryQueue creates a queue that keeps the jobs in memory only, leasing claimed jobs for lease.
+// Its jobs are lost when the process exits.
+func NewMemoryQueue(lease time.Duration) Queue {
+	return &journalQueue{jobs: make(map[string]models.Job), lease: lease}
+}
+
+// OpenJournalQueue creates a queue journaled to the file at path, leasing claimed jobs for lease.
+// An existing journal is replayed first. A last line cut short by a crash is truncated away, since
+// the change it recorded was never acknowledged.
+func OpenJournalQueue(path string, lease time.Duration) (Queue, error) {
+	q := &journalQueue{jobs: make(map[string]models.Job), lease: lease, path: path}
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	q.file = file
+	return q, nil
+}
+
+// replay loads the jobs recorded in the journal, if it exists, and truncates an incomplete last line.
+func (q *journalQueue) replay() error {
+	_, err := fsutil.ReplayLines(q.path, func(line int, data []byte) error {
+		var job models.Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return fmt.Errorf("job journal %s:%d: %w", q.path, line, err)
+		}
+		q.jobs[job.ID] = job
+		return nil
+	})
+	return err
+}
+
+// record stores the new state of a job, appending it to the journal first.
+// The caller must hold q.mu.
+func (q *journalQueue) record(job models.Job) error {
+	if q.file != nil {
+		line, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		if _, err := q.file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		if err := q.file.Sync(); err != nil {
+			return err
+		}
+	}
+	q.jobs[job.ID] = job
+	return nil
+}
+
+// Enqueue records a new job.
+func (q *journalQueue) Enqueue(_ context.Context, job models.Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.record(job)
+}
+
+// Claim leases the job that has been due the longest.
+func (q *journalQueue) Claim(_ context.Context, now time.Time) (models.Job, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due models.Job
+	found := false
+	for _, job := range q.jobs {
+		if job.Status != config.JobStatusPending && job.Status != config.JobStatusRunning {
+			continue
+		}
+		if job.NextAttemptAt.After(now) {
+			continue
+		}
+		if !found || job.NextAttemptAt.Before(due.NextAttemptAt) {
+			due, found = job, true
+		}
+	}
+	if !found {
+		return models.Job{}, false, nil
+	}
+
+	due.Status = config.JobStatusRunning
+	due.Attempts++
+	due.NextAttemptAt = now.Add(q.lease)
+	due.UpdatedAt = now
+	if err := q.record(due); err != nil {
+		return models.Job{}, false, err
+	}
+	return due, true, nil
+}
+
+// update records the outcome of an attempt of a job.
+func (q *journalQueue) update(id, status, lastError string, next time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return config.ErrNotFound
+	}
+	job.Status = status
+	job.LastError = lastError
+	job.NextAttemptAt = next
+	job.UpdatedAt = time.Now()
+	return q.record(job)
+}
+
+// Complete marks a job as done.
+func (q *journalQueue) Complete(_ context.Context, id string) error {
+	return q.update(id, config.JobStatusDone, "", time.Now())
+}
+
+// Retry makes a failed job due again at next.
+func (q *journalQueue) Retry(_ context.Context, id, lastError string, next time.Time) error {
+	return q.update(id, config.JobStatusPending, lastError, next)
+}
+
+// Bury dead-letters a failed job.
+func (q *journalQueue) Bury(_ context.Context, id, lastError string) error {
+	return q.update(id, config.JobStatusDead, lastError, time.Now())
+}
+
+// Get retrieves a job by its ID.
+func (q *journalQueue) Get(_ context.Context, id string) (models.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return models.Job{}, config.ErrNotFound
+	}
+	return job, nil
+}
+
+// Prune removes the finished jobs last updated before the given moment and, if any were removed,
+// compacts the journal.
+func (q *journalQueue) Prune(_ context.Context, before time.Time) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	removed := 0
+	for id, job := range q.jobs {
+		if (job.Status == config.JobStatusDone || job.Status == config.JobStatusDead) && job.UpdatedAt.Before(before) {
+			delete(q.jobs, id)
+			removed++
+		}
+	}
+	if removed == 0 || q.file == nil {
+		return removed, nil
+	}
+	return removed, q.compact()
+}
+
+// compact replaces the journal with one holding a single line per remaining job. The journal is
+// replaced atomically, so a crash leaves either the old journal or the new one.
+// The caller must hold q.mu.
+func (q *journalQueue) compact() error {
+	err := fsutil.WriteFile(q.path, 0644, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for _, job := range q.jobs {
+			if err := encoder.Encode(job); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	q.file.Close()
+	q.file = file
+	return nil
+}
+
+// Close closes the journal.
+func (q *journalQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
@@ -0,0 +1,154 @@
+package jobs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/jobs"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newJob returns a pending job due at the given moment.
+func newJob(id string, due time.Time) models.Job {
+	return models.Job{
+		ID:            id,
+		UserID:        "user",
+		ShortURLs:     []string{"abc"},
+		Status:        config.JobStatusPending,
+		NextAttemptAt: due,
+		CreatedAt:     due,
+		UpdatedAt:     due,
+	}
+}
+
+func TestQueueLifecycle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	queue := jobs.NewMemoryQueue(time.Minute)
+	defer queue.Close()
+
+	require.NoError(t, queue.Enqueue(ctx, newJob("later", now.Add(time.Second))))
+	require.NoError(t, queue.Enqueue(ctx, newJob("first", now.Add(-time.Second))))
+
+	// The job due the longest is claimed first and jobs that are not due are left alone.
+	job, ok, err := queue.Claim(ctx, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "first", job.ID)
+	assert.Equal(t, config.JobStatusRunning, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+
+	_, ok, err = queue.Claim(ctx, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// A retried job is claimed again once it is due.
+	require.NoError(t, queue.Retry(ctx, "first", "database is down", now.Add(time.Second)))
+	job, err = queue.Get(ctx, "first")
+	require.NoError(t, err)
+	assert.Equal(t, config.JobStatusPending, job.Status)
+	assert.Equal(t, "database is down", job.LastError)
+
+	job, ok, err = queue.Claim(ctx, now.Add(2*time.Second))
+	require.NoError(t, err)
+	require.True(t, ok)
+	job2, ok, err := queue.Claim(ctx, now.Add(2*time.Second))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.ElementsMatch(t, []string{"first", "later"}, []string{job.ID, job2.ID})
+
+	require.NoError(t, queue.Complete(ctx, "first"))
+	require.NoError(t, queue.Bury(ctx, "later", "still down"))
+
+	job, err = queue.Get(ctx, "first")
+	require.NoError(t, err)
+	assert.Equal(t, config.JobStatusDone, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	job, err = queue.Get(ctx, "later")
+	require.NoError(t, err)
+	assert.Equal(t, config.JobStatusDead, job.Status)
+
+	_, err = queue.Get(ctx, "missing")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+	assert.ErrorIs(t, queue.Complete(ctx, "missing"), config.ErrNotFound)
+}
+
+func TestQueueLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	queue := jobs.NewMemoryQueue(time.Minute)
+	defer queue.Close()
+
+	require.NoError(t, queue.Enqueue(ctx, newJob("job", now)))
+	_, ok, err := queue.Claim(ctx, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A running job is only claimed again once its lease has run out.
+	_, ok, err = queue.Claim(ctx, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	job, ok, err := queue.Claim(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 2, job.Attempts)
+}
+
+func TestJournalQueue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	path := filepath.Join(t.TempDir(), "jobs")
+
+	queue, err := jobs.OpenJournalQueue(path, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, queue.Enqueue(ctx, newJob("pending", now)))
+	require.NoError(t, queue.Enqueue(ctx, newJob("done", now)))
+	require.NoError(t, queue.Complete(ctx, "done"))
+	require.NoError(t, queue.Close())
+
+	// Simulate a crash in the middle of appending a line.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id":"torn","stat`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	queue, err = jobs.OpenJournalQueue(path, time.Minute)
+	require.NoError(t, err)
+	job, err := queue.Get(ctx, "pending")
+	require.NoError(t, err)
+	assert.Equal(t, config.JobStatusPending, job.Status)
+	assert.Equal(t, []string{"abc"}, job.ShortURLs)
+	job, err = queue.Get(ctx, "done")
+	require.NoError(t, err)
+	assert.Equal(t, config.JobStatusDone, job.Status)
+	_, err = queue.Get(ctx, "torn")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+
+	// Pruning compacts the journal, and appending after it keeps working.
+	removed, err := queue.Prune(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "compaction left a temporary file behind")
+	require.NoError(t, queue.Enqueue(ctx, newJob("new", now)))
+	require.NoError(t, queue.Close())
+
+	queue, err = jobs.OpenJournalQueue(path, time.Minute)
+	require.NoError(t, err)
+	defer queue.Close()
+	_, err = queue.Get(ctx, "done")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+	_, err = queue.Get(ctx, "pending")
+	assert.NoError(t, err)
+	_, err = queue.Get(ctx, "new")
+	assert.NoError(t, err)
+}
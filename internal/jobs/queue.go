@@ -0,0 +1,42 @@
+// Package jobs implements the persistent queue of deletion jobs. A job stays in the queue from the
+// moment a deletion is accepted until it succeeds or is dead-lettered, so that a crash or a failing
+// database does not lose it. The queue is kept in a Postgres table in database mode, in an
+// append-only journal file in file mode and in memory otherwise; the worker package drains it.
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+)
+
+// Queue stores jobs and hands the due ones to the workers. Claiming a job leases it: a job that
+// is neither completed, retried nor buried before its lease runs out is claimed again.
+type Queue interface {
+	// Enqueue stores a new pending job.
+	Enqueue(ctx context.Context, job models.Job) error
+
+	// Claim starts the job that has been due the longest at now, marking it as running and
+	// counting the attempt. It returns false if no job is due.
+	Claim(ctx context.Context, now time.Time) (models.Job, bool, error)
+
+	// Complete marks a claimed job as done.
+	Complete(ctx context.Context, id string) error
+
+	// Retry records the failure of an attempt and makes the job due again at next.
+	Retry(ctx context.Context, id, lastError string, next time.Time) error
+
+	// Bury records the failure of the last attempt and dead-letters the job.
+	Bury(ctx context.Context, id, lastError string) error
+
+	// Get retrieves a job by its ID. It returns config.ErrNotFound if the job does not exist.
+	Get(ctx context.Context, id string) (models.Job, error)
+
+	// Prune removes the done and dead jobs last updated before the given moment
+	// and returns the number of removed jobs.
+	Prune(ctx context.Context, before time.Time) (int, error)
+
+	// Close releases the resources of the queue.
+	Close() error
+}
@@ -0,0 +1,289 @@
+// Package metrics implements a minimal registry of counters, gauges and histograms
+// exposed in the Prometheus text exposition format, so that the service can be
+// scraped without depending on the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector is a metric family that can write itself in the text exposition format.
+type collector interface {
+	// name returns the name of the metric family.
+	name() string
+	// write writes the HELP, TYPE and sample lines of the family.
+	write(w *bufio.Writer)
+}
+
+// Registry holds the metric families exposed by the service. It is safe for concurrent use.
+type Registry struct {
+	collectors []collector // collectors holds the registered families.
+	mu         sync.Mutex  // mu protects collectors.
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds a family to the registry.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText writes every registered family in the Prometheus text exposition format,
+// ordered by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mu.Unlock()
+
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an HTTP handler serving the registered families to a Prometheus scraper.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			logger.Errorf("Error writing metrics: %v", err)
+		}
+	})
+}
+
+// desc describes a metric family.
+type desc struct {
+	family string   // family is the metric name.
+	help   string   // help is the description written on the HELP line.
+	kind   string   // kind is the metric type written on the TYPE line.
+	labels []string // labels are the names of the labels of every series.
+}
+
+// name returns the name of the metric family.
+func (d desc) name() string {
+	return d.family
+}
+
+// writeHeader writes the HELP and TYPE lines of the family.
+func (d desc) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP " + d.family + " " + helpEscaper.Replace(d.help) + "\n")
+	w.WriteString("# TYPE " + d.family + " " + d.kind + "\n")
+}
+
+// writeSample writes one sample line. extraName and extraValue add a label that is not part
+// of the family's labels, such as le of histogram buckets; extraName may be empty.
+func (d desc) writeSample(w *bufio.Writer, suffix string, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(d.family + suffix)
+	if len(values) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, value := range values {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(d.labels[i] + `="` + labelEscaper.Replace(value) + `"`)
+		}
+		if extraName != "" {
+			if len(values) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+var (
+	// helpEscaper escapes HELP text as required by the text exposition format.
+	helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	// labelEscaper escapes label values as required by the text exposition format.
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// formatFloat formats a sample value the way Prometheus expects it.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// seriesKey joins label values into a map key; the separator cannot occur in valid UTF-8.
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// sortedKeys returns the keys of a series map in a stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	desc
+	values map[string]*counterSeries // values holds the series keyed by their label values.
+	mu     sync.Mutex                // mu protects values.
+}
+
+// counterSeries is the value of one labelled counter.
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec registers a counter family with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{family: name, help: help, kind: "counter", labels: labels},
+		values: make(map[string]*counterSeries),
+	}
+	r.register(c)
+	return c
+}
+
+// Inc increments the counter with the given label values, in the order of the label names.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v, which must not be negative, to the counter with the given label values.
+func (c *CounterVec) Add(v float64, values ...string) {
+	key := seriesKey(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.values[key]
+	if !ok {
+		s = &counterSeries{labels: append([]string(nil), values...)}
+		c.values[key] = s
+	}
+	s.value += v
+}
+
+// write writes the family in the text exposition format.
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeHeader(w)
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		c.writeSample(w, "", s.labels, "", "", s.value)
+	}
+}
+
+// HistogramVec is a family of histograms partitioned by label values.
+type HistogramVec struct {
+	desc
+	buckets []float64                   // buckets are the upper bounds of the buckets, in increasing order.
+	series  map[string]*histogramSeries // series holds the histograms keyed by their label values.
+	mu      sync.Mutex                  // mu protects series.
+}
+
+// histogramSeries is the state of one labelled histogram.
+type histogramSeries struct {
+	labels []string
+	counts []uint64 // counts holds the number of observations per bucket, not cumulated.
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec registers a histogram family with the given bucket upper bounds and label names.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{family: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records v in the histogram with the given label values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	key := seriesKey(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labels: append([]string(nil), values...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// write writes the family in the text exposition format.
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			h.writeSample(w, "_bucket", s.labels, "le", formatFloat(bound), float64(cumulative))
+		}
+		h.writeSample(w, "_bucket", s.labels, "le", "+Inf", float64(s.count))
+		h.writeSample(w, "_sum", s.labels, "", "", s.sum)
+		h.writeSample(w, "_count", s.labels, "", "", float64(s.count))
+	}
+}
+
+// funcMetric is an unlabelled metric whose value is read when the registry is written.
+type funcMetric struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is returned by fn at scrape time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{desc: desc{family: name, help: help, kind: "gauge"}, fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is returned by fn at scrape time.
+// fn must never return a smaller value than before.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{desc: desc{family: name, help: help, kind: "counter"}, fn: fn})
+}
+
+// write writes the metric in the text exposition format.
+func (m *funcMetric) write(w *bufio.Writer) {
+	m.writeHeader(w)
+	m.writeSample(w, "", nil, "", "", m.fn())
+}
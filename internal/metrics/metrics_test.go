@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteText(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	requests := reg.NewCounterVec("requests_total", "Number of requests.", "method", "path")
+	requests.Inc("GET", "/a")
+	requests.Inc("GET", "/a")
+	requests.Add(3, "POST", `/b"c\d`)
+
+	latency := reg.NewHistogramVec("latency_seconds", "Request latency.\nIn seconds.", []float64{0.1, 1}, "method")
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.1, "GET")
+	latency.Observe(0.5, "GET")
+	latency.Observe(5, "GET")
+
+	reg.NewGaugeFunc("queue_depth", "Tasks waiting.", func() float64 { return 7 })
+	reg.NewCounterFunc("failures_total", "Failed tasks.", func() float64 { return 2 })
+
+	var sb strings.Builder
+	assert.NoError(t, reg.WriteText(&sb))
+
+	expected := `# HELP failures_total Failed tasks.
+# TYPE failures_total counter
+failures_total 2
+# HELP latency_seconds Request latency.\nIn seconds.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 2
+latency_seconds_bucket{method="GET",le="1"} 3
+latency_seconds_bucket{method="GET",le="+Inf"} 4
+latency_seconds_sum{method="GET"} 5.65
+latency_seconds_count{method="GET"} 4
+# HELP queue_depth Tasks waiting.
+# TYPE queue_depth gauge
+queue_depth 7
+# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{method="GET",path="/a"} 2
+requests_total{method="POST",path="/b\"c\\d"} 3
+`
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewCounterVec("empty_total", "Never incremented.")
+
+	rr := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP empty_total Never incremented.\n# TYPE empty_total counter\n", rr.Body.String())
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
+	"github.com/go-chi/chi/v5"
+)
+
+// unmatchedRoute is the route label of requests that did not match any route.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware counts the HTTP requests and records their latency in reg, labelled by
+// method, chi route pattern (such as /{id}) and response status. Using the pattern instead of
+// the path keeps the number of series bounded.
+func MetricsMiddleware(reg *metrics.Registry) func(http.Handler) http.Handler {
+	requests := reg.NewCounterVec("http_requests_total",
+		"Number of HTTP requests.", "method", "route", "status")
+	duration := reg.NewHistogramVec("http_request_duration_seconds",
+		"Latency of HTTP requests in seconds.", metrics.DefaultBuckets, "method", "route", "status")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(ww, r)
+
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			code := strconv.Itoa(status)
+			requests.Inc(r.Method, route, code)
+			duration.Observe(time.Since(start).Seconds(), r.Method, route, code)
+		})
+	}
+}
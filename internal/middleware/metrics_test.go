@@ -0,0 +1,38 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
+	"github.com/gleb-korostelev/short-url.git/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	reg := metrics.NewRegistry()
+	r := chi.NewRouter()
+	r.Use(middleware.MetricsMiddleware(reg))
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "http://example.com")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/abc", "/def", "/ping", "/api/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var sb strings.Builder
+	assert.NoError(t, reg.WriteText(&sb))
+	text := sb.String()
+	assert.Contains(t, text, `http_requests_total{method="GET",route="/{id}",status="307"} 2`)
+	assert.Contains(t, text, `http_requests_total{method="GET",route="/ping",status="200"} 1`)
+	assert.Contains(t, text, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, text, `http_request_duration_seconds_count{method="GET",route="/{id}",status="307"} 2`)
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+)
+
+// RateLimit describes how many requests a client may send within a period.
+// Requests is also the burst size: a client that stayed idle may send that many requests at once.
+// The zero value disables rate limiting.
+type RateLimit struct {
+	Requests int           // Requests is the number of requests allowed per period.
+	Per      time.Duration // Per is the period over which Requests are allowed.
+}
+
+// ParseRateLimit parses a rate limit given as "<requests>/<period>", for example "60/m" or "5/10s".
+// The period is either one of the units s, m and h or a duration accepted by time.ParseDuration.
+// An empty string or "0" disables rate limiting.
+func ParseRateLimit(s string) (RateLimit, error) {
+	if s == "" || s == "0" {
+		return RateLimit{}, nil
+	}
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("%w: %q is not in <requests>/<period> form", config.ErrInvalidRateLimit, s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("%w: %q is not a positive number of requests", config.ErrInvalidRateLimit, requests)
+	}
+	if period == "s" || period == "m" || period == "h" {
+		period = "1" + period
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("%w: %q is not a positive period", config.ErrInvalidRateLimit, period)
+	}
+	return RateLimit{Requests: n, Per: per}, nil
+}
+
+// RateLimitMiddleware limits the requests passing through it with token buckets, one per user ID
+// set by EnsureUserCookie and one per client IP, so that neither dropping the cookie nor sharing
+// an address lets a client exceed the limit. The client IP is found by utils.ClientIP, which ignores
+// the forwarding headers a client could forge to get a fresh bucket. A request is let through only if both of its buckets
+// hold a token. Every response carries X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (seconds until the buckets are full again); refused requests get HTTP 429
+// Too Many Requests with a Retry-After header.
+//
+// Each call creates independent buckets, so routes wrapped by separate calls are limited separately.
+// A zero limit returns a middleware that lets every request through.
+func RateLimitMiddleware(limit RateLimit) func(http.Handler) http.Handler {
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	buckets := newBucketStore(limit)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys := []string{"ip:" + utils.ClientIP(r)}
+			if userID, ok := r.Context().Value(config.UserContextKey).(string); ok && userID != "" {
+				keys = append(keys, "user:"+userID)
+			}
+
+			remaining, reset, retryAfter, allowed := buckets.take(keys, time.Now())
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// bucket is a token bucket refilled continuously at the rate of its store.
+type bucket struct {
+	tokens float64   // tokens is the number of tokens at the moment of last.
+	last   time.Time // last is the moment tokens was computed.
+}
+
+// bucketStore holds the token buckets of one rate limit, keyed by client.
+type bucketStore struct {
+	capacity  float64            // capacity is the number of tokens in a full bucket.
+	rate      float64            // rate is the number of tokens added per second.
+	per       time.Duration      // per is the time an empty bucket takes to fill up.
+	buckets   map[string]*bucket // buckets holds the buckets that are not full.
+	lastSweep time.Time          // lastSweep is the last time full buckets were dropped.
+	mu        sync.Mutex         // mu protects buckets and lastSweep.
+}
+
+// newBucketStore creates an empty store of buckets for limit.
+func newBucketStore(limit RateLimit) *bucketStore {
+	return &bucketStore{
+		capacity: float64(limit.Requests),
+		rate:     float64(limit.Requests) / limit.Per.Seconds(),
+		per:      limit.Per,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// take removes a token from the bucket of every key if all of them hold one, and from none otherwise.
+// It returns the number of whole tokens left in the emptiest bucket, the time until all buckets are
+// full again, the time until the next token is available if the request was refused, and whether
+// the request is allowed.
+func (s *bucketStore) take(keys []string, now time.Time) (int, time.Duration, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	tokens := s.capacity
+	for _, key := range keys {
+		tokens = math.Min(tokens, s.refill(key, now).tokens)
+	}
+
+	allowed := tokens >= 1
+	var retryAfter time.Duration
+	if allowed {
+		for _, key := range keys {
+			s.buckets[key].tokens--
+		}
+		tokens--
+	} else {
+		retryAfter = s.timeToFill(1 - tokens)
+	}
+	return int(tokens), s.timeToFill(s.capacity - tokens), retryAfter, allowed
+}
+
+// refill returns the bucket of key with the tokens added since it was last used.
+// A key without a bucket gets a full one. The caller must hold s.mu.
+func (s *bucketStore) refill(key string, now time.Time) *bucket {
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: s.capacity, last: now}
+		s.buckets[key] = b
+		return b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(s.capacity, b.tokens+elapsed*s.rate)
+		b.last = now
+	}
+	return b
+}
+
+// timeToFill returns the time needed to add the given number of tokens to a bucket.
+func (s *bucketStore) timeToFill(tokens float64) time.Duration {
+	return time.Duration(tokens / s.rate * float64(time.Second))
+}
+
+// sweep drops the buckets that have refilled completely, at most once per fill period,
+// so that clients which stop sending requests do not accumulate. The caller must hold s.mu.
+func (s *bucketStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.per {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= s.per {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,153 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected middleware.RateLimit
+		wantErr  bool
+	}{
+		{name: "Disabled Empty", input: "", expected: middleware.RateLimit{}},
+		{name: "Disabled Zero", input: "0", expected: middleware.RateLimit{}},
+		{name: "Per Second", input: "5/s", expected: middleware.RateLimit{Requests: 5, Per: time.Second}},
+		{name: "Per Minute", input: "60/m", expected: middleware.RateLimit{Requests: 60, Per: time.Minute}},
+		{name: "Per Hour", input: "1000/h", expected: middleware.RateLimit{Requests: 1000, Per: time.Hour}},
+		{name: "Duration", input: "5/10s", expected: middleware.RateLimit{Requests: 5, Per: 10 * time.Second}},
+		{name: "Missing Period", input: "60", wantErr: true},
+		{name: "Negative Requests", input: "-1/m", wantErr: true},
+		{name: "Bad Period", input: "60/fortnight", wantErr: true},
+		{name: "Zero Period", input: "60/0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, err := middleware.ParseRateLimit(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, config.ErrInvalidRateLimit)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := middleware.RateLimitMiddleware(middleware.RateLimit{Requests: 2, Per: time.Hour})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	send := func(ip, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+		req.RemoteAddr = ip + ":1234"
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), config.UserContextKey, userID))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("192.0.2.1", "user-1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Remaining"))
+
+	rr = send("192.0.2.1", "user-1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+
+	// The bucket of the IP is empty.
+	rr = send("192.0.2.1", "user-2")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.InDelta(t, 1800, retryAfter, 1)
+	reset, err := strconv.Atoi(rr.Header().Get("X-RateLimit-Reset"))
+	assert.NoError(t, err)
+	assert.InDelta(t, 3600, reset, 1)
+
+	// The bucket of the user is empty although the IP changed.
+	rr = send("192.0.2.2", "user-1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+
+	// The refused requests did not consume the tokens of the other buckets.
+	rr = send("192.0.2.2", "user-2")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestRateLimitMiddlewareForwardingHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		trustedProxy string
+		remoteAddr   string
+	}{
+		{
+			name:       "Direct Client",
+			remoteAddr: "192.0.2.1:1234",
+		},
+		{
+			name:         "Untrusted Proxy",
+			trustedProxy: "10.0.0.0/8",
+			remoteAddr:   "192.0.2.1:1234",
+		},
+		{
+			name:         "Trusted Proxy",
+			trustedProxy: "10.0.0.0/8",
+			remoteAddr:   "10.0.0.1:1234",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.TrustedProxy = tt.trustedProxy
+			defer func() { config.TrustedProxy = "" }()
+			handler := middleware.RateLimitMiddleware(middleware.RateLimit{Requests: 2, Per: time.Hour})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}))
+
+			// A client forging a new forwarded address on every request still drains a single
+			// bucket: the trusted proxy appends the address it sees after the forged ones.
+			codes := make([]int, 3)
+			for i := range codes {
+				req := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+				req.RemoteAddr = tt.remoteAddr
+				req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i+1)+", 192.0.2.1")
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				codes[i] = rr.Code
+			}
+			assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+		})
+	}
+}
+
+func TestRateLimitMiddlewareDisabled(t *testing.T) {
+	handler := middleware.RateLimitMiddleware(middleware.RateLimit{})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	for i := 0; i < 10; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/abc", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("X-RateLimit-Limit"))
+	}
+}
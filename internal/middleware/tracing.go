@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+	"github.com/go-chi/chi/v5"
+)
+
+// TracingMiddleware starts a server span for every HTTP request. A parent received in the W3C
+// traceparent header is continued, so the span joins the trace of the caller. Once the request
+// is served the span is named after the method and chi route pattern and records the status.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if parent, ok := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader)); ok {
+			ctx = tracing.ContextWithRemoteParent(ctx, parent)
+		}
+		ctx, span := tracing.Start(ctx, r.Method, tracing.WithKind(tracing.KindServer),
+			tracing.WithAttributes("http.method", r.Method, "http.target", r.URL.Path))
+		if span == nil {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		defer span.End()
+
+		ww := &responseWriter{ResponseWriter: w}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetName(r.Method + " " + route)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.RecordError(errors.New(http.StatusText(status)))
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/middleware"
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingExporter keeps the exported spans in memory.
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Close() error {
+	return nil
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := tracing.NewTracer(exporter, 16, 16, time.Hour, time.Second)
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	var handlerSpan tracing.SpanContext
+	r := chi.NewRouter()
+	r.Use(middleware.TracingMiddleware)
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = tracing.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	require.Len(t, exporter.spans, 1)
+	span := exporter.spans[0]
+	assert.Equal(t, "GET /{id}", span.Name)
+	assert.Equal(t, tracing.KindServer, span.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", span.ParentSpanID.String())
+	assert.Equal(t, span.SpanID, handlerSpan.SpanID)
+	assert.Equal(t, http.StatusTemporaryRedirect, span.Attributes["http.status_code"])
+	assert.Empty(t, span.Error)
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+)
+
+// TrustedSubnetMiddleware lets through only the requests whose client address, as reported by
+// utils.ClientIP, belongs to config.TrustedSubnet, and responds to the others with HTTP 403 Forbidden.
+// If the trusted subnet is not configured, every request is refused.
+func TrustedSubnetMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !utils.IsTrustedIP(utils.ClientIP(r), config.TrustedSubnet) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedSubnetMiddleware(t *testing.T) {
+	defer func(subnet, proxy string) {
+		config.TrustedSubnet, config.TrustedProxy = subnet, proxy
+	}(config.TrustedSubnet, config.TrustedProxy)
+	config.TrustedProxy = "172.16.0.0/12"
+
+	handler := middleware.TrustedSubnetMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		subnet       string
+		remoteAddr   string
+		realIP       string
+		expectedCode int
+	}{
+		{name: "Trusted Client", subnet: "192.168.1.0/24", remoteAddr: "192.168.1.10:1234", expectedCode: http.StatusOK},
+		{name: "Untrusted Client", subnet: "192.168.1.0/24", remoteAddr: "10.0.0.1:1234", expectedCode: http.StatusForbidden},
+		{name: "Forged X-Real-IP", subnet: "192.168.1.0/24", remoteAddr: "10.0.0.1:1234", realIP: "192.168.1.10", expectedCode: http.StatusForbidden},
+		{name: "X-Real-IP From Trusted Proxy", subnet: "192.168.1.0/24", remoteAddr: "172.16.0.1:1234", realIP: "192.168.1.10", expectedCode: http.StatusOK},
+		{name: "No Trusted Subnet", remoteAddr: "192.168.1.10:1234", expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.TrustedSubnet = tt.subnet
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
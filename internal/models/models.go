@@ -3,38 +3,96 @@
 package models
 
 import (
+	"time"
+
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 )
 
 // URLPayload defines the structure for receiving URLs in requests.
 type URLPayload struct {
-	URL string `json:"url"`
+	URL         string     `json:"url"`
+	CustomAlias string     `json:"custom_alias,omitempty"` // Optional caller-chosen short code
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`   // Optional absolute expiry (RFC 3339)
+	TTLSeconds  int64      `json:"ttl_seconds,omitempty"`  // Optional lifetime in seconds from now
+	QR          bool       `json:"qr,omitempty"`           // Whether to include the QR code URL in the response
+	Password    string     `json:"password,omitempty"`     // Optional passphrase required to follow the link
+}
+
+// URLErrorResponse is the body of a response rejecting a URL that cannot be shortened.
+type URLErrorResponse struct {
+	Error string `json:"error"` // Human-readable reason
+	Code  string `json:"code"`  // Machine-readable reason, such as "scheme_not_allowed"
+}
+
+// PolicyEntryRequest is the body of a request adding or removing a URL policy entry.
+type PolicyEntryRequest struct {
+	Entry string `json:"entry"` // Domain, *.suffix or /regex/
+}
+
+// PolicyResponse lists the entries of the URL policy.
+type PolicyResponse struct {
+	Mode    string   `json:"mode"`    // "blocklist" or "allowlist"
+	Entries []string `json:"entries"` // Entries in the order they were added
 }
 
 // ShortURLResponse defines the structure for sending shortened URLs in responses.
 type ShortURLResponse struct {
 	Result string `json:"result"`
+	QR     string `json:"qr,omitempty"` // URL of the QR code image, if requested
 }
 
 // URLData describes the structure of URL data in the database.
 type URLData struct {
-	UUID        uuid.UUID `db:"user_id"`      // UUID of the user
-	ShortURL    string    `db:"short_url"`    // Shortened URL
-	OriginalURL string    `db:"original_url"` // Original URL
-	DeletedFlag bool      `db:"is_deleted"`   // Flag indicating if the URL is deleted
+	UUID         uuid.UUID  `db:"user_id"`                         // UUID of the user
+	ShortURL     string     `db:"short_url"`                       // Shortened URL
+	OriginalURL  string     `db:"original_url"`                    // Original URL
+	DeletedFlag  bool       `db:"is_deleted"`                      // Flag indicating if the URL is deleted
+	ExpiresAt    *time.Time `db:"expires_at" json:",omitempty"`    // Moment the URL stops redirecting, nil if it never expires
+	PasswordHash string     `db:"password_hash" json:",omitempty"` // Bcrypt hash of the link password, empty if the link is not protected
+}
+
+// Expired reports whether the URL has an expiry that is not after now.
+func (d URLData) Expired(now time.Time) bool {
+	return d.ExpiresAt != nil && !d.ExpiresAt.After(now)
+}
+
+// ShortenOptions carries optional per-link settings supplied when a URL is shortened.
+type ShortenOptions struct {
+	CustomAlias  string     // Caller-chosen short code used instead of a generated one
+	ExpiresAt    *time.Time // Moment the link stops redirecting, nil if it never expires
+	PasswordHash string     // Bcrypt hash of the password required to follow the link, empty if not protected
+}
+
+// BatchURL describes a URL to be stored as part of a batch.
+type BatchURL struct {
+	OriginalURL string         // Original URL to be shortened
+	Opts        ShortenOptions // Custom alias, expiration and password of the link
+}
+
+// BatchURLResult reports the outcome of storing a single URL of a batch.
+type BatchURLResult struct {
+	ShortURL string // Shortened URL, empty if Err is set
+	Existed  bool   // Whether the original URL was already stored, in which case ShortURL is the existing one
+	Err      error  // Error that prevented storing this URL, such as config.ErrAliasTaken
 }
 
 // ShortenBatchRequestItem describes a request item for batch URL shortening.
 type ShortenBatchRequestItem struct {
-	CorrelationID string `json:"correlation_id"` // Correlation identifier for tracking requests
-	OriginalURL   string `json:"original_url"`   // Original URL to be shortened
+	CorrelationID string     `json:"correlation_id"`         // Correlation identifier for tracking requests
+	OriginalURL   string     `json:"original_url"`           // Original URL to be shortened
+	CustomAlias   string     `json:"custom_alias,omitempty"` // Optional caller-chosen short code
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`   // Optional absolute expiry (RFC 3339)
+	TTLSeconds    int64      `json:"ttl_seconds,omitempty"`  // Optional lifetime in seconds from now
+	Password      string     `json:"password,omitempty"`     // Optional passphrase required to follow the link
 }
 
 // ShortenBatchResponseItem describes a response item for a batch URL shortening request.
 type ShortenBatchResponseItem struct {
-	CorrelationID string `json:"correlation_id"` // Correlation identifier from the request
-	ShortURL      string `json:"short_url"`      // Shortened URL
+	CorrelationID string `json:"correlation_id"`      // Correlation identifier from the request
+	ShortURL      string `json:"short_url,omitempty"` // Shortened URL, empty if the item was not saved
+	Status        string `json:"status"`              // Outcome of the item: created, conflict or error
+	Error         string `json:"error,omitempty"`     // Reason the item was not saved
 }
 
 // UserURLs represents both shortened and original URLs associated with a user.
@@ -43,6 +101,60 @@ type UserURLs struct {
 	OriginalURL string `json:"original_url"` // Original URL
 }
 
+// Click describes a single redirect through a shortened URL.
+type Click struct {
+	ShortURL  string    `json:"short_url"`            // Shortened URL that was followed
+	ClickedAt time.Time `json:"clicked_at"`           // Moment of the redirect
+	Referrer  string    `json:"referrer,omitempty"`   // Referer header of the request
+	UserAgent string    `json:"user_agent,omitempty"` // User-Agent header of the request
+	IPHash    string    `json:"ip_hash,omitempty"`    // Keyed hash of the client IP address
+}
+
+// URLDeletion identifies a shortened URL that a user asked to delete.
+type URLDeletion struct {
+	UserID   string // ID of the user requesting the deletion
+	ShortURL string // Shortened URL to delete
+}
+
+// DailyClicks holds the number of redirects through a shortened URL on a single UTC day.
+type DailyClicks struct {
+	Date   string `json:"date"`   // Day in YYYY-MM-DD format
+	Clicks int64  `json:"clicks"` // Number of redirects on that day
+}
+
+// URLStats summarizes the redirects through a shortened URL.
+type URLStats struct {
+	ShortURL    string        `json:"short_url"`    // Shortened URL the statistics belong to
+	TotalClicks int64         `json:"total_clicks"` // Number of redirects overall
+	Daily       []DailyClicks `json:"daily"`        // Number of redirects per day, oldest first
+}
+
+// InternalStats describes the service-wide counters exposed to trusted callers.
+type InternalStats struct {
+	URLs  int `json:"urls"`  // Number of shortened URLs
+	Users int `json:"users"` // Number of distinct users
+}
+
+// Job describes a request to delete short URLs that is persisted in the job queue
+// until it succeeds or is dead-lettered after too many failed attempts.
+type Job struct {
+	ID            string    `json:"id"`                   // Identifier returned to the client
+	UserID        string    `json:"user_id"`              // User whose short URLs are deleted
+	ShortURLs     []string  `json:"short_urls"`           // Short URLs to delete
+	Status        string    `json:"status"`               // One of the config.JobStatus values
+	Attempts      int       `json:"attempts"`             // Number of times the job has been started
+	LastError     string    `json:"last_error,omitempty"` // Error of the last failed attempt
+	NextAttemptAt time.Time `json:"next_attempt_at"`      // Moment the job may be started (again)
+	CreatedAt     time.Time `json:"created_at"`           // Moment the job was enqueued
+	UpdatedAt     time.Time `json:"updated_at"`           // Moment the status last changed
+}
+
+// JobAccepted is the response to a request that was queued as a job.
+type JobAccepted struct {
+	JobID     string `json:"job_id"`     // Identifier of the job
+	StatusURL string `json:"status_url"` // URL reporting the status of the job
+}
+
 // Claims defines custom JWT claims used for authentication.
 type Claims struct {
 	UserID string `json:"user_id"` // User identifier
@@ -51,9 +163,38 @@ type Claims struct {
 
 // Config defines server settings thats taken from JSON file
 type Config struct {
-	ServerAddr   string `json:"server_address"`
-	BaseURL      string `json:"base_url"`
-	BaseFilePath string `json:"file_storage_path"`
-	DBDSN        string `json:"database_dsn"`
-	EnableHTTPS  bool   `json:"enable_https"`
+	ServerAddr     string `json:"server_address"`
+	GRPCServerAddr string `json:"grpc_server_address"`
+	BaseURL        string `json:"base_url"`
+	BaseFilePath   string `json:"file_storage_path"`
+	FileSync       string `json:"file_storage_sync"`
+	SQLitePath     string `json:"sqlite_storage_path"`
+	KVFilePath     string `json:"kv_storage_path"`
+	MemorySnapshot string `json:"memory_snapshot_path"`
+	DBDSN          string `json:"database_dsn"`
+	EnableHTTPS    bool   `json:"enable_https"`
+	TrustedSubnet  string `json:"trusted_subnet"`
+	TrustedProxy   string `json:"trusted_proxy"`
+	CacheType      string `json:"cache_type"`
+	RedisAddr      string `json:"redis_address"`
+
+	CreateRateLimit   string `json:"rate_limit_create"`
+	RedirectRateLimit string `json:"rate_limit_redirect"`
+	DeleteRateLimit   string `json:"rate_limit_delete"`
+
+	TraceExporter string `json:"trace_exporter"`
+	TraceEndpoint string `json:"trace_endpoint"`
+
+	StorageReadTimeout  string `json:"storage_read_timeout"`
+	StorageWriteTimeout string `json:"storage_write_timeout"`
+
+	SnapshotInterval string `json:"memory_snapshot_interval"`
+	JournalWindow    string `json:"memory_journal_window"`
+
+	URLSchemes        string `json:"url_schemes"`
+	StripURLFragments bool   `json:"strip_url_fragments"`
+
+	PolicyFile   string `json:"url_policy_file"`
+	PolicyMode   string `json:"url_policy_mode"`
+	PolicyAction string `json:"url_policy_action"`
 }
@@ -0,0 +1,333 @@
+// Package policy decides which URLs may be shortened and redirected to. A Checker is consulted
+// before a URL is saved and again before redirecting to it, so that URLs shortened before an
+// entry was added are rejected as well. Engine is the Checker backed by a list of entries that
+// is kept in a local file, reloaded when the file changes and managed through the admin API.
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/fsutil"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"golang.org/x/net/idna"
+)
+
+// Checker decides whether a URL may be shortened or redirected to.
+type Checker interface {
+	// Check returns a *BlockedError if rawURL is rejected and nil otherwise.
+	Check(rawURL string) error
+}
+
+// BlockedError reports a URL rejected by a Checker. It wraps config.ErrURLBlocked.
+type BlockedError struct {
+	URL    string // URL is the rejected URL.
+	Reason string // Reason tells why the URL is rejected, such as the entry it matches.
+}
+
+// Error returns the reason prefixed with config.ErrURLBlocked.
+func (e *BlockedError) Error() string {
+	return config.ErrURLBlocked.Error() + ": " + e.Reason
+}
+
+// Unwrap returns config.ErrURLBlocked, so that errors.Is recognises every BlockedError.
+func (e *BlockedError) Unwrap() error {
+	return config.ErrURLBlocked
+}
+
+// rule is a parsed policy entry. An entry is one of:
+//   - a domain such as "example.com", matching URLs whose host is exactly that domain;
+//   - a wildcard suffix such as "*.example.com", matching URLs whose host is a subdomain of it;
+//   - a regular expression between slashes such as "/\.exe$/", matching anywhere in the URL.
+type rule struct {
+	entry    string         // entry is the normalized form of the entry, as listed and stored.
+	domain   string         // domain is the host or, for a wildcard, the parent domain to match.
+	wildcard bool           // wildcard matches the subdomains of domain instead of domain itself.
+	re       *regexp.Regexp // re is the regular expression to match; nil for domains.
+}
+
+// parseEntry parses a policy entry, lowercasing domains and converting them to ASCII (IDNA).
+// It returns an error wrapping config.ErrInvalidPolicyEntry if the entry is malformed.
+func parseEntry(entry string) (rule, error) {
+	entry = strings.TrimSpace(entry)
+	if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+		re, err := regexp.Compile(entry[1 : len(entry)-1])
+		if err != nil {
+			return rule{}, fmt.Errorf("%w: %v", config.ErrInvalidPolicyEntry, err)
+		}
+		return rule{entry: entry, re: re}, nil
+	}
+
+	domain, wildcard := strings.CutPrefix(entry, "*.")
+	ascii, err := idna.Lookup.ToASCII(strings.ToLower(domain))
+	if err != nil || ascii == "" {
+		return rule{}, fmt.Errorf("%w: %q is neither a domain, a *.suffix nor a /regex/", config.ErrInvalidPolicyEntry, entry)
+	}
+	r := rule{entry: ascii, domain: ascii, wildcard: wildcard}
+	if wildcard {
+		r.entry = "*." + ascii
+	}
+	return r, nil
+}
+
+// match reports whether the rule matches a URL with the given lowercase host.
+func (r rule) match(rawURL, host string) bool {
+	switch {
+	case r.re != nil:
+		return r.re.MatchString(rawURL)
+	case r.wildcard:
+		return strings.HasSuffix(host, "."+r.domain)
+	default:
+		return host == r.domain
+	}
+}
+
+// Engine is a Checker rejecting the URLs that match one of its entries or, in allowlist mode,
+// the URLs that match none of them. Its entries can be kept in a file, one per line with blank
+// lines and lines starting with "#" ignored; Run reloads the file whenever it changes.
+// It is safe for concurrent use.
+type Engine struct {
+	path     string        // path is the file of entries; empty keeps the entries in memory only.
+	mode     string        // mode is config.PolicyModeBlocklist or config.PolicyModeAllowlist.
+	interval time.Duration // interval is the time between two checks of the file for changes.
+
+	mu      sync.RWMutex // mu guards the fields below.
+	rules   []rule       // rules are the parsed entries in the order they were added.
+	modTime time.Time    // modTime is the modification time of the file when it was last loaded or saved.
+	size    int64        // size is the size of the file when it was last loaded or saved.
+}
+
+// NewEngine creates an Engine in the given mode with the entries of the file at path, which is
+// checked for changes every interval by Run. A missing file is treated as empty and created on
+// the first change made through Add or Remove. An empty path keeps the entries in memory only.
+// It returns config.ErrUnknownPolicyMode for an unsupported mode and an error naming the line
+// of the first malformed entry in the file.
+func NewEngine(path, mode string, interval time.Duration) (*Engine, error) {
+	if mode != config.PolicyModeBlocklist && mode != config.PolicyModeAllowlist {
+		return nil, fmt.Errorf("%w: %q", config.ErrUnknownPolicyMode, mode)
+	}
+	e := &Engine{path: path, mode: mode, interval: interval}
+	if path == "" {
+		return e, nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := e.load(info); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Mode returns config.PolicyModeBlocklist or config.PolicyModeAllowlist.
+func (e *Engine) Mode() string {
+	return e.mode
+}
+
+// Check returns a *BlockedError if rawURL matches an entry in blocklist mode,
+// or matches no entry in allowlist mode.
+func (e *Engine) Check(rawURL string) error {
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range e.rules {
+		if !r.match(rawURL, host) {
+			continue
+		}
+		if e.mode == config.PolicyModeAllowlist {
+			return nil
+		}
+		return &BlockedError{URL: rawURL, Reason: fmt.Sprintf("matches %q", r.entry)}
+	}
+	if e.mode == config.PolicyModeAllowlist {
+		return &BlockedError{URL: rawURL, Reason: "matches no allowed entry"}
+	}
+	return nil
+}
+
+// Entries returns the normalized entries in the order they were added.
+func (e *Engine) Entries() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	entries := make([]string, len(e.rules))
+	for i, r := range e.rules {
+		entries[i] = r.entry
+	}
+	return entries
+}
+
+// Add adds an entry and saves the entries to the file. Comments in the file are not kept.
+// It returns an error wrapping config.ErrInvalidPolicyEntry if the entry is malformed and
+// config.ErrPolicyEntryExists if it is already present.
+func (e *Engine) Add(entry string) error {
+	r, err := parseEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.index(r.entry) >= 0 {
+		return config.ErrPolicyEntryExists
+	}
+	rules := append(slices.Clip(e.rules), r)
+	if err := e.save(rules); err != nil {
+		return err
+	}
+	e.rules = rules
+	return nil
+}
+
+// Remove removes an entry and saves the entries to the file. Comments in the file are not kept.
+// It returns config.ErrPolicyEntryNotFound if the entry is not present.
+func (e *Engine) Remove(entry string) error {
+	r, err := parseEntry(entry)
+	if err != nil {
+		return config.ErrPolicyEntryNotFound
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.index(r.entry)
+	if i < 0 {
+		return config.ErrPolicyEntryNotFound
+	}
+	rules := slices.Delete(slices.Clone(e.rules), i, i+1)
+	if err := e.save(rules); err != nil {
+		return err
+	}
+	e.rules = rules
+	return nil
+}
+
+// index returns the position of the normalized entry in rules, or -1 if it is not present.
+// The caller must hold mu.
+func (e *Engine) index(entry string) int {
+	return slices.IndexFunc(e.rules, func(r rule) bool { return r.entry == entry })
+}
+
+// Reload loads the file again if it changed since it was last loaded or saved.
+// If the file is missing or has a malformed entry, the current entries are kept and the error is returned.
+func (e *Engine) Reload() error {
+	if e.path == "" {
+		return nil
+	}
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if info.ModTime().Equal(e.modTime) && info.Size() == e.size {
+		return nil
+	}
+	if err := e.load(info); err != nil {
+		return err
+	}
+	logger.Infof("Reloaded %d URL policy entries from %s", len(e.rules), e.path)
+	return nil
+}
+
+// Run reloads the file every interval until ctx is cancelled, logging the entries it cannot load.
+// It blocks, so it is typically started in its own goroutine. Without a file it returns at once.
+func (e *Engine) Run(ctx context.Context) {
+	if e.path == "" {
+		return
+	}
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Reload(); err != nil {
+				logger.Errorf("Error reloading URL policy: %v", err)
+			}
+		}
+	}
+}
+
+// load replaces the entries with those of the file, whose state before reading is info.
+// Repeated entries are kept once. The caller must hold mu or have exclusive access to e.
+func (e *Engine) load(info os.FileInfo) error {
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
+
+	var rules []rule
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r, err := parseEntry(text)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", e.path, line, err)
+		}
+		if !seen[r.entry] {
+			seen[r.entry] = true
+			rules = append(rules, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	e.rules = rules
+	e.modTime = info.ModTime()
+	e.size = info.Size()
+	return nil
+}
+
+// save replaces the file with the given rules, one entry per line. The file is replaced atomically,
+// so that it is never seen half written, and keeps its mode. The caller must hold mu.
+func (e *Engine) save(rules []rule) error {
+	if e.path == "" {
+		return nil
+	}
+	err := fsutil.WriteFile(e.path, 0644, func(w io.Writer) error {
+		for _, r := range rules {
+			if _, err := io.WriteString(w, r.entry+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Remember the saved file so that Reload does not load it again.
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+	e.modTime = info.ModTime()
+	e.size = info.Size()
+	return nil
+}
@@ -0,0 +1,131 @@
+package policy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePolicy writes entries to the policy file at path, moving its modification time forward
+// so that a reload notices the change even within the timestamp granularity of the file system.
+func writePolicy(t *testing.T, path, entries string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(entries), 0644))
+	next := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, next, next))
+}
+
+func TestEngineCheck(t *testing.T) {
+	entries := "# sample policy\nevil.com\n*.phish.example\n\n/\\.exe$/\nbücher.example\n"
+
+	tests := []struct {
+		name    string
+		mode    string
+		url     string
+		blocked bool
+	}{
+		{name: "Exact Domain", mode: config.PolicyModeBlocklist, url: "https://evil.com/path", blocked: true},
+		{name: "Domain Is Case Insensitive", mode: config.PolicyModeBlocklist, url: "https://EVIL.com", blocked: true},
+		{name: "Domain Does Not Match Subdomain", mode: config.PolicyModeBlocklist, url: "https://www.evil.com"},
+		{name: "Domain Does Not Match Suffix", mode: config.PolicyModeBlocklist, url: "https://notevil.com"},
+		{name: "Wildcard Matches Subdomain", mode: config.PolicyModeBlocklist, url: "http://a.b.phish.example/login", blocked: true},
+		{name: "Wildcard Does Not Match Domain", mode: config.PolicyModeBlocklist, url: "http://phish.example"},
+		{name: "Regex", mode: config.PolicyModeBlocklist, url: "https://downloads.example.com/setup.exe", blocked: true},
+		{name: "IDNA Domain", mode: config.PolicyModeBlocklist, url: "https://xn--bcher-kva.example/", blocked: true},
+		{name: "Unlisted URL", mode: config.PolicyModeBlocklist, url: "https://example.com"},
+		{name: "Allowlist Accepts Listed URL", mode: config.PolicyModeAllowlist, url: "https://wiki.phish.example"},
+		{name: "Allowlist Rejects Unlisted URL", mode: config.PolicyModeAllowlist, url: "https://example.com", blocked: true},
+	}
+
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	writePolicy(t, path, entries)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := policy.NewEngine(path, tt.mode, time.Hour)
+			require.NoError(t, err)
+
+			err = engine.Check(tt.url)
+			if !tt.blocked {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, config.ErrURLBlocked)
+			var blocked *policy.BlockedError
+			require.ErrorAs(t, err, &blocked)
+			assert.Equal(t, tt.url, blocked.URL)
+		})
+	}
+}
+
+func TestNewEngine(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := policy.NewEngine("", "denylist", time.Hour)
+	assert.ErrorIs(t, err, config.ErrUnknownPolicyMode)
+
+	malformed := filepath.Join(dir, "malformed.txt")
+	writePolicy(t, malformed, "evil.com\n/(/\n")
+	_, err = policy.NewEngine(malformed, config.PolicyModeBlocklist, time.Hour)
+	assert.ErrorIs(t, err, config.ErrInvalidPolicyEntry)
+	assert.ErrorContains(t, err, "malformed.txt:2")
+
+	// A missing file starts empty and is created by the first change.
+	missing := filepath.Join(dir, "missing.txt")
+	engine, err := policy.NewEngine(missing, config.PolicyModeBlocklist, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, engine.Entries())
+	require.NoError(t, engine.Add("evil.com"))
+	data, err := os.ReadFile(missing)
+	require.NoError(t, err)
+	assert.Equal(t, "evil.com\n", string(data))
+}
+
+func TestEngineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	writePolicy(t, path, "evil.com\n")
+	engine, err := policy.NewEngine(path, config.PolicyModeBlocklist, time.Hour)
+	require.NoError(t, err)
+
+	// An unchanged file is not loaded again.
+	require.NoError(t, engine.Reload())
+	assert.Equal(t, []string{"evil.com"}, engine.Entries())
+
+	writePolicy(t, path, "evil.com\n*.phish.example\n")
+	require.NoError(t, engine.Reload())
+	assert.Equal(t, []string{"evil.com", "*.phish.example"}, engine.Entries())
+	assert.Error(t, engine.Check("https://login.phish.example"))
+
+	// A malformed file is reported and the entries loaded before are kept.
+	writePolicy(t, path, "[invalid\n")
+	assert.ErrorIs(t, engine.Reload(), config.ErrInvalidPolicyEntry)
+	assert.Equal(t, []string{"evil.com", "*.phish.example"}, engine.Entries())
+
+	require.NoError(t, os.Remove(path))
+	assert.Error(t, engine.Reload())
+	assert.Equal(t, []string{"evil.com", "*.phish.example"}, engine.Entries())
+}
+
+func TestEngineSaveKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	writePolicy(t, path, "evil.com\n")
+	require.NoError(t, os.Chmod(path, 0640))
+	engine, err := policy.NewEngine(path, config.PolicyModeBlocklist, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, engine.Add("*.phish.example"))
+	require.NoError(t, engine.Remove("evil.com"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "*.phish.example\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
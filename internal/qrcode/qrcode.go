@@ -0,0 +1,472 @@
+// Package qrcode is a self-contained QR code encoder.
+// It encodes byte data in versions 1 to 40 at any error correction level, picks the
+// mask with the lowest penalty score and renders the symbol as PNG or SVG.
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+)
+
+// ECLevel is the error correction level of a QR code.
+type ECLevel int
+
+// Error correction levels, from the least to the most redundant.
+const (
+	Low      ECLevel = iota // Low recovers about 7% of the symbol.
+	Medium                  // Medium recovers about 15% of the symbol.
+	Quartile                // Quartile recovers about 25% of the symbol.
+	High                    // High recovers about 30% of the symbol.
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+
+	// Penalty weights of the mask evaluation rules.
+	penaltyN1 = 3
+	penaltyN2 = 3
+	penaltyN3 = 40
+	penaltyN4 = 10
+)
+
+// formatBits returns the two bits identifying the level in the format information.
+func (l ECLevel) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// ParseECLevel parses one of "L", "M", "Q" or "H", case-insensitively.
+// It returns config.ErrInvalidECLevel for any other value.
+func ParseECLevel(s string) (ECLevel, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return Low, nil
+	case "M":
+		return Medium, nil
+	case "Q":
+		return Quartile, nil
+	case "H":
+		return High, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", config.ErrInvalidECLevel, s)
+	}
+}
+
+// Code is an encoded QR code symbol.
+type Code struct {
+	size       int    // size is the number of modules along each side.
+	modules    []bool // modules holds the color of every module row by row; true is dark.
+	isFunction []bool // isFunction marks the modules that are not available for codewords.
+}
+
+// Encode encodes data in byte mode using the smallest version that fits at the given level.
+// It returns config.ErrQRDataTooLong if data does not fit in a version 40 symbol.
+func Encode(data []byte, level ECLevel) (*Code, error) {
+	return encode(data, level, -1)
+}
+
+// Size returns the number of modules along each side of the symbol, excluding the quiet zone.
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Dark reports whether the module at column x and row y is dark.
+// Coordinates outside the symbol are light.
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && x < c.size && y >= 0 && y < c.size && c.modules[y*c.size+x]
+}
+
+// encode encodes data with the given mask, or with the mask of the lowest penalty if mask is negative.
+func encode(data []byte, level ECLevel, mask int) (*Code, error) {
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if 4+charCountBits(version)+len(data)*8 <= numDataCodewords(version, level)*8 {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, config.ErrQRDataTooLong
+	}
+
+	codewords := addErrorCorrection(dataCodewords(data, version, level), version, level)
+
+	c := newCode(version)
+	c.drawFunctionPatterns(version)
+	c.drawCodewords(codewords)
+
+	if mask < 0 {
+		minPenalty := -1
+		for m := 0; m < 8; m++ {
+			c.applyMask(m)
+			c.drawFormatBits(level, m)
+			penalty := c.penaltyScore()
+			if minPenalty < 0 || penalty < minPenalty {
+				mask, minPenalty = m, penalty
+			}
+			c.applyMask(m) // Masks are XOR-based, so applying one twice undoes it.
+		}
+	}
+	c.applyMask(mask)
+	c.drawFormatBits(level, mask)
+	return c, nil
+}
+
+// charCountBits returns the width of the character count indicator of byte mode.
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// dataCodewords builds the data codewords: the byte mode segment, the terminator and the padding.
+func dataCodewords(data []byte, version int, level ECLevel) []byte {
+	capacity := numDataCodewords(version, level) * 8
+	bits := newBitBuffer(capacity)
+	bits.append(0x4, 4)
+	bits.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	terminator := capacity - bits.len()
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-bits.len()%8)%8)
+	for pad := 0xEC; bits.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	return bits.bytes()
+}
+
+// addErrorCorrection splits the data codewords into blocks, appends the error correction
+// codewords of each block and interleaves the result.
+func addErrorCorrection(data []byte, version int, level ECLevel) []byte {
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	eccLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortDataLen := rawCodewords/numBlocks - eccLen
+
+	divisor := rsDivisor(eccLen)
+	dataBlocks := make([][]byte, numBlocks)
+	eccBlocks := make([][]byte, numBlocks)
+	for i, offset := 0, 0; i < numBlocks; i++ {
+		n := shortDataLen
+		if i >= numShortBlocks {
+			n++
+		}
+		dataBlocks[i] = data[offset : offset+n]
+		eccBlocks[i] = rsRemainder(dataBlocks[i], divisor)
+		offset += n
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := 0; i <= shortDataLen; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for _, block := range eccBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// newCode allocates an all-light symbol of the given version.
+func newCode(version int) *Code {
+	size := version*4 + 17
+	return &Code{
+		size:       size,
+		modules:    make([]bool, size*size),
+		isFunction: make([]bool, size*size),
+	}
+}
+
+// setFunction colors a function module and excludes it from codeword placement.
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y*c.size+x] = dark
+	c.isFunction[y*c.size+x] = true
+}
+
+// drawFunctionPatterns draws the timing, finder and alignment patterns and the version information,
+// and reserves the modules of the format information.
+func (c *Code) drawFunctionPatterns(version int) {
+	for i := 0; i < c.size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.size-4, 3)
+	c.drawFinderPattern(3, c.size-4)
+
+	positions := alignmentPatternPositions(version)
+	last := len(positions) - 1
+	for i, y := range positions {
+		for j, x := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignmentPattern(x, y)
+		}
+	}
+
+	// Reserve the format information; it is drawn once the mask is known.
+	c.drawFormatBits(Low, 0)
+	c.drawVersionBits(version)
+}
+
+// drawFinderPattern draws a finder pattern with its separator centered at column x and row y.
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.size || yy < 0 || yy >= c.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignmentPattern draws an alignment pattern centered at column x and row y.
+func (c *Code) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the format information for a level and mask,
+// together with the dark module.
+func (c *Code) drawFormatBits(level ECLevel, mask int) {
+	data := level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.size-8, true)
+}
+
+// drawVersionBits draws both copies of the version information of versions 7 and above.
+func (c *Code) drawVersionBits(version int) {
+	if version < 7 {
+		return
+	}
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		a := c.size - 11 + i%3
+		b := i / 3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords places the codeword bits in the zigzag order, skipping function modules.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if !c.isFunction[y*c.size+x] && i < len(codewords)*8 {
+					c.modules[y*c.size+x] = bit(int(codewords[i>>3]), 7-(i&7))
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the non-function modules selected by a mask pattern.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !c.isFunction[y*c.size+x] {
+				c.modules[y*c.size+x] = !c.modules[y*c.size+x]
+			}
+		}
+	}
+}
+
+// penaltyScore evaluates the symbol with the four mask evaluation rules; lower is better.
+func (c *Code) penaltyScore() int {
+	penalty := 0
+
+	// Rule 1: runs of five or more modules of the same color in a row or column.
+	for y := 0; y < c.size; y++ {
+		penalty += runPenalty(c.size, func(i int) bool { return c.Dark(i, y) })
+	}
+	for x := 0; x < c.size; x++ {
+		penalty += runPenalty(c.size, func(i int) bool { return c.Dark(x, i) })
+	}
+
+	// Rule 2: 2x2 blocks of the same color.
+	for y := 0; y < c.size-1; y++ {
+		for x := 0; x < c.size-1; x++ {
+			color := c.Dark(x, y)
+			if color == c.Dark(x+1, y) && color == c.Dark(x, y+1) && color == c.Dark(x+1, y+1) {
+				penalty += penaltyN2
+			}
+		}
+	}
+
+	// Rule 3: patterns resembling a finder pattern in a row or column.
+	finderLike := [2][11]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for y := 0; y < c.size; y++ {
+		for x := 0; x+11 <= c.size; x++ {
+			for _, pattern := range finderLike {
+				row, col := true, true
+				for k, dark := range pattern {
+					row = row && c.Dark(x+k, y) == dark
+					col = col && c.Dark(y, x+k) == dark
+				}
+				if row {
+					penalty += penaltyN3
+				}
+				if col {
+					penalty += penaltyN3
+				}
+			}
+		}
+	}
+
+	// Rule 4: deviation of the proportion of dark modules from one half.
+	dark := 0
+	for _, module := range c.modules {
+		if module {
+			dark++
+		}
+	}
+	total := c.size * c.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	penalty += k * penaltyN4
+	return penalty
+}
+
+// runPenalty scores the runs of same-colored modules of a single line of n modules.
+func runPenalty(n int, dark func(i int) bool) int {
+	penalty := 0
+	run := 1
+	for i := 1; i <= n; i++ {
+		if i < n && dark(i) == dark(i-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += penaltyN1 + run - 5
+		}
+		run = 1
+	}
+	return penalty
+}
+
+// bit reports whether bit i of x is set.
+func bit(x, i int) bool {
+	return (x>>uint(i))&1 != 0
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// bitBuffer accumulates bits most significant first.
+type bitBuffer struct {
+	data []byte
+	n    int
+}
+
+// newBitBuffer creates a buffer with room for capacity bits.
+func newBitBuffer(capacity int) *bitBuffer {
+	return &bitBuffer{data: make([]byte, 0, (capacity+7)/8)}
+}
+
+// append adds the low length bits of value.
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.data = append(b.data, 0)
+		}
+		if bit(value, i) {
+			b.data[b.n/8] |= 0x80 >> uint(b.n%8)
+		}
+		b.n++
+	}
+}
+
+// len returns the number of bits in the buffer.
+func (b *bitBuffer) len() int {
+	return b.n
+}
+
+// bytes returns the buffered bits; the last byte is padded with zero bits.
+func (b *bitBuffer) bytes() []byte {
+	return b.data
+}
@@ -0,0 +1,124 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" in alphanumeric mode at level M, from the worked example in ISO/IEC 18004 Annex I.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	assert.Equal(t, expected, rsRemainder(data, rsDivisor(len(expected))))
+}
+
+func TestNumDataCodewords(t *testing.T) {
+	tests := []struct {
+		version  int
+		level    ECLevel
+		expected int
+	}{
+		{1, Low, 19},
+		{1, High, 9},
+		{5, Quartile, 62},
+		{10, Medium, 216},
+		{40, Low, 2956},
+		{40, High, 1276},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, numDataCodewords(tt.version, tt.level), "version %d level %d", tt.version, tt.level)
+	}
+}
+
+func TestEncodeVersionSelection(t *testing.T) {
+	tests := []struct {
+		name         string
+		length       int
+		level        ECLevel
+		expectedSize int
+		expectedErr  error
+	}{
+		{name: "Version 1", length: 17, level: Low, expectedSize: 21},
+		{name: "Version 2", length: 18, level: Low, expectedSize: 25},
+		{name: "Version 7 With Version Info", length: 154, level: Low, expectedSize: 45},
+		{name: "Version 40", length: 2953, level: Low, expectedSize: 177},
+		{name: "Too Long", length: 2954, level: Low, expectedErr: config.ErrQRDataTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Encode([]byte(strings.Repeat("a", tt.length)), tt.level)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedSize, code.Size())
+		})
+	}
+}
+
+func TestEncodeFunctionPatterns(t *testing.T) {
+	code, err := Encode([]byte("http://localhost:8080/abc"), Medium)
+	assert.NoError(t, err)
+
+	// Finder patterns have a dark center and a light separator ring.
+	for _, corner := range [][2]int{{3, 3}, {code.Size() - 4, 3}, {3, code.Size() - 4}} {
+		assert.True(t, code.Dark(corner[0], corner[1]))
+		assert.False(t, code.Dark(corner[0]+2, corner[1]))
+		assert.True(t, code.Dark(corner[0]+3, corner[1]))
+	}
+	// The dark module is always set.
+	assert.True(t, code.Dark(8, code.Size()-8))
+	// Timing patterns alternate.
+	for i := 8; i < code.Size()-8; i++ {
+		assert.Equal(t, i%2 == 0, code.Dark(i, 6))
+		assert.Equal(t, i%2 == 0, code.Dark(6, i))
+	}
+}
+
+func TestParseECLevel(t *testing.T) {
+	level, err := ParseECLevel("q")
+	assert.NoError(t, err)
+	assert.Equal(t, Quartile, level)
+
+	_, err = ParseECLevel("X")
+	assert.ErrorIs(t, err, config.ErrInvalidECLevel)
+}
+
+func TestWritePNG(t *testing.T) {
+	code, err := Encode([]byte("http://localhost:8080/abc"), Medium)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, code.WritePNG(&buf, 256, 4))
+	img, err := png.Decode(&buf)
+	assert.NoError(t, err)
+
+	// 25 modules plus the quiet zone at 7 pixels per module.
+	modules := code.Size() + 8
+	assert.Equal(t, modules*(256/modules), img.Bounds().Dx())
+
+	scale := 256 / modules
+	r, _, _, _ := img.At(4*scale, 4*scale).RGBA()
+	assert.Zero(t, r, "top-left finder corner should be dark")
+	r, _, _, _ = img.At(0, 0).RGBA()
+	assert.NotZero(t, r, "quiet zone should be light")
+}
+
+func TestWriteSVG(t *testing.T) {
+	code, err := Encode([]byte("http://localhost:8080/abc"), Medium)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, code.WriteSVG(&buf, 300, 2))
+	svg := buf.String()
+	assert.Contains(t, svg, `width="300" height="300"`)
+	assert.Contains(t, svg, `viewBox="0 0 29 29"`)
+	assert.Contains(t, svg, "M2,2h1v1h-1z")
+}
@@ -0,0 +1,59 @@
+package qrcode
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// WritePNG renders the symbol as a square PNG image of about size pixels, surrounded by a quiet
+// zone of margin modules. Every module is drawn with the same whole number of pixels, at least one,
+// so the image may be slightly smaller than requested, or larger if size is too small for the symbol.
+func (c *Code) WritePNG(w io.Writer, size, margin int) error {
+	modules := c.size + 2*margin
+	scale := size / modules
+	if scale < 1 {
+		scale = 1
+	}
+
+	palette := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, modules*scale, modules*scale), palette)
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[((y+margin)*scale+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[(x+margin)*scale+dx] = 1
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// WriteSVG renders the symbol as an SVG image of size by size pixels, surrounded by a quiet zone
+// of margin modules. The image is vector based, so it scales to any size without losing sharpness.
+func (c *Code) WriteSVG(w io.Writer, size, margin int) error {
+	modules := c.size + 2*margin
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		size, size, modules, modules)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="#FFFFFF"/>`+"\n")
+	fmt.Fprint(bw, `<path fill="#000000" d="`)
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.Dark(x, y) {
+				fmt.Fprintf(bw, "M%d,%dh1v1h-1z", x+margin, y+margin)
+			}
+		}
+	}
+	fmt.Fprint(bw, `"/>`+"\n</svg>\n")
+	return bw.Flush()
+}
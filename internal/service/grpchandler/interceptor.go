@@ -0,0 +1,73 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenMetadataKey is the metadata key that carries the user's JWT in requests and response headers.
+const TokenMetadataKey = "token"
+
+// errNoToken indicates that a request carries no JWT in its metadata.
+var errNoToken = errors.New("no token in metadata")
+
+// AuthInterceptor is the gRPC counterpart of middleware.EnsureUserCookie.
+// It reads the user's JWT from the "token" metadata key and stores the user ID in the context
+// under config.UserContextKey. If the token is missing or invalid, a new user ID is generated
+// and its JWT is returned to the client in the "token" response header.
+// Tokens that cannot be verified for any other reason are rejected with codes.Unauthenticated.
+func AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	userID, authErr := userIDFromMetadata(ctx)
+	if authErr != nil {
+		if !errors.Is(authErr, errNoToken) && !errors.Is(authErr, config.ErrTokenInvalid) {
+			logger.Infof("Failed to authorize due to error: %v", authErr)
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		userID = uuid.New().String()
+		token, err := utils.GenerateJWT(userID, config.JwtKeySecret)
+		if err != nil {
+			logger.Errorf("Error generating token: %v", err)
+			return nil, status.Error(codes.Internal, "Internal Server Error")
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(TokenMetadataKey, token)); err != nil {
+			logger.Errorf("Error sending token header: %v", err)
+		}
+		logger.Infof("Generated new user ID and sent it in metadata due to error: %v", authErr)
+	}
+	return handler(context.WithValue(ctx, config.UserContextKey, userID), req)
+}
+
+// userIDFromMetadata extracts and verifies the JWT from the incoming metadata of ctx.
+func userIDFromMetadata(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errNoToken
+	}
+	values := md.Get(TokenMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return "", errNoToken
+	}
+	claims, err := utils.VerifyJWT(values[0], config.JwtKeySecret)
+	if err != nil {
+		return "", err
+	}
+	return claims.UserID, nil
+}
+
+// userIDFromContext returns the user ID stored by AuthInterceptor.
+func userIDFromContext(ctx context.Context) (string, error) {
+	userID, ok := ctx.Value(config.UserContextKey).(string)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	return userID, nil
+}
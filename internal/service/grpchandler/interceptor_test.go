@@ -0,0 +1,54 @@
+package grpchandler_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/service/grpchandler"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	validToken, err := utils.GenerateJWT("valid-user-id", config.JwtKeySecret)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		md           metadata.MD
+		expectedCode codes.Code
+		expectedUser string
+	}{
+		{
+			name:         "Valid Token",
+			md:           metadata.Pairs(grpchandler.TokenMetadataKey, validToken),
+			expectedCode: codes.OK,
+			expectedUser: "valid-user-id",
+		},
+		{
+			name:         "Malformed Token",
+			md:           metadata.Pairs(grpchandler.TokenMetadataKey, "not-a-jwt"),
+			expectedCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			var gotUser string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				gotUser, _ = ctx.Value(config.UserContextKey).(string)
+				return nil, nil
+			}
+
+			_, err := grpchandler.AuthInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Equal(t, tt.expectedUser, gotUser)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Package grpchandler contains the gRPC counterpart of the HTTP handlers.
+// It exposes URL creation, lookup, listing and deletion over the Shortener service
+// and identifies users by a JWT carried in request metadata instead of a cookie.
+package grpchandler
+
+import (
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	pb "github.com/gleb-korostelev/short-url.git/internal/proto"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/throttle"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	"google.golang.org/grpc"
+)
+
+// ShortenerService implements the pb.ShortenerServer interface on top of the same
+// storage and worker pool used by the HTTP handlers.
+type ShortenerService struct {
+	pb.UnimplementedShortenerServer
+
+	store     storage.Storage      // store is the interface to the URL storage backend.
+	worker    *worker.DBWorkerPool // worker handles asynchronous tasks using a worker pool.
+	passwords *throttle.Limiter    // passwords counts wrong passwords per protected short URL.
+	jobs      *worker.DeletionJobs // jobs persists deletions as jobs; nil deletes in the background without a job.
+}
+
+// Option configures optional dependencies of a ShortenerService.
+type Option func(*ShortenerService)
+
+// WithDeletionJobs makes DeleteURLs persist every deletion as a job of jobs and enables GetJob,
+// like handler.WithDeletionJobs does for the HTTP API.
+func WithDeletionJobs(jobs *worker.DeletionJobs) Option {
+	return func(s *ShortenerService) {
+		s.jobs = jobs
+	}
+}
+
+// WithPasswordLimiter makes the wrong passwords of protected links count against limiter,
+// like handler.WithPasswordLimiter does for the HTTP API, so that both APIs share one budget.
+func WithPasswordLimiter(limiter *throttle.Limiter) Option {
+	return func(s *ShortenerService) {
+		s.passwords = limiter
+	}
+}
+
+// NewShortenerService creates a new instance of ShortenerService with the provided storage
+// and worker pool implementations.
+//
+// store: Provides access to the URL storage and manipulation functions.
+// worker: Manages asynchronous execution of background tasks that shouldn't block the RPC handlers.
+// opts: Optional dependencies such as the deletion jobs and the password limiter.
+func NewShortenerService(store storage.Storage, worker *worker.DBWorkerPool, opts ...Option) *ShortenerService {
+	s := &ShortenerService{
+		store:     store,
+		worker:    worker,
+		passwords: throttle.NewLimiter(config.PasswordMaxAttempts, config.PasswordAttemptWindow),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewServer creates a gRPC server with the Shortener service svc registered and
+// TracingInterceptor and AuthInterceptor installed in front of every unary call.
+//
+// svc: The service, as created by NewShortenerService.
+// opts: Additional server options such as transport credentials.
+func NewServer(svc *ShortenerService, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(TracingInterceptor, AuthInterceptor))
+	server := grpc.NewServer(opts...)
+	pb.RegisterShortenerServer(server, svc)
+	return server
+}
@@ -0,0 +1,145 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	pb "github.com/gleb-korostelev/short-url.git/internal/proto"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Shorten is the gRPC counterpart of the POST /api/shorten handler.
+// The URL is saved through the worker pool, like in the HTTP handler.
+//
+// It returns codes.InvalidArgument for a URL that cannot be shortened or is rejected by the URL policy,
+// or an invalid alias, expiration or password, and codes.AlreadyExists if the custom alias is taken. If the URL was shortened before,
+// the existing short URL is returned with already_exists set.
+func (s *ShortenerService) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	originalURL, err := utils.NormalizeURL(req.GetUrl())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	opts, err := utils.BuildShortenOptions(req.GetCustomAlias(), timestampToTime(req.GetExpiresAt()), req.GetTtlSeconds(), req.GetPassword())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var (
+		shortURL string
+		code     int
+		saveErr  error
+	)
+	doneChan := make(chan struct{})
+	err = s.worker.AddTask(worker.Task{
+		Action: func(ctx context.Context) error {
+			shortURL, code, saveErr = s.store.SaveUniqueURL(ctx, originalURL, userID, opts)
+			return nil
+		},
+		Done: doneChan,
+		Ctx:  ctx,
+	})
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	<-doneChan
+
+	if errors.Is(saveErr, context.DeadlineExceeded) || errors.Is(saveErr, context.Canceled) {
+		return nil, status.FromContextError(saveErr).Err()
+	}
+	if errors.Is(saveErr, config.ErrAliasTaken) {
+		return nil, status.Error(codes.AlreadyExists, config.ErrAliasTaken.Error())
+	}
+	if errors.Is(saveErr, config.ErrURLBlocked) {
+		return nil, status.Error(codes.InvalidArgument, saveErr.Error())
+	}
+	if saveErr != nil {
+		logger.Errorf("Error with saving data: %v", saveErr)
+		return nil, status.Error(codes.Internal, "Error with saving")
+	}
+	return &pb.ShortenResponse{
+		Result:        shortURL,
+		AlreadyExists: code == http.StatusConflict,
+	}, nil
+}
+
+// ShortenBatch is the gRPC counterpart of the POST /api/shorten/batch?atomic=true handler.
+// All items are validated before any of them is saved, and the batch is saved atomically: the response
+// has no per-item status, so a batch with a failing item saves nothing and fails as a whole.
+//
+// It returns codes.InvalidArgument for an empty batch, an invalid item or one rejected by the URL policy,
+// and codes.AlreadyExists if a requested custom alias is taken.
+func (s *ShortenerService) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := req.GetItems()
+	if len(items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Empty batch is not allowed")
+	}
+
+	batch := make([]models.BatchURL, len(items))
+	for i, item := range items {
+		originalURL, err := utils.NormalizeURL(item.GetOriginalUrl())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts, err := utils.BuildShortenOptions(item.GetCustomAlias(), timestampToTime(item.GetExpiresAt()), item.GetTtlSeconds(), item.GetPassword())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		batch[i] = models.BatchURL{OriginalURL: originalURL, Opts: opts}
+	}
+
+	results, err := s.store.SaveURLsBatch(ctx, userID, batch, true)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, status.FromContextError(err).Err()
+		}
+		logger.Errorf("Error with saving data: %v", err)
+		return nil, status.Error(codes.Internal, "Error with saving")
+	}
+	for _, result := range results {
+		switch {
+		case result.Err == nil, errors.Is(result.Err, config.ErrBatchAborted):
+		case errors.Is(result.Err, config.ErrAliasTaken):
+			return nil, status.Error(codes.AlreadyExists, config.ErrAliasTaken.Error())
+		case errors.Is(result.Err, config.ErrURLBlocked):
+			return nil, status.Error(codes.InvalidArgument, result.Err.Error())
+		default:
+			logger.Errorf("Error with saving data: %v", result.Err)
+			return nil, status.Error(codes.Internal, "Error with saving")
+		}
+	}
+	resp := &pb.ShortenBatchResponse{Items: make([]*pb.ShortenBatchResponseItem, 0, len(items))}
+	for i, result := range results {
+		resp.Items = append(resp.Items, &pb.ShortenBatchResponseItem{
+			CorrelationId: items[i].GetCorrelationId(),
+			ShortUrl:      result.ShortURL,
+		})
+	}
+	return resp, nil
+}
+
+// timestampToTime converts an optional protobuf timestamp into an optional time.
+func timestampToTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
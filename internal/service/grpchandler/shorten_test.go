@@ -0,0 +1,166 @@
+package grpchandler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	pb "github.com/gleb-korostelev/short-url.git/internal/proto"
+	"github.com/gleb-korostelev/short-url.git/internal/service/grpchandler"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestShorten(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := grpchandler.NewShortenerService(mockStore, workerPool)
+
+	tests := []struct {
+		name         string
+		userID       string
+		request      *pb.ShortenRequest
+		setupMocks   func()
+		expectedCode codes.Code
+		expectedResp *pb.ShortenResponse
+	}{
+		{
+			name:         "Unauthenticated",
+			request:      &pb.ShortenRequest{Url: "http://example.com"},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Empty URL",
+			userID:       "valid-user-id",
+			request:      &pb.ShortenRequest{},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Invalid Alias",
+			userID:       "valid-user-id",
+			request:      &pb.ShortenRequest{Url: "http://example.com", CustomAlias: "a"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:    "Alias Taken",
+			userID:  "valid-user-id",
+			request: &pb.ShortenRequest{Url: "http://example.com", CustomAlias: "promo"},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{CustomAlias: "promo"}).
+					Return("", http.StatusConflict, config.ErrAliasTaken)
+			},
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name:    "Database Error",
+			userID:  "valid-user-id",
+			request: &pb.ShortenRequest{Url: "http://example.com"},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{}).
+					Return("", http.StatusInternalServerError, errors.New("db error"))
+			},
+			expectedCode: codes.Internal,
+		},
+		{
+			name:    "URL Already Exists",
+			userID:  "valid-user-id",
+			request: &pb.ShortenRequest{Url: "http://example.com"},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{}).
+					Return("http://short.url/abc123", http.StatusConflict, nil)
+			},
+			expectedCode: codes.OK,
+			expectedResp: &pb.ShortenResponse{Result: "http://short.url/abc123", AlreadyExists: true},
+		},
+		{
+			name:    "Successful Shorten",
+			userID:  "valid-user-id",
+			request: &pb.ShortenRequest{Url: "http://example.com"},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{}).
+					Return("http://short.url/abc123", http.StatusCreated, nil)
+			},
+			expectedCode: codes.OK,
+			expectedResp: &pb.ShortenResponse{Result: "http://short.url/abc123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setupMocks != nil {
+				tt.setupMocks()
+			}
+			ctx := context.Background()
+			if tt.userID != "" {
+				ctx = context.WithValue(ctx, config.UserContextKey, tt.userID)
+			}
+
+			resp, err := svc.Shorten(ctx, tt.request)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedResp != nil {
+				assert.Equal(t, tt.expectedResp.GetResult(), resp.GetResult())
+				assert.Equal(t, tt.expectedResp.GetAlreadyExists(), resp.GetAlreadyExists())
+			}
+		})
+	}
+}
+
+func TestShortenBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := grpchandler.NewShortenerService(mockStore, workerPool)
+	ctx := context.WithValue(context.Background(), config.UserContextKey, "valid-user-id")
+
+	_, err := svc.ShortenBatch(ctx, &pb.ShortenBatchRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockStore.EXPECT().
+		SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{
+			{OriginalURL: "http://example.com/1"},
+			{OriginalURL: "http://example.com/2"},
+		}, true).
+		Return([]models.BatchURLResult{{ShortURL: "http://short.url/one"}, {ShortURL: "http://short.url/two"}}, nil)
+
+	resp, err := svc.ShortenBatch(ctx, &pb.ShortenBatchRequest{Items: []*pb.ShortenBatchRequestItem{
+		{CorrelationId: "1", OriginalUrl: "http://example.com/1"},
+		{CorrelationId: "2", OriginalUrl: "http://example.com/2"},
+	}})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.GetItems(), 2) {
+		assert.Equal(t, "1", resp.GetItems()[0].GetCorrelationId())
+		assert.Equal(t, "http://short.url/one", resp.GetItems()[0].GetShortUrl())
+		assert.Equal(t, "2", resp.GetItems()[1].GetCorrelationId())
+		assert.Equal(t, "http://short.url/two", resp.GetItems()[1].GetShortUrl())
+	}
+
+	// A taken alias aborts the whole batch, so the other item is not saved either.
+	mockStore.EXPECT().
+		SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{
+			{OriginalURL: "http://example.com/1"},
+			{OriginalURL: "http://example.com/2", Opts: models.ShortenOptions{CustomAlias: "taken"}},
+		}, true).
+		Return([]models.BatchURLResult{{Err: config.ErrBatchAborted}, {Err: config.ErrAliasTaken}}, nil)
+
+	_, err = svc.ShortenBatch(ctx, &pb.ShortenBatchRequest{Items: []*pb.ShortenBatchRequestItem{
+		{CorrelationId: "1", OriginalUrl: "http://example.com/1"},
+		{CorrelationId: "2", OriginalUrl: "http://example.com/2", CustomAlias: "taken"},
+	}})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
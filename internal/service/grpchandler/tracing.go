@@ -0,0 +1,33 @@
+package grpchandler
+
+import (
+	"context"
+
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TracingInterceptor is the gRPC counterpart of middleware.TracingMiddleware.
+// It starts a server span named after the full method of every unary call, continuing the
+// trace of the caller when the "traceparent" metadata key carries a W3C trace context.
+func TracingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tracing.TraceparentHeader); len(values) > 0 {
+			if parent, ok := tracing.ParseTraceparent(values[0]); ok {
+				ctx = tracing.ContextWithRemoteParent(ctx, parent)
+			}
+		}
+	}
+	ctx, span := tracing.Start(ctx, info.FullMethod, tracing.WithKind(tracing.KindServer),
+		tracing.WithAttributes("rpc.system", "grpc", "rpc.method", info.FullMethod))
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		span.SetAttribute("rpc.grpc.status_code", int(status.Code(err)))
+		span.RecordError(err)
+	}
+	return resp, err
+}
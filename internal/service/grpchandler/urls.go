@@ -0,0 +1,193 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	pb "github.com/gleb-korostelev/short-url.git/internal/proto"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GetOriginal is the gRPC counterpart of the GET /{id} redirect handler.
+// Instead of redirecting, it returns the original URL.
+//
+// It returns codes.NotFound if the short URL is unknown, deleted or expired;
+// the message tells deleted and expired links apart. Password protected links require
+// the password in the request: it returns codes.PermissionDenied if it is missing or wrong,
+// and codes.ResourceExhausted once too many wrong passwords were tried for the short URL.
+// If the original URL is rejected by the URL policy, it returns codes.PermissionDenied.
+func (s *ShortenerService) GetOriginal(ctx context.Context, req *pb.GetOriginalRequest) (*pb.GetOriginalResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "ID is required")
+	}
+
+	originalURL, err := s.store.GetOriginalLink(ctx, req.GetId())
+	if errors.Is(err, config.ErrPasswordRequired) {
+		return s.unlockLink(ctx, req)
+	}
+	if err != nil {
+		return nil, lookupError(err)
+	}
+	return &pb.GetOriginalResponse{OriginalUrl: originalURL}, nil
+}
+
+// unlockLink checks the password supplied for a protected link and returns its original URL.
+func (s *ShortenerService) unlockLink(ctx context.Context, req *pb.GetOriginalRequest) (*pb.GetOriginalResponse, error) {
+	if req.GetPassword() == "" {
+		return nil, status.Error(codes.PermissionDenied, config.ErrPasswordRequired.Error())
+	}
+	now := time.Now()
+	if _, blocked := s.passwords.Blocked(req.GetId(), now); blocked {
+		return nil, status.Error(codes.ResourceExhausted, "Too many wrong passwords, try again later")
+	}
+
+	originalURL, passwordHash, err := s.store.GetProtectedLink(ctx, req.GetId())
+	if err != nil {
+		return nil, lookupError(err)
+	}
+	if err := utils.CheckPassword(passwordHash, req.GetPassword()); err != nil {
+		s.passwords.Fail(req.GetId(), now)
+		return nil, status.Error(codes.PermissionDenied, config.ErrWrongPassword.Error())
+	}
+	s.passwords.Reset(req.GetId())
+	return &pb.GetOriginalResponse{OriginalUrl: originalURL}, nil
+}
+
+// lookupError converts a failed lookup of a short URL into a gRPC status.
+func lookupError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return status.FromContextError(err).Err()
+	}
+	if errors.Is(err, config.ErrGone) || errors.Is(err, config.ErrExpired) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, config.ErrURLBlocked) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.NotFound, "This URL doesn't exist")
+}
+
+// ListUserURLs is the gRPC counterpart of the GET /api/user/urls handler.
+// An empty list is returned if the user has no URLs.
+func (s *ShortenerService) ListUserURLs(ctx context.Context, _ *emptypb.Empty) (*pb.ListUserURLsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	urls, err := s.store.GetAllURLS(ctx, userID, config.BaseURL)
+	if err != nil {
+		logger.Errorf("Error retrieving URLs from store: %v", err)
+		return nil, status.Error(codes.Internal, "Internal Server Error")
+	}
+
+	resp := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(urls))}
+	for _, url := range urls {
+		resp.Urls = append(resp.Urls, &pb.UserURL{
+			ShortUrl:    url.ShortURL,
+			OriginalUrl: url.OriginalURL,
+		})
+	}
+	return resp, nil
+}
+
+// DeleteURLs is the gRPC counterpart of the DELETE /api/user/urls handler.
+// The call returns without waiting for the deletion; failing to hand it to the worker pool
+// results in codes.Unavailable.
+//
+// With deletion jobs configured, the deletion is persisted as a job before the call returns, and the
+// response carries the job ID, to be passed to GetJob, and the URL of GET /api/user/jobs/{id}.
+// Failing to persist the job results in codes.Internal.
+func (s *ShortenerService) DeleteURLs(ctx context.Context, req *pb.DeleteURLsRequest) (*pb.DeleteURLsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	shortURLs := req.GetShortUrls()
+	if s.jobs != nil {
+		job, err := s.jobs.Submit(ctx, userID, shortURLs)
+		if err != nil {
+			logger.Errorf("Error queueing deletion job: %v", err)
+			return nil, status.Error(codes.Internal, "Internal Server Error")
+		}
+		return &pb.DeleteURLsResponse{
+			JobId:     job.ID,
+			StatusUrl: config.BaseURL + "/api/user/jobs/" + job.ID,
+		}, nil
+	}
+
+	err = s.worker.AddTask(worker.Task{
+		Action: func(ctx context.Context) error {
+			err := s.store.MarkURLsAsDeleted(ctx, userID, shortURLs)
+			if err != nil {
+				logger.Errorf("Internal server error %v", err)
+				return err
+			}
+			return nil
+		},
+		// The task outlives the call, so it keeps the trace but not the cancellation.
+		Ctx: context.WithoutCancel(ctx),
+	})
+	if err != nil {
+		logger.Errorf("Error queueing deletion: %v", err)
+		return nil, status.Error(codes.Unavailable, "Deletion could not be queued")
+	}
+	return &pb.DeleteURLsResponse{}, nil
+}
+
+// GetJob is the gRPC counterpart of the GET /api/user/jobs/{id} handler.
+//
+// It returns codes.NotFound if the job does not exist and codes.PermissionDenied if it belongs
+// to another user.
+func (s *ShortenerService) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.Job, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Job IDs are UUIDs, so anything else cannot name a job.
+	if _, err := uuid.Parse(req.GetId()); err != nil || s.jobs == nil {
+		return nil, status.Error(codes.NotFound, config.ErrNotFound.Error())
+	}
+
+	job, err := s.jobs.Job(ctx, req.GetId())
+	if err != nil {
+		if errors.Is(err, config.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, config.ErrNotFound.Error())
+		}
+		logger.Errorf("Error retrieving job: %v", err)
+		return nil, status.Error(codes.Internal, "Internal Server Error")
+	}
+	if job.UserID != userID {
+		return nil, status.Error(codes.PermissionDenied, config.ErrForbidden.Error())
+	}
+	return &pb.Job{
+		Id:            job.ID,
+		ShortUrls:     job.ShortURLs,
+		Status:        job.Status,
+		Attempts:      int32(job.Attempts),
+		LastError:     job.LastError,
+		NextAttemptAt: timestamppb.New(job.NextAttemptAt),
+		CreatedAt:     timestamppb.New(job.CreatedAt),
+		UpdatedAt:     timestamppb.New(job.UpdatedAt),
+	}, nil
+}
+
+// Ping is the gRPC counterpart of the GET /ping handler.
+// It returns codes.Unavailable if the storage cannot be reached.
+func (s *ShortenerService) Ping(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if _, err := s.store.Ping(ctx); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,280 @@
+package grpchandler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/jobs"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	pb "github.com/gleb-korostelev/short-url.git/internal/proto"
+	"github.com/gleb-korostelev/short-url.git/internal/service/grpchandler"
+	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
+	"github.com/gleb-korostelev/short-url.git/internal/throttle"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestGetOriginal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := grpchandler.NewShortenerService(mockStore, workerPool)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		id           string
+		password     string
+		setupMocks   func()
+		expectedCode codes.Code
+		expectedURL  string
+	}{
+		{
+			name:         "Empty ID",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "Password Missing",
+			id:   "locked",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "locked").Return("", config.ErrPasswordRequired)
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Password Wrong",
+			id:       "locked",
+			password: "guess",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "locked").Return("", config.ErrPasswordRequired)
+				mockStore.EXPECT().GetProtectedLink(gomock.Any(), "locked").Return("http://example.com/doc", string(hash), nil)
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Password Correct",
+			id:       "locked",
+			password: "secret",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "locked").Return("", config.ErrPasswordRequired)
+				mockStore.EXPECT().GetProtectedLink(gomock.Any(), "locked").Return("http://example.com/doc", string(hash), nil)
+			},
+			expectedCode: codes.OK,
+			expectedURL:  "http://example.com/doc",
+		},
+		{
+			name: "URL Gone",
+			id:   "gone",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "gone").Return("", config.ErrGone)
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "URL Not Found",
+			id:   "missing",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "missing").Return("", errors.New("not found"))
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "URL Found",
+			id:   "abc123",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "abc123").Return("http://example.com", nil)
+			},
+			expectedCode: codes.OK,
+			expectedURL:  "http://example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setupMocks != nil {
+				tt.setupMocks()
+			}
+			resp, err := svc.GetOriginal(context.Background(), &pb.GetOriginalRequest{Id: tt.id, Password: tt.password})
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Equal(t, tt.expectedURL, resp.GetOriginalUrl())
+		})
+	}
+}
+
+func TestGetOriginalSharedPasswordLimiter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "locked").Return("", config.ErrPasswordRequired).AnyTimes()
+	mockStore.EXPECT().GetProtectedLink(gomock.Any(), "locked").Return("http://example.com", string(hash), nil).AnyTimes()
+
+	// The wrong passwords are sent over HTTP, the right one over gRPC.
+	passwords := throttle.NewLimiter(config.PasswordMaxAttempts, config.PasswordAttemptWindow)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	apiSvc := handler.NewAPIService(mockStore, workerPool, handler.WithPasswordLimiter(passwords))
+	svc := grpchandler.NewShortenerService(mockStore, workerPool, grpchandler.WithPasswordLimiter(passwords))
+
+	r := chi.NewRouter()
+	r.Get("/{id}", apiSvc.GetOriginal)
+	for i := 0; i < config.PasswordMaxAttempts; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/locked", nil)
+		req.Header.Set(config.LinkPasswordHeader, "guess")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	}
+
+	_, err = svc.GetOriginal(context.Background(), &pb.GetOriginalRequest{Id: "locked", Password: "secret"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestListUserURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := grpchandler.NewShortenerService(mockStore, workerPool)
+	ctx := context.WithValue(context.Background(), config.UserContextKey, "valid-user-id")
+
+	mockStore.EXPECT().GetAllURLS(gomock.Any(), "valid-user-id", config.BaseURL).
+		Return([]models.UserURLs{{ShortURL: "http://short.url/abc123", OriginalURL: "http://example.com"}}, nil)
+
+	resp, err := svc.ListUserURLs(ctx, &emptypb.Empty{})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.GetUrls(), 1) {
+		assert.Equal(t, "http://short.url/abc123", resp.GetUrls()[0].GetShortUrl())
+		assert.Equal(t, "http://example.com", resp.GetUrls()[0].GetOriginalUrl())
+	}
+
+	_, err = svc.ListUserURLs(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestDeleteURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := grpchandler.NewShortenerService(mockStore, workerPool)
+	ctx := context.WithValue(context.Background(), config.UserContextKey, "valid-user-id")
+
+	deleted := make(chan struct{})
+	mockStore.EXPECT().MarkURLsAsDeleted(gomock.Any(), "valid-user-id", []string{"abc123"}).
+		DoAndReturn(func(context.Context, string, []string) error {
+			close(deleted)
+			return nil
+		})
+
+	_, err := svc.DeleteURLs(ctx, &pb.DeleteURLsRequest{ShortUrls: []string{"abc123"}})
+	assert.NoError(t, err)
+
+	select {
+	case <-deleted:
+	case <-time.After(time.Second):
+		t.Fatal("deletion was not submitted to the worker pool")
+	}
+}
+
+func TestDeleteURLsJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	deletionJobs := worker.NewDeletionJobs(workerPool, jobs.NewMemoryQueue(time.Minute), mockStore,
+		time.Hour, config.JobMaxAttempts, time.Second, time.Minute)
+	svc := grpchandler.NewShortenerService(mockStore, workerPool, grpchandler.WithDeletionJobs(deletionJobs))
+	withUser := func(userID string) context.Context {
+		return context.WithValue(context.Background(), config.UserContextKey, userID)
+	}
+
+	// The deletion is accepted as a job without being run, since the job runner is not started.
+	resp, err := svc.DeleteURLs(withUser("owner-id"), &pb.DeleteURLsRequest{ShortUrls: []string{"abc", "def"}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.GetJobId())
+	assert.Equal(t, config.BaseURL+"/api/user/jobs/"+resp.GetJobId(), resp.GetStatusUrl())
+
+	tests := []struct {
+		name         string
+		id           string
+		userID       string
+		expectedCode codes.Code
+	}{
+		{
+			name:         "Owner",
+			id:           resp.GetJobId(),
+			userID:       "owner-id",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Other User",
+			id:           resp.GetJobId(),
+			userID:       "other-id",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Unknown Job",
+			id:           "0b6b4b9e-4f4a-4a43-9f5c-9f0c5b9d6a10",
+			userID:       "owner-id",
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "Malformed ID",
+			id:           "not-a-job",
+			userID:       "owner-id",
+			expectedCode: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := svc.GetJob(withUser(tt.userID), &pb.GetJobRequest{Id: tt.id})
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				assert.Equal(t, tt.id, job.GetId())
+				assert.Equal(t, []string{"abc", "def"}, job.GetShortUrls())
+				assert.Equal(t, config.JobStatusPending, job.GetStatus())
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := grpchandler.NewShortenerService(mockStore, workerPool)
+
+	mockStore.EXPECT().Ping(gomock.Any()).Return(http.StatusOK, nil)
+	_, err := svc.Ping(context.Background(), &emptypb.Empty{})
+	assert.NoError(t, err)
+
+	mockStore.EXPECT().Ping(gomock.Any()).Return(http.StatusInternalServerError, config.ErrWrongMode)
+	_, err = svc.Ping(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// DeletePolicyEntry handles the HTTP DELETE request removing an entry from the URL policy.
+// The entry is expected in the entry field of a JSON body, as listed by GET /api/internal/policy;
+// domains are matched case-insensitively. The change is saved to the policy file.
+// Access is granted only if the client address, as reported by utils.ClientIP, belongs to
+// config.TrustedSubnet: forwarding headers count only when sent by a trusted proxy.
+//
+// The function responds with:
+// - HTTP 403 Forbidden if the trusted subnet is not configured or the caller's address is outside it.
+// - HTTP 404 Not Found if no URL policy is enabled or the entry is not present.
+// - HTTP 400 Bad Request if the body is not valid JSON.
+// - HTTP 500 Internal Server Error if the policy file cannot be saved.
+// - HTTP 200 OK with the remaining policy mode and entries in JSON format on success.
+func (svc *APIService) DeletePolicyEntry(w http.ResponseWriter, r *http.Request) {
+	if !svc.authorizePolicy(w, r) {
+		return
+	}
+
+	var payload models.PolicyEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := svc.policy.Remove(payload.Entry); err != nil {
+		if errors.Is(err, config.ErrPolicyEntryNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Errorf("Error removing URL policy entry: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writePolicy(w, http.StatusOK, svc.policy.Mode(), svc.policy.Entries())
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
 	"github.com/gleb-korostelev/short-url.git/internal/worker"
 	"github.com/gleb-korostelev/short-url.git/tools/logger"
 )
@@ -13,6 +14,10 @@ import (
 // DeleteURLsHandler handles the HTTP DELETE request for deleting one or more URLs.
 // It requires the user to be authenticated and provides the functionality to mark URLs as deleted.
 // This handler responds with HTTP status 202 (Accepted) to indicate that the delete request has been queued.
+//
+// With a deletion queue configured, the request is persisted as a job before it is accepted and the
+// response carries the job ID and the URL of GET /api/user/jobs/{id}, also given in the Location header.
+// Failing to persist the job results in HTTP 500 Internal Server Error.
 func (svc *APIService) DeleteURLsHandler(w http.ResponseWriter, r *http.Request) {
 	// Retrieve the user ID from the context, and return HTTP 401 Unauthorized if it's missing.
 	userID, ok := r.Context().Value(config.UserContextKey).(string)
@@ -28,6 +33,11 @@ func (svc *APIService) DeleteURLsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if svc.jobs != nil {
+		svc.submitDeletion(w, r, userID, shortURLs)
+		return
+	}
+
 	// Add the task to delete the URLs to the worker pool.
 	svc.worker.AddTask(worker.Task{
 		Action: func(ctx context.Context) error {
@@ -39,8 +49,28 @@ func (svc *APIService) DeleteURLsHandler(w http.ResponseWriter, r *http.Request)
 			}
 			return nil
 		},
+		// The task outlives the request, so it keeps the trace but not the cancellation.
+		Ctx: context.WithoutCancel(r.Context()),
 	})
 
 	// Respond with HTTP 202 Accepted to indicate the deletion task has been queued.
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// submitDeletion persists the deletion as a job and responds with HTTP 202 Accepted and the job's status URL.
+func (svc *APIService) submitDeletion(w http.ResponseWriter, r *http.Request, userID string, shortURLs []string) {
+	job, err := svc.jobs.Submit(r.Context(), userID, shortURLs)
+	if err != nil {
+		logger.Errorf("Error queueing deletion job: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	statusURL := config.BaseURL + "/api/user/jobs/" + job.ID
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", statusURL)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(models.JobAccepted{JobID: job.ID, StatusURL: statusURL}); err != nil {
+		logger.Errorf("Error encoding job to JSON: %v", err)
+	}
+}
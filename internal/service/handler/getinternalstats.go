@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// GetInternalStats handles the HTTP GET request for the service-wide counters.
+// Access is granted only if the address in the X-Real-IP header belongs to config.TrustedSubnet.
+//
+// If the trusted subnet is not configured or the caller's address is outside it,
+// it responds with HTTP 403 Forbidden. Internal errors result in HTTP 500 Internal Server Error.
+// On success it returns the number of shortened URLs and distinct users in JSON format with HTTP 200 OK.
+func (svc *APIService) GetInternalStats(w http.ResponseWriter, r *http.Request) {
+	// Reject callers that are not in the trusted subnet.
+	if !utils.IsTrustedIP(r.Header.Get("X-Real-IP"), config.TrustedSubnet) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	urls, err := svc.store.CountURLs(r.Context())
+	if err != nil {
+		logger.Errorf("Error counting URLs: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	users, err := svc.store.CountUsers(r.Context())
+	if err != nil {
+		logger.Errorf("Error counting users: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Return the counters in JSON format with HTTP 200 OK.
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.InternalStats{URLs: urls, Users: users}); err != nil {
+		logger.Errorf("Error encoding internal stats to JSON: %v", err)
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,95 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInternalStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := handler.NewAPIService(mockStore, workerPool)
+
+	defer func(subnet string) { config.TrustedSubnet = subnet }(config.TrustedSubnet)
+
+	tests := []struct {
+		name           string
+		trustedSubnet  string
+		realIP         string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Subnet Not Configured",
+			realIP:         "192.168.1.10",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Missing Real IP",
+			trustedSubnet:  "192.168.1.0/24",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "IP Outside Subnet",
+			trustedSubnet:  "192.168.1.0/24",
+			realIP:         "10.0.0.1",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:          "Storage Error",
+			trustedSubnet: "192.168.1.0/24",
+			realIP:        "192.168.1.10",
+			setupMocks: func() {
+				mockStore.EXPECT().CountURLs(gomock.Any()).Return(0, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   "Internal Server Error\n",
+		},
+		{
+			name:          "Successful Stats",
+			trustedSubnet: "192.168.1.0/24",
+			realIP:        "192.168.1.10",
+			setupMocks: func() {
+				mockStore.EXPECT().CountURLs(gomock.Any()).Return(42, nil)
+				mockStore.EXPECT().CountUsers(gomock.Any()).Return(7, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"urls":42,"users":7}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setupMocks != nil {
+				tt.setupMocks()
+			}
+			config.TrustedSubnet = tt.trustedSubnet
+
+			req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rr := httptest.NewRecorder()
+
+			svc.GetInternalStats(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
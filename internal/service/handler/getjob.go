@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// GetJob handles the HTTP GET request for the status of a deletion job. The job ID, as returned
+// in the response to DELETE /api/user/urls, is expected as a URL parameter and the job must belong
+// to the authenticated user.
+//
+// If the user ID is missing from the context, it responds with HTTP 401 Unauthorized.
+// If the job does not exist, it responds with HTTP 404 Not Found, and if it belongs to
+// another user, with HTTP 403 Forbidden. Internal errors result in HTTP 500 Internal Server Error.
+// On success it returns the job, including its status, attempts and last error,
+// in JSON format with HTTP 200 OK.
+func (svc *APIService) GetJob(w http.ResponseWriter, r *http.Request) {
+	// Retrieve the user ID from the context, and return HTTP 401 Unauthorized if it's missing.
+	userID, ok := r.Context().Value(config.UserContextKey).(string)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Job IDs are UUIDs, so anything else cannot name a job.
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil || svc.jobs == nil {
+		http.Error(w, config.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	job, err := svc.jobs.Job(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, config.ErrNotFound) {
+			http.Error(w, config.ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Errorf("Error retrieving job: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if job.UserID != userID {
+		http.Error(w, config.ErrForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Return the job in JSON format with HTTP 200 OK.
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.Errorf("Error encoding job to JSON: %v", err)
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,108 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/jobs"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	deletionJobs := worker.NewDeletionJobs(workerPool, jobs.NewMemoryQueue(time.Minute), mockStore,
+		time.Hour, config.JobMaxAttempts, time.Second, time.Minute)
+	svc := handler.NewAPIService(mockStore, workerPool, handler.WithDeletionJobs(deletionJobs))
+
+	r := chi.NewRouter()
+	r.Delete("/api/user/urls", svc.DeleteURLsHandler)
+	r.Get("/api/user/jobs/{id}", svc.GetJob)
+
+	owner := "owner-id"
+	withUser := func(req *http.Request, userID string) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), config.UserContextKey, userID))
+	}
+
+	// The deletion is accepted as a job without being run, since the job runner is not started.
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/user/urls", bytes.NewBufferString(`["abc","def"]`))
+	r.ServeHTTP(rr, withUser(req, owner))
+	require.Equal(t, http.StatusAccepted, rr.Code)
+
+	var accepted models.JobAccepted
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&accepted))
+	assert.NotEmpty(t, accepted.JobID)
+	assert.Equal(t, config.BaseURL+"/api/user/jobs/"+accepted.JobID, accepted.StatusURL)
+	assert.Equal(t, accepted.StatusURL, rr.Header().Get("Location"))
+
+	tests := []struct {
+		name         string
+		id           string
+		userID       string
+		expectedCode int
+	}{
+		{
+			name:         "Owner",
+			id:           accepted.JobID,
+			userID:       owner,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Other User",
+			id:           accepted.JobID,
+			userID:       "other-id",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Unknown Job",
+			id:           "0b6b4b9e-4f4a-4a43-9f5c-9f0c5b9d6a10",
+			userID:       owner,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Malformed ID",
+			id:           "not-a-uuid",
+			userID:       owner,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/user/jobs/"+tt.id, nil)
+			r.ServeHTTP(rr, withUser(req, tt.userID))
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			var job models.Job
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&job))
+			assert.Equal(t, accepted.JobID, job.ID)
+			assert.Equal(t, config.JobStatusPending, job.Status)
+			assert.Equal(t, []string{"abc", "def"}, job.ShortURLs)
+		})
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/user/jobs/"+accepted.JobID, nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
@@ -3,19 +3,68 @@ package handler
 import (
 	"context"
 	"errors"
+	"html/template"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
 	"github.com/go-chi/chi/v5"
 )
 
+// passwordPrompt is the page asking for the password of a protected link.
+// It posts the password back to the short URL it was served from.
+var passwordPrompt = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Password required</title></head>
+<body>
+<p>This link is password protected.</p>
+{{if .}}<p>{{.}}</p>
+{{end}}<form method="post">
+<input type="password" name="password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// blockedWarning is the interstitial page served instead of redirecting to a URL rejected by the
+// URL policy when config.PolicyAction is config.PolicyActionWarn. It links to the URL, leaving
+// the choice to follow it to the user.
+var blockedWarning = template.Must(template.New("blocked").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Warning</title></head>
+<body>
+<p>This link leads to a site that has been flagged and may be unsafe.</p>
+<p>{{.Reason}}</p>
+<p><a href="{{.URL}}" rel="noopener noreferrer nofollow">Continue to {{.URL}}</a></p>
+</body>
+</html>
+`))
+
 // GetOriginal handles HTTP requests to retrieve the original URL based on a shortened URL identifier.
 // The shortened URL ID is expected as a URL parameter.
 //
 // If the ID is not provided or the shortened URL cannot be found, it responds with HTTP 400 Bad Request.
-// If the shortened URL has been marked as deleted, it responds with HTTP 410 Gone.
+// If the shortened URL has been marked as deleted or has expired, it responds with HTTP 410 Gone.
 // Upon successful retrieval of the original URL, it sets the HTTP Location header with the original URL
-// and responds with HTTP 307 Temporary Redirect.
+// and responds with HTTP 307 Temporary Redirect. If click analytics are enabled, the redirect is
+// recorded asynchronously with its referrer, user agent and hashed client IP.
+//
+// Password protected links only redirect once their password is supplied, either in the
+// X-Link-Password header or in the password field of a form posted to the same path. Without a
+// password an HTML page asking for it is served with HTTP 401 Unauthorized, as is a wrong password.
+// After config.PasswordMaxAttempts wrong passwords for the same ID within config.PasswordAttemptWindow,
+// further attempts are refused with HTTP 429 Too Many Requests and a Retry-After header. A posted
+// form is answered with HTTP 303 See Other so that the browser follows the redirect with GET.
+//
+// If the original URL is rejected by the URL policy, it responds with HTTP 451 Unavailable For Legal
+// Reasons or, if config.PolicyAction is config.PolicyActionWarn, with an HTML page warning about the
+// URL and linking to it. Such requests are not recorded as clicks.
 func (svc *APIService) GetOriginal(w http.ResponseWriter, r *http.Request) {
 	// Extract the 'id' URL parameter using the chi router.
 	id := chi.URLParam(r, "id")
@@ -25,20 +74,116 @@ func (svc *APIService) GetOriginal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Retrieve the original URL from the store using the provided ID.
-	originalURL, err := svc.store.GetOriginalLink(context.Background(), id)
-	if err != nil {
-		// Handle specific known errors, such as when the URL has been marked as deleted.
-		if errors.Is(err, config.ErrGone) {
-			http.Error(w, config.ErrGone.Error(), http.StatusGone)
+	originalURL, err := svc.store.GetOriginalLink(r.Context(), id)
+	if errors.Is(err, config.ErrPasswordRequired) {
+		var ok bool
+		originalURL, ok = svc.unlockLink(w, r, id)
+		if !ok {
 			return
 		}
-		// Respond with Bad Request if the URL cannot be found or other errors occur.
-		http.Error(w, "This URL doesn't exist", http.StatusBadRequest)
+		err = nil
+	}
+	if err != nil {
+		writeLookupError(w, err)
 		return
 	}
 
+	// Hand the redirect event to the click recorder without waiting for it to be stored.
+	if svc.clicks != nil {
+		svc.clicks.Record(models.Click{
+			ShortURL:  id,
+			ClickedAt: time.Now().UTC(),
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+			IPHash:    utils.HashIP(utils.ClientIP(r)),
+		})
+	}
+
 	// Set the Location header with the retrieved original URL.
 	w.Header().Set("Location", string(originalURL))
-	// Respond with Temporary Redirect to the original URL.
+	// Respond with Temporary Redirect to the original URL, or See Other for a posted password form.
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
 	w.WriteHeader(http.StatusTemporaryRedirect)
 }
+
+// unlockLink checks the password supplied for the protected link id and returns its original URL.
+// If the password is missing, wrong or may not be tried yet, the response is written and false is returned.
+func (svc *APIService) unlockLink(w http.ResponseWriter, r *http.Request, id string) (string, bool) {
+	password := r.Header.Get(config.LinkPasswordHeader)
+	fromForm := password == "" && r.Method == http.MethodPost
+	if fromForm {
+		password = r.PostFormValue("password")
+	}
+	if password == "" {
+		writePasswordPrompt(w, "")
+		return "", false
+	}
+
+	// Refuse further attempts once too many wrong passwords were tried for this link.
+	now := time.Now()
+	if wait, blocked := svc.passwords.Blocked(id, now); blocked {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many wrong passwords, try again later", http.StatusTooManyRequests)
+		return "", false
+	}
+
+	originalURL, passwordHash, err := svc.store.GetProtectedLink(r.Context(), id)
+	if err != nil {
+		writeLookupError(w, err)
+		return "", false
+	}
+	if err := utils.CheckPassword(passwordHash, password); err != nil {
+		svc.passwords.Fail(id, now)
+		if fromForm {
+			writePasswordPrompt(w, config.ErrWrongPassword.Error())
+			return "", false
+		}
+		http.Error(w, config.ErrWrongPassword.Error(), http.StatusUnauthorized)
+		return "", false
+	}
+	svc.passwords.Reset(id)
+	return originalURL, true
+}
+
+// writeLookupError responds to a failed lookup of a short URL.
+func writeLookupError(w http.ResponseWriter, err error) {
+	// Report a lookup that ran out of time instead of pretending the URL is unknown.
+	if errors.Is(err, context.DeadlineExceeded) {
+		http.Error(w, "Storage timeout", http.StatusGatewayTimeout)
+		return
+	}
+	// Refuse to redirect to a URL rejected by the URL policy, or warn about it.
+	var blocked *policy.BlockedError
+	if errors.As(err, &blocked) {
+		if config.PolicyAction == config.PolicyActionWarn {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-store")
+			blockedWarning.Execute(w, blocked)
+			return
+		}
+		http.Error(w, blocked.Error(), http.StatusUnavailableForLegalReasons)
+		return
+	}
+	// Handle specific known errors, such as when the URL has been marked as deleted.
+	if errors.Is(err, config.ErrGone) {
+		http.Error(w, config.ErrGone.Error(), http.StatusGone)
+		return
+	}
+	if errors.Is(err, config.ErrExpired) {
+		http.Error(w, config.ErrExpired.Error(), http.StatusGone)
+		return
+	}
+	// Respond with Bad Request if the URL cannot be found or other errors occur.
+	http.Error(w, "This URL doesn't exist", http.StatusBadRequest)
+}
+
+// writePasswordPrompt serves the password page with HTTP 401 Unauthorized and an optional message.
+func writePasswordPrompt(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusUnauthorized)
+	passwordPrompt.Execute(w, message)
+}
@@ -3,11 +3,17 @@ package handler_test
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
 	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
 	"github.com/gleb-korostelev/short-url.git/internal/storage/repository"
 	"github.com/gleb-korostelev/short-url.git/internal/worker"
@@ -16,12 +22,13 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func BenchmarkProcessURLs(b *testing.B) {
 	ctrl := gomock.NewController(b)
 	defer ctrl.Finish()
-	mockdb := mock_db.NewMockDatabaseI(ctrl)
+	mockdb := mock_db.NewMockDB(ctrl)
 	mockStore := repository.NewDBStorage(mockdb)
 	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
 
@@ -80,6 +87,22 @@ func TestGetOriginal(t *testing.T) {
 			expectedCode: http.StatusGone,
 			expectedLoc:  "",
 		},
+		{
+			name:         "URL Expired",
+			id:           "expired",
+			mockResponse: "",
+			mockError:    config.ErrExpired,
+			expectedCode: http.StatusGone,
+			expectedLoc:  "",
+		},
+		{
+			name:         "Storage Timeout",
+			id:           "slow",
+			mockResponse: "",
+			mockError:    context.DeadlineExceeded,
+			expectedCode: http.StatusGatewayTimeout,
+			expectedLoc:  "",
+		},
 		{
 			name:         "Invalid ID",
 			id:           "invalid",
@@ -111,3 +134,242 @@ func TestGetOriginal(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOriginalRecordsClick(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(1)
+	defer workerPool.Shutdown()
+	recorder := worker.NewClickRecorder(workerPool, mockStore, 10, 10, time.Hour)
+	svc := handler.NewAPIService(mockStore, workerPool, handler.WithClickRecorder(recorder))
+
+	r := chi.NewRouter()
+	r.Get("/{id}", svc.GetOriginal)
+
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "123").Return("http://original.url/example", nil)
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "gone").Return("", config.ErrGone)
+
+	var saved []models.Click
+	mockStore.EXPECT().SaveClicks(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, clicks []models.Click) error {
+			saved = clicks
+			return nil
+		}).Times(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		recorder.Run(ctx)
+		close(stopped)
+	}()
+
+	req, _ := http.NewRequest(http.MethodGet, "/123", nil)
+	req.Header.Set("Referer", "http://referrer.example")
+	req.Header.Set("User-Agent", "test-agent")
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/gone", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusGone, rr.Code)
+
+	// Stopping the recorder flushes the buffered click.
+	cancel()
+	<-stopped
+
+	if assert.Len(t, saved, 1) {
+		assert.Equal(t, "123", saved[0].ShortURL)
+		assert.Equal(t, "http://referrer.example", saved[0].Referrer)
+		assert.Equal(t, "test-agent", saved[0].UserAgent)
+		assert.NotEmpty(t, saved[0].IPHash)
+		assert.NotContains(t, saved[0].IPHash, "192.0.2.1")
+	}
+}
+
+func TestGetOriginalProtected(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		header         string
+		form           string
+		expectLookup   bool
+		expectedCode   int
+		expectedLoc    string
+		expectedInBody string
+	}{
+		{
+			name:           "Prompt Without Password",
+			method:         http.MethodGet,
+			expectedCode:   http.StatusUnauthorized,
+			expectedInBody: `<form method="post">`,
+		},
+		{
+			name:         "Header Password",
+			method:       http.MethodGet,
+			header:       "secret",
+			expectLookup: true,
+			expectedCode: http.StatusTemporaryRedirect,
+			expectedLoc:  "http://original.url/example",
+		},
+		{
+			name:         "Wrong Header Password",
+			method:       http.MethodGet,
+			header:       "guess",
+			expectLookup: true,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Form Password",
+			method:       http.MethodPost,
+			form:         "secret",
+			expectLookup: true,
+			expectedCode: http.StatusSeeOther,
+			expectedLoc:  "http://original.url/example",
+		},
+		{
+			name:           "Wrong Form Password",
+			method:         http.MethodPost,
+			form:           "guess",
+			expectLookup:   true,
+			expectedCode:   http.StatusUnauthorized,
+			expectedInBody: config.ErrWrongPassword.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mock_db.NewMockStorage(ctrl)
+			svc := handler.NewAPIService(mockStore, worker.NewDBWorkerPool(config.MaxConcurrentUpdates))
+
+			r := chi.NewRouter()
+			r.Get("/{id}", svc.GetOriginal)
+			r.Post("/{id}", svc.GetOriginal)
+
+			mockStore.EXPECT().GetOriginalLink(gomock.Any(), "locked").Return("", config.ErrPasswordRequired)
+			if tt.expectLookup {
+				mockStore.EXPECT().GetProtectedLink(gomock.Any(), "locked").Return("http://original.url/example", string(hash), nil)
+			}
+
+			var body io.Reader
+			if tt.form != "" {
+				body = strings.NewReader(url.Values{"password": {tt.form}}.Encode())
+			}
+			req := httptest.NewRequest(tt.method, "/locked", body)
+			if tt.form != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.header != "" {
+				req.Header.Set(config.LinkPasswordHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.expectedLoc, rr.Header().Get("Location"))
+			if tt.expectedInBody != "" {
+				assert.Contains(t, rr.Body.String(), tt.expectedInBody)
+			}
+		})
+	}
+}
+
+func TestGetOriginalPasswordThrottling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	svc := handler.NewAPIService(mockStore, worker.NewDBWorkerPool(config.MaxConcurrentUpdates))
+
+	r := chi.NewRouter()
+	r.Get("/{id}", svc.GetOriginal)
+
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), gomock.Any()).Return("", config.ErrPasswordRequired).AnyTimes()
+	mockStore.EXPECT().GetProtectedLink(gomock.Any(), gomock.Any()).Return("http://original.url/example", string(hash), nil).AnyTimes()
+
+	get := func(id, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		req.Header.Set(config.LinkPasswordHeader, password)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < config.PasswordMaxAttempts; i++ {
+		assert.Equal(t, http.StatusUnauthorized, get("locked", "guess").Code)
+	}
+
+	// Even the right password is refused until the window ends.
+	rr := get("locked", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	// Other links are throttled independently.
+	assert.Equal(t, http.StatusTemporaryRedirect, get("other", "secret").Code)
+}
+
+func TestGetOriginalBlocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := handler.NewAPIService(mockStore, workerPool)
+
+	r := chi.NewRouter()
+	r.Get("/{id}", svc.GetOriginal)
+
+	defer func(action string) { config.PolicyAction = action }(config.PolicyAction)
+
+	tests := []struct {
+		name         string
+		action       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Blocked",
+			action:       config.PolicyActionBlock,
+			expectedCode: http.StatusUnavailableForLegalReasons,
+			expectedBody: `URL is blocked by policy: matches "*.evil.com"`,
+		},
+		{
+			name:         "Warning Page",
+			action:       config.PolicyActionWarn,
+			expectedCode: http.StatusOK,
+			expectedBody: `<a href="http://www.evil.com/x?a=1&amp;b=2" rel="noopener noreferrer nofollow">`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.PolicyAction = tt.action
+			mockStore.EXPECT().GetOriginalLink(gomock.Any(), "123").Return("", &policy.BlockedError{
+				URL:    "http://www.evil.com/x?a=1&b=2",
+				Reason: `matches "*.evil.com"`,
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/123", nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Empty(t, rr.Header().Get("Location"))
+			assert.Contains(t, rr.Body.String(), tt.expectedBody)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// GetPolicy handles the HTTP GET request for the entries of the URL policy.
+// Access is granted only if the client address, as reported by utils.ClientIP, belongs to
+// config.TrustedSubnet: forwarding headers count only when sent by a trusted proxy.
+//
+// If the trusted subnet is not configured or the caller's address is outside it,
+// it responds with HTTP 403 Forbidden, and if no URL policy is enabled, with HTTP 404 Not Found.
+// On success it returns the policy mode and entries in JSON format with HTTP 200 OK.
+func (svc *APIService) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	if !svc.authorizePolicy(w, r) {
+		return
+	}
+	writePolicy(w, http.StatusOK, svc.policy.Mode(), svc.policy.Entries())
+}
+
+// authorizePolicy checks that the caller may manage the URL policy and that one is enabled.
+// Otherwise the response is written and false is returned.
+func (svc *APIService) authorizePolicy(w http.ResponseWriter, r *http.Request) bool {
+	// Reject callers that are not in the trusted subnet.
+	if !utils.IsTrustedIP(utils.ClientIP(r), config.TrustedSubnet) {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	if svc.policy == nil {
+		http.Error(w, "URL policy is not enabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// writePolicy writes the mode and entries of the URL policy in JSON format with the given status code.
+func writePolicy(w http.ResponseWriter, status int, mode string, entries []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(models.PolicyResponse{Mode: mode, Entries: entries}); err != nil {
+		logger.Errorf("Error encoding URL policy to JSON: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
+	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# blocked\nevil.com\n"), 0644))
+	engine, err := policy.NewEngine(path, config.PolicyModeBlocklist, time.Hour)
+	require.NoError(t, err)
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := handler.NewAPIService(mockStore, workerPool, handler.WithPolicy(engine))
+
+	r := chi.NewRouter()
+	r.Get("/api/internal/policy", svc.GetPolicy)
+	r.Post("/api/internal/policy", svc.PostPolicyEntry)
+	r.Delete("/api/internal/policy", svc.DeletePolicyEntry)
+
+	defer func(subnet, proxy string) {
+		config.TrustedSubnet, config.TrustedProxy = subnet, proxy
+	}(config.TrustedSubnet, config.TrustedProxy)
+	config.TrustedSubnet = "192.168.1.0/24"
+	config.TrustedProxy = "172.16.0.0/12"
+
+	// The steps run in order against the same policy.
+	tests := []struct {
+		name           string
+		method         string
+		remoteAddr     string
+		realIP         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "IP Outside Subnet",
+			method:         http.MethodGet,
+			remoteAddr:     "10.0.0.1:1234",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Forged X-Real-IP",
+			method:         http.MethodPost,
+			remoteAddr:     "10.0.0.1:1234",
+			realIP:         "192.168.1.10",
+			body:           `{"entry":"forged.example"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "X-Real-IP From Untrusted Proxy",
+			method:         http.MethodGet,
+			remoteAddr:     "192.168.2.1:1234",
+			realIP:         "192.168.1.10",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "X-Real-IP From Trusted Proxy",
+			method:         http.MethodGet,
+			remoteAddr:     "172.16.0.1:1234",
+			realIP:         "192.168.1.10",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"mode":"blocklist","entries":["evil.com"]}` + "\n",
+		},
+		{
+			name:           "List Entries",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"mode":"blocklist","entries":["evil.com"]}` + "\n",
+		},
+		{
+			name:           "Add Wildcard",
+			method:         http.MethodPost,
+			body:           `{"entry":"*.Phish.example"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"mode":"blocklist","entries":["evil.com","*.phish.example"]}` + "\n",
+		},
+		{
+			name:           "Add Regex",
+			method:         http.MethodPost,
+			body:           `{"entry":"/\\.exe$/"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"mode":"blocklist","entries":["evil.com","*.phish.example","/\\.exe$/"]}` + "\n",
+		},
+		{
+			name:           "Add Existing Entry",
+			method:         http.MethodPost,
+			body:           `{"entry":"EVIL.com"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "policy entry already exists\n",
+		},
+		{
+			name:           "Add Invalid Regex",
+			method:         http.MethodPost,
+			body:           `{"entry":"/(/"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Add Invalid Domain",
+			method:         http.MethodPost,
+			body:           `{"entry":"evil.com/path"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Body",
+			method:         http.MethodPost,
+			body:           `evil.com`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid request body\n",
+		},
+		{
+			name:           "Remove Entry",
+			method:         http.MethodDelete,
+			body:           `{"entry":"evil.com"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"mode":"blocklist","entries":["*.phish.example","/\\.exe$/"]}` + "\n",
+		},
+		{
+			name:           "Remove Missing Entry",
+			method:         http.MethodDelete,
+			body:           `{"entry":"evil.com"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "policy entry doesn't exist\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/internal/policy", strings.NewReader(tt.body))
+			req.RemoteAddr = "192.168.1.10:1234"
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	// The changes are saved to the policy file.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "*.phish.example\n/\\.exe$/\n", string(data))
+}
+
+func TestPolicyAdminDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := handler.NewAPIService(mockStore, workerPool)
+
+	defer func(subnet string) { config.TrustedSubnet = subnet }(config.TrustedSubnet)
+	config.TrustedSubnet = "192.168.1.0/24"
+
+	req := httptest.NewRequest(http.MethodGet, "/api/internal/policy", nil)
+	req.RemoteAddr = "192.168.1.10:1234"
+	rr := httptest.NewRecorder()
+	svc.GetPolicy(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/qrcode"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// qrParams holds the rendering options of a QR code request.
+type qrParams struct {
+	format string         // format is "png" or "svg".
+	size   int            // size is the image width and height in pixels.
+	margin int            // margin is the quiet zone width in modules.
+	level  qrcode.ECLevel // level is the error correction level.
+}
+
+// GetQRCode handles the HTTP GET request for a QR code encoding the short URL of the given ID,
+// that is config.BaseURL + "/" + id.
+//
+// The image is rendered according to the optional query parameters:
+//   - format: png (default) or svg.
+//   - size: width and height of the image in pixels, up to config.MaxQRSize.
+//   - margin: width of the quiet zone in modules, up to config.MaxQRMargin.
+//   - ec: error correction level, one of L, M (default), Q or H.
+//
+// Invalid parameters result in HTTP 400 Bad Request. If the shortened URL does not exist it responds
+// with HTTP 404 Not Found, and if it has been deleted or has expired, with HTTP 410 Gone. Password
+// protected links get a QR code too; the password is asked for when the code is followed. Links to a URL
+// rejected by the URL policy get HTTP 451 Unavailable For Legal Reasons, unless config.PolicyAction is
+// config.PolicyActionWarn, in which case they get a QR code leading to the warning page.
+func (svc *APIService) GetQRCode(w http.ResponseWriter, r *http.Request) {
+	// Extract the 'id' URL parameter using the chi router.
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "This URL doesn't exist", http.StatusNotFound)
+		return
+	}
+
+	params, err := parseQRParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Only links that would redirect get a QR code; protected links redirect once unlocked.
+	if _, err := svc.store.GetOriginalLink(r.Context(), id); err != nil && !errors.Is(err, config.ErrPasswordRequired) {
+		switch {
+		case errors.Is(err, config.ErrGone) || errors.Is(err, config.ErrExpired):
+			http.Error(w, err.Error(), http.StatusGone)
+			return
+		case errors.Is(err, config.ErrURLBlocked):
+			// Links warned about by the URL policy still lead somewhere; blocked ones do not.
+			if config.PolicyAction != config.PolicyActionWarn {
+				http.Error(w, err.Error(), http.StatusUnavailableForLegalReasons)
+				return
+			}
+		default:
+			http.Error(w, "This URL doesn't exist", http.StatusNotFound)
+			return
+		}
+	}
+
+	code, err := qrcode.Encode([]byte(config.BaseURL+"/"+id), params.level)
+	if err != nil {
+		logger.Errorf("Error encoding QR code: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Render into a buffer so that an error can still be reported with a proper status.
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if params.format == "svg" {
+		contentType = "image/svg+xml"
+		err = code.WriteSVG(&buf, params.size, params.margin)
+	} else {
+		err = code.WritePNG(&buf, params.size, params.margin)
+	}
+	if err != nil {
+		logger.Errorf("Error rendering QR code: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// parseQRParams reads the rendering options of a QR code request, applying the defaults
+// for the ones that are missing.
+func parseQRParams(query url.Values) (qrParams, error) {
+	params := qrParams{
+		format: "png",
+		size:   config.DefaultQRSize,
+		margin: config.DefaultQRMargin,
+		level:  qrcode.Medium,
+	}
+
+	if format := query.Get("format"); format != "" {
+		if format != "png" && format != "svg" {
+			return params, fmt.Errorf("%w: format must be png or svg", config.ErrInvalidQRParams)
+		}
+		params.format = format
+	}
+	if size := query.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 || n > config.MaxQRSize {
+			return params, fmt.Errorf("%w: size must be between 1 and %d", config.ErrInvalidQRParams, config.MaxQRSize)
+		}
+		params.size = n
+	}
+	if margin := query.Get("margin"); margin != "" {
+		n, err := strconv.Atoi(margin)
+		if err != nil || n < 0 || n > config.MaxQRMargin {
+			return params, fmt.Errorf("%w: margin must be between 0 and %d", config.ErrInvalidQRParams, config.MaxQRMargin)
+		}
+		params.margin = n
+	}
+	if ec := query.Get("ec"); ec != "" {
+		level, err := qrcode.ParseECLevel(ec)
+		if err != nil {
+			return params, err
+		}
+		params.level = level
+	}
+	return params, nil
+}
@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetQRCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := handler.NewAPIService(mockStore, workerPool)
+
+	r := chi.NewRouter()
+	r.Get("/api/qr/{id}", svc.GetQRCode)
+
+	tests := []struct {
+		name         string
+		target       string
+		setupMocks   func()
+		expectedCode int
+		expectedType string
+	}{
+		{
+			name:   "PNG By Default",
+			target: "/api/qr/abc123",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "abc123").Return("http://example.com", nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedType: "image/png",
+		},
+		{
+			name:   "SVG With Options",
+			target: "/api/qr/abc123?format=svg&size=512&margin=2&ec=H",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "abc123").Return("http://example.com", nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedType: "image/svg+xml",
+		},
+		{
+			name:         "Invalid Format",
+			target:       "/api/qr/abc123?format=gif",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid Size",
+			target:       "/api/qr/abc123?size=0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid Margin",
+			target:       "/api/qr/abc123?margin=-1",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid Error Correction",
+			target:       "/api/qr/abc123?ec=X",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "URL Not Found",
+			target: "/api/qr/missing",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "missing").Return("", errors.New("not found"))
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "URL Gone",
+			target: "/api/qr/gone",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "gone").Return("", config.ErrGone)
+			},
+			expectedCode: http.StatusGone,
+		},
+		{
+			name:   "URL Expired",
+			target: "/api/qr/expired",
+			setupMocks: func() {
+				mockStore.EXPECT().GetOriginalLink(gomock.Any(), "expired").Return("", config.ErrExpired)
+			},
+			expectedCode: http.StatusGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setupMocks != nil {
+				tt.setupMocks()
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, rr.Header().Get("Content-Type"))
+			}
+			switch tt.expectedType {
+			case "image/png":
+				_, err := png.Decode(rr.Body)
+				assert.NoError(t, err)
+			case "image/svg+xml":
+				assert.True(t, strings.Contains(rr.Body.String(), `width="512"`))
+			}
+		})
+	}
+}
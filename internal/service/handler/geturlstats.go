@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetURLStats handles the HTTP GET request for the redirect statistics of a shortened URL.
+// The shortened URL ID is expected as a URL parameter and must belong to the authenticated user.
+//
+// If the user ID is missing from the context, it responds with HTTP 401 Unauthorized.
+// If the shortened URL does not exist, it responds with HTTP 404 Not Found, and if it belongs to
+// another user, with HTTP 403 Forbidden. Internal errors result in HTTP 500 Internal Server Error.
+// On success it returns the total number of redirects and the number of redirects per day
+// in JSON format with HTTP 200 OK.
+func (svc *APIService) GetURLStats(w http.ResponseWriter, r *http.Request) {
+	// Retrieve the user ID from the context, and return HTTP 401 Unauthorized if it's missing.
+	userID, ok := r.Context().Value(config.UserContextKey).(string)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Extract the 'id' URL parameter using the chi router.
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, config.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Fetch the statistics, which also verifies the ownership of the link.
+	stats, err := svc.store.GetURLStats(r.Context(), userID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, config.ErrNotFound):
+			http.Error(w, config.ErrNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, config.ErrForbidden):
+			http.Error(w, config.ErrForbidden.Error(), http.StatusForbidden)
+		default:
+			logger.Errorf("Error retrieving URL stats: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Return the statistics in JSON format with HTTP 200 OK.
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logger.Errorf("Error encoding URL stats to JSON: %v", err)
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,112 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetURLStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := handler.NewAPIService(mockStore, workerPool)
+
+	r := chi.NewRouter()
+	r.Get("/api/user/urls/{id}/stats", svc.GetURLStats)
+
+	tests := []struct {
+		name           string
+		id             string
+		userID         string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "Successful Stats",
+			id:     "abc",
+			userID: "valid-user-id",
+			setupMocks: func() {
+				mockStore.EXPECT().GetURLStats(gomock.Any(), "valid-user-id", "abc").Return(models.URLStats{
+					ShortURL:    "abc",
+					TotalClicks: 3,
+					Daily: []models.DailyClicks{
+						{Date: "2024-05-01", Clicks: 1},
+						{Date: "2024-05-02", Clicks: 2},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_url":"abc","total_clicks":3,"daily":[{"date":"2024-05-01","clicks":1},{"date":"2024-05-02","clicks":2}]}`,
+		},
+		{
+			name:           "Unauthorized Access",
+			id:             "abc",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "Unknown Link",
+			id:     "missing",
+			userID: "valid-user-id",
+			setupMocks: func() {
+				mockStore.EXPECT().GetURLStats(gomock.Any(), "valid-user-id", "missing").Return(models.URLStats{}, config.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "Link Of Another User",
+			id:     "foreign",
+			userID: "valid-user-id",
+			setupMocks: func() {
+				mockStore.EXPECT().GetURLStats(gomock.Any(), "valid-user-id", "foreign").Return(models.URLStats{}, config.ErrForbidden)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "Storage Error",
+			id:     "abc",
+			userID: "valid-user-id",
+			setupMocks: func() {
+				mockStore.EXPECT().GetURLStats(gomock.Any(), "valid-user-id", "abc").Return(models.URLStats{}, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/user/urls/"+tc.id+"/stats", nil)
+			if tc.userID != "" {
+				ctx := context.WithValue(req.Context(), config.UserContextKey, tc.userID)
+				req = req.WithContext(ctx)
+			}
+			rr := httptest.NewRecorder()
+
+			if tc.setupMocks != nil {
+				tc.setupMocks()
+			}
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+				assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 
@@ -29,7 +28,7 @@ func (svc *APIService) GetUserURLs(w http.ResponseWriter, r *http.Request) {
 	// logger.Infof("Retrieved userID is: %s", userID)
 
 	// Fetch all URLs associated with the user ID from the storage.
-	urls, err := svc.store.GetAllURLS(context.Background(), userID, config.BaseURL)
+	urls, err := svc.store.GetAllURLS(r.Context(), userID, config.BaseURL)
 	if err != nil {
 		logger.Errorf("Error retrieving URLs from store: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"fmt"
 	"net/http"
 )
@@ -17,7 +16,7 @@ import (
 // along with the error message.
 func (svc *APIService) Ping(w http.ResponseWriter, r *http.Request) {
 	// Attempt to ping the database.
-	status, err := svc.store.Ping(context.Background())
+	status, err := svc.store.Ping(r.Context())
 	if err != nil {
 		// If there is an error, write the status code associated with the error and print the error message.
 		w.WriteHeader(status)
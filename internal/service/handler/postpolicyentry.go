@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// PostPolicyEntry handles the HTTP POST request adding an entry to the URL policy. The entry is
+// expected in the entry field of a JSON body and is either a domain such as "example.com", a
+// wildcard suffix such as "*.example.com" matching its subdomains, or a regular expression between
+// slashes such as "/\.exe$/" matching anywhere in the URL. The entry is saved to the policy file.
+// Access is granted only if the client address, as reported by utils.ClientIP, belongs to
+// config.TrustedSubnet: forwarding headers count only when sent by a trusted proxy.
+//
+// The function responds with:
+// - HTTP 403 Forbidden if the trusted subnet is not configured or the caller's address is outside it.
+// - HTTP 404 Not Found if no URL policy is enabled.
+// - HTTP 400 Bad Request if the body is not valid JSON or the entry is malformed.
+// - HTTP 409 Conflict if the entry is already present.
+// - HTTP 500 Internal Server Error if the policy file cannot be saved.
+// - HTTP 201 Created with the policy mode and entries in JSON format on success.
+func (svc *APIService) PostPolicyEntry(w http.ResponseWriter, r *http.Request) {
+	if !svc.authorizePolicy(w, r) {
+		return
+	}
+
+	var payload models.PolicyEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := svc.policy.Add(payload.Entry); err != nil {
+		switch {
+		case errors.Is(err, config.ErrInvalidPolicyEntry):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, config.ErrPolicyEntryExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			logger.Errorf("Error adding URL policy entry: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+	writePolicy(w, http.StatusCreated, svc.policy.Mode(), svc.policy.Entries())
+}
@@ -2,11 +2,15 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
 	"github.com/gleb-korostelev/short-url.git/internal/worker"
 	"github.com/gleb-korostelev/short-url.git/tools/logger"
 )
@@ -17,7 +21,10 @@ import (
 //
 // The function ensures that the request uses the POST method. If not, it responds with HTTP 400 Bad Request.
 // It requires user authentication, responding with HTTP 401 Unauthorized if the user ID is not found in the context.
-// If the request body cannot be read, it responds with HTTP 400 Bad Request.
+// If the request body cannot be read, it responds with HTTP 400 Bad Request. If the body is not a URL that
+// can be shortened, it responds with HTTP 400 Bad Request and a JSON body giving the reason; otherwise
+// the URL is saved in its normalized form, see utils.NormalizeURL. A URL rejected by the URL policy
+// is refused the same way, with the code "url_blocked".
 // The response includes the shortened URL on success or appropriate error messages.
 func (svc *APIService) PostShorter(w http.ResponseWriter, r *http.Request) {
 	// Validate the request method.
@@ -44,15 +51,24 @@ func (svc *APIService) PostShorter(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	originalURL := string(body)
+	// Validate and normalize the original URL.
+	originalURL, err := utils.NormalizeURL(string(body))
+	if err != nil {
+		writeURLError(w, err)
+		return
+	}
 
 	// Create a channel to wait for the asynchronous task to complete.
 	doneChan := make(chan struct{})
 
-	// Submit the task to the worker pool.
-	svc.worker.AddTask(worker.Task{
+	// Submit the task to the worker pool, giving up if the client goes away while waiting for a worker.
+	err = svc.worker.AddTask(worker.Task{
 		Action: func(ctx context.Context) error {
-			shortURL, status, err := svc.store.SaveUniqueURL(ctx, originalURL, userID)
+			shortURL, status, err := svc.store.SaveUniqueURL(ctx, originalURL, userID, models.ShortenOptions{})
+			if errors.Is(err, config.ErrURLBlocked) {
+				writeURLError(w, err)
+				return nil
+			}
 			w.WriteHeader(status)
 			if err != nil {
 				logger.Errorf("Error with saving data: %v", err)
@@ -63,8 +79,31 @@ func (svc *APIService) PostShorter(w http.ResponseWriter, r *http.Request) {
 			return nil
 		},
 		Done: doneChan,
+		Ctx:  r.Context(),
 	})
+	if err != nil {
+		logger.Errorf("Request cancelled before saving: %v", err)
+		return
+	}
 
 	// Wait for the task to complete.
 	<-doneChan
 }
+
+// writeURLError responds with HTTP 400 Bad Request and a JSON body describing why a URL cannot be shortened,
+// either because it is invalid or because the URL policy rejects it.
+func writeURLError(w http.ResponseWriter, err error) {
+	response := models.URLErrorResponse{Error: err.Error(), Code: config.URLErrorMalformed}
+	var urlErr *utils.URLError
+	if errors.As(err, &urlErr) {
+		response.Code = urlErr.Code
+	}
+	if errors.Is(err, config.ErrURLBlocked) {
+		response.Code = config.URLErrorBlocked
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Errorf("Error encoding response: %v", err)
+	}
+}
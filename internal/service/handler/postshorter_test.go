@@ -8,8 +8,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
 	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
 	"github.com/gleb-korostelev/short-url.git/internal/worker"
 	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
@@ -60,7 +62,7 @@ func TestPostShorter(t *testing.T) {
 			body:   bytes.NewReader([]byte("http://example.com")),
 			userID: "valid-user-id",
 			setupMocks: func() {
-				mockStore.EXPECT().SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id").Return("http://short.url", http.StatusCreated, nil)
+				mockStore.EXPECT().SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{}).Return("http://short.url", http.StatusCreated, nil)
 			},
 			expectedStatus: http.StatusCreated,
 			expectedBody:   "http://short.url",
@@ -91,6 +93,40 @@ func TestPostShorter(t *testing.T) {
 	}
 }
 
+func TestPostShorterCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The only worker is kept busy, so the request waits for it until it is cancelled.
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(1)
+	defer workerPool.Shutdown()
+	release := make(chan struct{})
+	workerPool.AddTask(worker.Task{Action: func(ctx context.Context) error {
+		<-release
+		return nil
+	}})
+	defer close(release)
+	svc := handler.NewAPIService(mockStore, workerPool)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), config.UserContextKey, "valid-user-id"))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader([]byte("http://example.com")))
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		svc.PostShorter(rr, req)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("PostShorter did not return after the request was cancelled")
+	}
+}
+
 // errReader helps simulate an error while reading the request body.
 type errReader struct {
 	err error
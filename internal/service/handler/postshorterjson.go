@@ -1,21 +1,33 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
 )
 
 // PostShorterJSON handles HTTP POST requests to create shortened URLs using JSON data.
 // This method requires that the request method be POST and the content type be JSON.
 // It ensures user authentication, reads the URL from the JSON payload, and saves the shortened URL.
 //
+// An optional custom_alias field requests a specific short code instead of a generated one, and
+// either expires_at (RFC 3339) or ttl_seconds limits how long the link keeps redirecting.
+// Setting qr adds the URL of the link's QR code image to the response, and setting password
+// protects the link so that it only redirects once the password is supplied.
+//
 // The function responds with:
-// - HTTP 400 Bad Request if the request method is not POST or if there's an error parsing the request body.
+// - HTTP 400 Bad Request if the request method is not POST, if there's an error parsing the request body,
+// if the custom alias is malformed or reserved, or if the expiration or password is invalid.
+// A URL that cannot be shortened is reported with a JSON body giving the reason; otherwise the URL is
+// saved in its normalized form, see utils.NormalizeURL. A URL rejected by the URL policy is reported the
+// same way, with the code "url_blocked".
 // - HTTP 401 Unauthorized if the user is not authenticated.
+// - HTTP 409 Conflict if the custom alias is already taken.
 // - HTTP 201 or other appropriate HTTP status based on the result of the URL saving operation.
 //
 // If the operation is successful, it returns the shortened URL in a JSON structure.
@@ -43,9 +55,31 @@ func (svc *APIService) PostShorterJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate and normalize the original URL.
+	originalURL, err := utils.NormalizeURL(payload.URL)
+	if err != nil {
+		writeURLError(w, err)
+		return
+	}
+
+	// Validate the requested custom alias, expiration and password, if any.
+	opts, err := utils.BuildShortenOptions(payload.CustomAlias, payload.ExpiresAt, payload.TTLSeconds, payload.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Attempt to save the URL and obtain a shortened version.
-	shortURL, status, err := svc.store.SaveUniqueURL(context.Background(), payload.URL, userID)
+	shortURL, status, err := svc.store.SaveUniqueURL(r.Context(), originalURL, userID, opts)
 	if err != nil {
+		if errors.Is(err, config.ErrAliasTaken) {
+			http.Error(w, config.ErrAliasTaken.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, config.ErrURLBlocked) {
+			writeURLError(w, err)
+			return
+		}
 		http.Error(w, "Error with saving", status)
 		return
 	}
@@ -53,6 +87,9 @@ func (svc *APIService) PostShorterJSON(w http.ResponseWriter, r *http.Request) {
 
 	// Encode the shortened URL in a JSON response.
 	response := models.ShortURLResponse{Result: shortURL}
+	if payload.QR {
+		response.QR = config.BaseURL + "/api/qr/" + strings.TrimPrefix(shortURL, config.BaseURL+"/")
+	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 	}
@@ -3,17 +3,22 @@ package handler_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
 	"github.com/gleb-korostelev/short-url.git/internal/service/handler"
 	"github.com/gleb-korostelev/short-url.git/internal/worker"
 	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPostShorterJSON(t *testing.T) {
@@ -24,6 +29,8 @@ func TestPostShorterJSON(t *testing.T) {
 	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
 	svc := handler.NewAPIService(mockStore, workerPool)
 
+	expiresAt := time.Date(2999, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name           string
 		method         string
@@ -60,7 +67,7 @@ func TestPostShorterJSON(t *testing.T) {
 			requestBody: `{"url":"http://example.com"}`,
 			setupMocks: func() {
 				mockStore.EXPECT().
-					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id").
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{}).
 					Return("", http.StatusInternalServerError, errors.New("db error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -73,12 +80,113 @@ func TestPostShorterJSON(t *testing.T) {
 			requestBody: `{"url":"http://example.com"}`,
 			setupMocks: func() {
 				mockStore.EXPECT().
-					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id").
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{}).
 					Return("http://short.url", http.StatusCreated, nil)
 			},
 			expectedStatus: http.StatusCreated,
 			expectedBody:   `{"result":"http://short.url"}`,
 		},
+		{
+			name:        "Shorten With QR",
+			method:      "POST",
+			userID:      "valid-user-id",
+			requestBody: `{"url":"http://example.com","qr":true}`,
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{}).
+					Return(config.BaseURL+"/abc123", http.StatusCreated, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"result":"` + config.BaseURL + `/abc123","qr":"` + config.BaseURL + `/api/qr/abc123"}`,
+		},
+		{
+			name:           "Password Too Long",
+			method:         "POST",
+			userID:         "valid-user-id",
+			requestBody:    `{"url":"http://example.com","password":"` + strings.Repeat("x", config.PasswordMaxLength+1) + `"}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "password is invalid: length must be between 1 and 72 bytes\n",
+		},
+		{
+			name:           "Reserved Alias",
+			method:         "POST",
+			userID:         "valid-user-id",
+			requestBody:    `{"url":"http://example.com","custom_alias":"api"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "custom alias is invalid: \"api\" is reserved\n",
+		},
+		{
+			name:           "Malformed Alias",
+			method:         "POST",
+			userID:         "valid-user-id",
+			requestBody:    `{"url":"http://example.com","custom_alias":"spring sale"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "custom alias is invalid: character ' ' is not allowed\n",
+		},
+		{
+			name:        "Successful Expiry",
+			method:      "POST",
+			userID:      "valid-user-id",
+			requestBody: `{"url":"http://example.com","expires_at":"2999-01-01T00:00:00Z"}`,
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{ExpiresAt: &expiresAt}).
+					Return("http://short.url", http.StatusCreated, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"result":"http://short.url"}`,
+		},
+		{
+			name:           "Expiry In The Past",
+			method:         "POST",
+			userID:         "valid-user-id",
+			requestBody:    `{"url":"http://example.com","expires_at":"2000-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "expiration is invalid: expires_at must be in the future\n",
+		},
+		{
+			name:           "Expiry And TTL Together",
+			method:         "POST",
+			userID:         "valid-user-id",
+			requestBody:    `{"url":"http://example.com","expires_at":"2999-01-01T00:00:00Z","ttl_seconds":60}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "expiration is invalid: expires_at and ttl_seconds are mutually exclusive\n",
+		},
+		{
+			name:           "Negative TTL",
+			method:         "POST",
+			userID:         "valid-user-id",
+			requestBody:    `{"url":"http://example.com","ttl_seconds":-5}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "expiration is invalid: ttl_seconds must be positive\n",
+		},
+		{
+			name:        "Alias Taken",
+			method:      "POST",
+			userID:      "valid-user-id",
+			requestBody: `{"url":"http://example.com","custom_alias":"spring-sale"}`,
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{CustomAlias: "spring-sale"}).
+					Return("", http.StatusConflict, config.ErrAliasTaken)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "custom alias is already taken\n",
+		},
+		{
+			name:        "Successful Alias",
+			method:      "POST",
+			userID:      "valid-user-id",
+			requestBody: `{"url":"http://example.com","custom_alias":"spring-sale"}`,
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), "http://example.com", "valid-user-id", models.ShortenOptions{CustomAlias: "spring-sale"}).
+					Return("http://localhost:8080/spring-sale", http.StatusCreated, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"result":"http://localhost:8080/spring-sale"}`,
+		},
 	}
 
 	for _, tc := range tests {
@@ -99,7 +207,8 @@ func TestPostShorterJSON(t *testing.T) {
 			assert.Equal(t, tc.expectedStatus, rr.Code)
 			if tc.expectedBody != "" {
 				responseBody := rr.Body.String()
-				if tc.expectedStatus == http.StatusBadRequest || tc.expectedStatus == http.StatusInternalServerError {
+				if tc.expectedStatus == http.StatusBadRequest || tc.expectedStatus == http.StatusInternalServerError ||
+					tc.expectedStatus == http.StatusConflict {
 					assert.Equal(t, tc.expectedBody, responseBody)
 				} else {
 					assert.JSONEq(t, tc.expectedBody, responseBody)
@@ -108,3 +217,118 @@ func TestPostShorterJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestPostShorterJSONNormalizesURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	workerPool := worker.NewDBWorkerPool(config.MaxConcurrentUpdates)
+	svc := handler.NewAPIService(mockStore, workerPool)
+
+	tests := []struct {
+		name           string
+		url            string
+		stripFragments bool
+		expectedURL    string
+		expectedCode   string
+	}{
+		{
+			name:        "Lowercase Scheme And Host",
+			url:         "HTTP://Example.COM/Path?Q=1",
+			expectedURL: "http://example.com/Path?Q=1",
+		},
+		{
+			name:        "Default Port Stripped",
+			url:         "https://example.com:443/a",
+			expectedURL: "https://example.com/a",
+		},
+		{
+			name:        "Other Port Kept",
+			url:         "http://example.com:8080/a",
+			expectedURL: "http://example.com:8080/a",
+		},
+		{
+			name:        "International Domain",
+			url:         "https://Bücher.example/café",
+			expectedURL: "https://xn--bcher-kva.example/caf%C3%A9",
+		},
+		{
+			name:        "IPv6 Host",
+			url:         "http://[::1]:80/",
+			expectedURL: "http://[::1]/",
+		},
+		{
+			name:        "Fragment Kept",
+			url:         "https://example.com/a#top",
+			expectedURL: "https://example.com/a#top",
+		},
+		{
+			name:           "Fragment Stripped",
+			url:            "https://example.com/a#top",
+			stripFragments: true,
+			expectedURL:    "https://example.com/a",
+		},
+		{
+			name:         "Empty URL",
+			url:          "  ",
+			expectedCode: config.URLErrorEmpty,
+		},
+		{
+			name:         "JavaScript URI",
+			url:          "javascript:alert(1)",
+			expectedCode: config.URLErrorScheme,
+		},
+		{
+			name:         "Relative URL",
+			url:          "example.com/a",
+			expectedCode: config.URLErrorMalformed,
+		},
+		{
+			name:         "Missing Host",
+			url:          "http:///a",
+			expectedCode: config.URLErrorHost,
+		},
+		{
+			name:         "Invalid Port",
+			url:          "http://example.com:99999/",
+			expectedCode: config.URLErrorPort,
+		},
+		{
+			name:         "Too Long",
+			url:          "http://example.com/" + strings.Repeat("a", config.MaxURLLength),
+			expectedCode: config.URLErrorTooLong,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config.StripURLFragments = tc.stripFragments
+			defer func() { config.StripURLFragments = false }()
+
+			if tc.expectedURL != "" {
+				mockStore.EXPECT().
+					SaveUniqueURL(gomock.Any(), tc.expectedURL, "valid-user-id", models.ShortenOptions{}).
+					Return("http://short.url", http.StatusCreated, nil)
+			}
+
+			body, _ := json.Marshal(models.URLPayload{URL: tc.url})
+			req, _ := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewBuffer(body))
+			req = req.WithContext(context.WithValue(req.Context(), config.UserContextKey, "valid-user-id"))
+			rr := httptest.NewRecorder()
+
+			svc.PostShorterJSON(rr, req)
+
+			if tc.expectedURL != "" {
+				assert.Equal(t, http.StatusCreated, rr.Code)
+				return
+			}
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+			var response models.URLErrorResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+			assert.Equal(t, tc.expectedCode, response.Code)
+			assert.Contains(t, response.Error, config.ErrInvalidURL.Error())
+		})
+	}
+}
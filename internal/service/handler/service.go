@@ -3,8 +3,11 @@
 package handler
 
 import (
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
 	"github.com/gleb-korostelev/short-url.git/internal/service"
 	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/throttle"
 	"github.com/gleb-korostelev/short-url.git/internal/worker"
 )
 
@@ -12,8 +15,46 @@ import (
 // to interact with the URL storage and processing tasks. It abstracts the
 // details of data manipulation and task scheduling away from the HTTP interface.
 type APIService struct {
-	store  storage.Storage      // store is the interface to the URL storage backend.
-	worker *worker.DBWorkerPool // worker handles asynchronous tasks using a worker pool.
+	store     storage.Storage       // store is the interface to the URL storage backend.
+	worker    *worker.DBWorkerPool  // worker handles asynchronous tasks using a worker pool.
+	clicks    *worker.ClickRecorder // clicks records redirect events; nil disables click analytics.
+	passwords *throttle.Limiter     // passwords counts wrong passwords per protected short URL.
+	jobs      *worker.DeletionJobs  // jobs persists deletions as jobs; nil deletes in the background without a job.
+	policy    *policy.Engine        // policy is the URL policy managed through the admin API; nil disables the API.
+}
+
+// Option configures optional dependencies of an APIService.
+type Option func(*APIService)
+
+// WithClickRecorder enables click analytics: every successful redirect is handed to recorder.
+func WithClickRecorder(recorder *worker.ClickRecorder) Option {
+	return func(svc *APIService) {
+		svc.clicks = recorder
+	}
+}
+
+// WithDeletionJobs makes DELETE /api/user/urls persist every deletion as a job of jobs
+// and enables GET /api/user/jobs/{id}.
+func WithDeletionJobs(jobs *worker.DeletionJobs) Option {
+	return func(svc *APIService) {
+		svc.jobs = jobs
+	}
+}
+
+// WithPolicy enables the admin API managing the entries of engine under /api/internal/policy.
+// The policy is enforced by the storage, see screened.NewScreenedStorage.
+func WithPolicy(engine *policy.Engine) Option {
+	return func(svc *APIService) {
+		svc.policy = engine
+	}
+}
+
+// WithPasswordLimiter makes the wrong passwords of protected links count against limiter,
+// so that they can be shared with the gRPC API, instead of a limiter of the service's own.
+func WithPasswordLimiter(limiter *throttle.Limiter) Option {
+	return func(svc *APIService) {
+		svc.passwords = limiter
+	}
 }
 
 // NewAPIService creates a new instance of APIService with the provided storage
@@ -22,9 +63,15 @@ type APIService struct {
 //
 // store: Provides access to the URL storage and manipulation functions.
 // worker: Manages asynchronous execution of background tasks that shouldn't block the HTTP handlers.
-func NewAPIService(store storage.Storage, worker *worker.DBWorkerPool) service.APIServiceI {
-	return &APIService{
-		store:  store,
-		worker: worker,
+// opts: Optional dependencies such as WithClickRecorder, WithDeletionJobs, WithPolicy and WithPasswordLimiter.
+func NewAPIService(store storage.Storage, worker *worker.DBWorkerPool, opts ...Option) service.APIServiceI {
+	svc := &APIService{
+		store:     store,
+		worker:    worker,
+		passwords: throttle.NewLimiter(config.PasswordMaxAttempts, config.PasswordAttemptWindow),
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
+	return svc
 }
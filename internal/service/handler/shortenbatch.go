@@ -1,25 +1,38 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
 )
 
 // ShortenBatchHandler processes HTTP POST requests to shorten multiple URLs simultaneously.
-// It reads a list of URLs from the request body in JSON format and attempts to save each one,
-// returning a corresponding list of shortened URLs or error messages.
+// It reads a list of URLs from the request body in JSON format and saves them with a single storage
+// operation, returning the outcome of every item.
 //
 // The function checks for the POST method and expects the user to be authenticated.
 // If the request body does not contain valid JSON, or the batch is empty, it responds with
-// HTTP 400 Bad Request. Each URL saving operation is performed, and the results are accumulated
-// and returned as JSON with HTTP 201 Created on success.
+// HTTP 400 Bad Request.
 //
-// If an error occurs during the saving of any URL, it stops processing further and returns the results
-// obtained until the error occurred.
+// Items may carry an optional custom_alias, an optional expires_at or ttl_seconds and an optional password.
+// Every response item carries the correlation_id of its request item and a status:
+// - "created" with the new short_url if the URL was shortened.
+// - "conflict" with the existing short_url if the URL was already shortened, or repeats an earlier item.
+// - "error" with an error message if the URL, alias, expiration or password is invalid, the alias is taken,
+// or the URL is rejected by the URL policy.
+// URLs are saved in their normalized form, see utils.NormalizeURL.
+//
+// The response is HTTP 201 Created if no item failed and HTTP 207 Multi-Status if some did, in which case
+// the other items are saved nonetheless. With the atomic=true query parameter the batch is all-or-nothing:
+// if any item fails nothing is saved, the items that would have been saved fail as well and the response
+// is HTTP 422 Unprocessable Entity.
+//
+// If the batch cannot be saved at all, nothing is saved and the response is HTTP 500 Internal Server Error.
 func (svc *APIService) ShortenBatchHandler(w http.ResponseWriter, r *http.Request) {
 	// Ensure the user is authenticated.
 	userID, ok := r.Context().Value(config.UserContextKey).(string)
@@ -28,6 +41,15 @@ func (svc *APIService) ShortenBatchHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	atomic := false
+	if raw := r.URL.Query().Get("atomic"); raw != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(raw); err != nil {
+			http.Error(w, "Invalid atomic parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Decode the JSON body to get a list of URLs to shorten.
 	var reqItems []models.ShortenBatchRequestItem
 	if err := json.NewDecoder(r.Body).Decode(&reqItems); err != nil {
@@ -45,23 +67,85 @@ func (svc *APIService) ShortenBatchHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Process each URL in the batch and collect the results.
-	var respItems []models.ShortenBatchResponseItem
-	for _, item := range reqItems {
-		shortURL, err := svc.store.SaveURL(context.Background(), item.OriginalURL, userID)
+	// Validate the URLs, custom aliases, expirations and passwords, failing invalid items on their own.
+	respItems := make([]models.ShortenBatchResponseItem, len(reqItems))
+	batch := make([]models.BatchURL, 0, len(reqItems))
+	batchIndex := make([]int, 0, len(reqItems)) // batchIndex maps the items of batch to their request items.
+	failed := false
+	for i, item := range reqItems {
+		respItems[i].CorrelationID = item.CorrelationID
+		batchItem, err := buildBatchItem(item)
+		if err != nil {
+			respItems[i].Status = config.BatchItemError
+			respItems[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		batch = append(batch, batchItem)
+		batchIndex = append(batchIndex, i)
+	}
+
+	if atomic && failed {
+		for _, i := range batchIndex {
+			respItems[i].Status = config.BatchItemError
+			respItems[i].Error = config.ErrBatchAborted.Error()
+		}
+		writeBatchResponse(w, http.StatusUnprocessableEntity, respItems)
+		return
+	}
+
+	// Save the valid items at once and collect their results.
+	if len(batch) > 0 {
+		results, err := svc.store.SaveURLsBatch(r.Context(), userID, batch, atomic)
 		if err != nil {
-			// Return the results obtained until the error occurred.
-			json.NewEncoder(w).Encode(respItems)
-			http.Error(w, "Error with saving", http.StatusBadRequest)
-			break
+			logger.Errorf("Error saving a batch of %d URLs: %v", len(batch), err)
+			http.Error(w, "Error with saving", http.StatusInternalServerError)
+			return
+		}
+		for j, result := range results {
+			i := batchIndex[j]
+			switch {
+			case result.Err != nil:
+				respItems[i].Status = config.BatchItemError
+				respItems[i].Error = result.Err.Error()
+				failed = true
+			case result.Existed:
+				respItems[i].Status = config.BatchItemConflict
+				respItems[i].ShortURL = result.ShortURL
+			default:
+				respItems[i].Status = config.BatchItemCreated
+				respItems[i].ShortURL = result.ShortURL
+			}
 		}
-		respItems = append(respItems, models.ShortenBatchResponseItem{
-			CorrelationID: item.CorrelationID,
-			ShortURL:      shortURL,
-		})
 	}
 
-	// Successfully encode and return the full list of shortened URLs.
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(respItems)
+	switch {
+	case !failed:
+		writeBatchResponse(w, http.StatusCreated, respItems)
+	case atomic:
+		writeBatchResponse(w, http.StatusUnprocessableEntity, respItems)
+	default:
+		writeBatchResponse(w, http.StatusMultiStatus, respItems)
+	}
+}
+
+// buildBatchItem validates and normalizes the URL of a batch item and validates its per-link settings.
+func buildBatchItem(item models.ShortenBatchRequestItem) (models.BatchURL, error) {
+	originalURL, err := utils.NormalizeURL(item.OriginalURL)
+	if err != nil {
+		return models.BatchURL{}, err
+	}
+	opts, err := utils.BuildShortenOptions(item.CustomAlias, item.ExpiresAt, item.TTLSeconds, item.Password)
+	if err != nil {
+		return models.BatchURL{}, err
+	}
+	return models.BatchURL{OriginalURL: originalURL, Opts: opts}, nil
+}
+
+// writeBatchResponse writes the items of a batch response with the given status code.
+func writeBatchResponse(w http.ResponseWriter, status int, items []models.ShortenBatchResponseItem) {
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		logger.Errorf("Error encoding batch response: %v", err)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -29,6 +30,7 @@ func TestShortenBatchHandler(t *testing.T) {
 	tests := []struct {
 		name            string
 		userID          string
+		query           string
 		requestBody     []models.ShortenBatchRequestItem
 		setupMocks      func()
 		expectedStatus  int
@@ -42,12 +44,161 @@ func TestShortenBatchHandler(t *testing.T) {
 				{CorrelationID: "1", OriginalURL: "http://example.com"},
 			},
 			setupMocks: func() {
-				mockStore.EXPECT().SaveURL(gomock.Any(), "http://example.com", "valid-user-id").Return("http://short.url", nil)
+				mockStore.EXPECT().
+					SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{{OriginalURL: "http://example.com"}}, false).
+					Return([]models.BatchURLResult{{ShortURL: "http://short.url"}}, nil)
 			},
 			expectedStatus:  http.StatusCreated,
-			expectedBody:    `[{"correlation_id":"1","short_url":"http://short.url"}]`,
+			expectedBody:    `[{"correlation_id":"1","short_url":"http://short.url","status":"created"}]`,
 			expectedHeaders: map[string]string{"Content-Type": "application/json"},
 		},
+		{
+			name:   "Batch with an already shortened URL",
+			userID: "valid-user-id",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com"},
+				{CorrelationID: "2", OriginalURL: "http://example.org"},
+			},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{
+						{OriginalURL: "http://example.com"},
+						{OriginalURL: "http://example.org"},
+					}, false).
+					Return([]models.BatchURLResult{
+						{ShortURL: "http://short.url/existing", Existed: true},
+						{ShortURL: "http://short.url/new"},
+					}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `[{"correlation_id":"1","short_url":"http://short.url/existing","status":"conflict"},
+				{"correlation_id":"2","short_url":"http://short.url/new","status":"created"}]`,
+		},
+		{
+			name:   "Batch with custom alias",
+			userID: "valid-user-id",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com", CustomAlias: "spring-sale"},
+			},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{
+						{OriginalURL: "http://example.com", Opts: models.ShortenOptions{CustomAlias: "spring-sale"}},
+					}, false).
+					Return([]models.BatchURLResult{{ShortURL: "http://localhost:8080/spring-sale"}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `[{"correlation_id":"1","short_url":"http://localhost:8080/spring-sale","status":"created"}]`,
+		},
+		{
+			name:   "Reserved alias fails only its item",
+			userID: "valid-user-id",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com"},
+				{CorrelationID: "2", OriginalURL: "http://example.org", CustomAlias: "ping"},
+			},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{{OriginalURL: "http://example.com"}}, false).
+					Return([]models.BatchURLResult{{ShortURL: "http://short.url"}}, nil)
+			},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody: `[{"correlation_id":"1","short_url":"http://short.url","status":"created"},
+				{"correlation_id":"2","status":"error","error":"custom alias is invalid: \"ping\" is reserved"}]`,
+		},
+		{
+			name:   "Invalid expiry fails its item",
+			userID: "valid-user-id",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com", TTLSeconds: -1},
+			},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody:   `[{"correlation_id":"1","status":"error","error":"expiration is invalid: ttl_seconds must be positive"}]`,
+		},
+		{
+			name:   "Invalid URL fails its item",
+			userID: "valid-user-id",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "ftp://example.com/file"},
+				{CorrelationID: "2", OriginalURL: "not a url"},
+			},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody: `[{"correlation_id":"1","status":"error","error":"URL is invalid: scheme \"ftp\" is not allowed"},
+				{"correlation_id":"2","status":"error","error":"URL is invalid: \"not a url\" is not an absolute URL"}]`,
+		},
+		{
+			name:   "Alias already taken",
+			userID: "valid-user-id",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com", CustomAlias: "spring-sale"},
+			},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{
+						{OriginalURL: "http://example.com", Opts: models.ShortenOptions{CustomAlias: "spring-sale"}},
+					}, false).
+					Return([]models.BatchURLResult{{Err: config.ErrAliasTaken}}, nil)
+			},
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody:   `[{"correlation_id":"1","status":"error","error":"custom alias is already taken"}]`,
+		},
+		{
+			name:   "Atomic batch with an invalid item saves nothing",
+			userID: "valid-user-id",
+			query:  "?atomic=true",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com"},
+				{CorrelationID: "2", OriginalURL: "not a url"},
+			},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `[{"correlation_id":"1","status":"error","error":"batch was not saved because another item failed"},
+				{"correlation_id":"2","status":"error","error":"URL is invalid: \"not a url\" is not an absolute URL"}]`,
+		},
+		{
+			name:   "Atomic batch with a taken alias saves nothing",
+			userID: "valid-user-id",
+			query:  "?atomic=true",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com"},
+				{CorrelationID: "2", OriginalURL: "http://example.org", CustomAlias: "spring-sale"},
+			},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveURLsBatch(gomock.Any(), "valid-user-id", []models.BatchURL{
+						{OriginalURL: "http://example.com"},
+						{OriginalURL: "http://example.org", Opts: models.ShortenOptions{CustomAlias: "spring-sale"}},
+					}, true).
+					Return([]models.BatchURLResult{{Err: config.ErrBatchAborted}, {Err: config.ErrAliasTaken}}, nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `[{"correlation_id":"1","status":"error","error":"batch was not saved because another item failed"},
+				{"correlation_id":"2","status":"error","error":"custom alias is already taken"}]`,
+		},
+		{
+			name:           "Invalid atomic parameter",
+			userID:         "valid-user-id",
+			query:          "?atomic=maybe",
+			requestBody:    []models.ShortenBatchRequestItem{{CorrelationID: "1", OriginalURL: "http://example.com"}},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid atomic parameter\n",
+		},
+		{
+			name:   "Storage error",
+			userID: "valid-user-id",
+			requestBody: []models.ShortenBatchRequestItem{
+				{CorrelationID: "1", OriginalURL: "http://example.com"},
+			},
+			setupMocks: func() {
+				mockStore.EXPECT().
+					SaveURLsBatch(gomock.Any(), "valid-user-id", gomock.Any(), false).
+					Return(nil, errors.New("database is down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
 		{
 			name:           "Unauthorized without user context",
 			userID:         "",
@@ -76,7 +227,7 @@ func TestShortenBatchHandler(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			body, _ := json.Marshal(tc.requestBody)
-			req, _ := http.NewRequest("POST", "/api/shorten/batch", bytes.NewBuffer(body))
+			req, _ := http.NewRequest("POST", "/api/shorten/batch"+tc.query, bytes.NewBuffer(body))
 			if tc.userID != "" {
 				ctx := context.WithValue(req.Context(), config.UserContextKey, tc.userID)
 				req = req.WithContext(ctx)
@@ -3,12 +3,21 @@
 package router
 
 import (
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
 	"github.com/gleb-korostelev/short-url.git/internal/middleware"
 	"github.com/gleb-korostelev/short-url.git/internal/service"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+// RateLimits holds the per-user and per-IP request limits of the route groups.
+// A zero limit leaves its routes unlimited.
+type RateLimits struct {
+	Create   middleware.RateLimit // Create limits the routes creating short URLs.
+	Redirect middleware.RateLimit // Redirect limits the routes redirecting to original URLs.
+	Delete   middleware.RateLimit // Delete limits the route deleting short URLs.
+}
+
 // RouterInit initializes the web server's routes and configures middleware. It takes a service
 // interface and a logger as parameters, setting up routes that handle URL shortening operations
 // and other related tasks.
@@ -17,6 +26,8 @@ import (
 //
 //	svc:    A service interface that provides methods for handling various HTTP requests related to URL management.
 //	logger: A logger from the zap library used for logging within middleware.
+//	limits: The rate limits of the create, redirect and delete routes.
+//	reg:    The metrics registry receiving the HTTP metrics and served on /metrics.
 //
 // Returns:
 //
@@ -24,35 +35,71 @@ import (
 //
 // The function sets up the following routes:
 //   - GET /ping: Checks database connectivity.
+//   - GET /metrics: Serves the metrics of the service in the Prometheus text format to callers from the trusted subnet.
 //   - GET /{id}: Retrieves the original URL corresponding to a shortened ID.
 //   - GET /api/user/urls: Retrieves all URLs associated with the authenticated user.
+//   - GET /api/user/urls/{id}/stats: Retrieves redirect statistics of a URL owned by the authenticated user.
+//   - GET /api/user/jobs/{id}: Retrieves the status of a deletion job of the authenticated user.
+//   - GET /api/internal/stats: Retrieves service-wide counters for callers from the trusted subnet.
+//   - GET /api/internal/policy: Lists the URL policy entries for callers from the trusted subnet.
+//   - GET /api/qr/{id}: Renders a QR code encoding the short URL of an ID.
 //   - POST /: Creates a shortened URL from a plain text body.
+//   - POST /{id}: Redirects to the original URL of a password protected ID once the posted password matches.
 //   - POST /api/shorten: Creates a shortened URL from JSON input.
-//   - POST /api/shorten/batch: Handles batch creation of shortened URLs.
-//   - DELETE /api/user/urls: Deletes one or more URLs associated with the user.
+//   - POST /api/shorten/batch: Handles batch creation of shortened URLs with per-item results; atomic=true makes it all-or-nothing.
+//   - POST /api/internal/policy: Adds a URL policy entry for callers from the trusted subnet.
+//   - DELETE /api/internal/policy: Removes a URL policy entry for callers from the trusted subnet.
+//   - DELETE /api/user/urls: Deletes one or more URLs associated with the user, returning the ID of the deletion job.
 //
 // Middleware used:
+//   - TracingMiddleware: Traces every request, continuing the trace of a W3C traceparent header.
+//   - MetricsMiddleware: Counts the requests and records their latency per route and status.
 //   - GzipCompressMiddleware: Compresses response data if the client supports gzip.
 //   - GzipDecompressMiddleware: Decompresses request data if compressed with gzip.
 //   - LoggingMiddleware: Logs the details of the HTTP request and response.
-//   - EnsureUserCookie: Ensures that a user session is valid or creates a new session.
-func RouterInit(svc service.APIServiceI, logger *zap.Logger) *chi.Mux {
+//   - EnsureUserCookie: Ensures that a user session is valid or creates a new session, on every route but /metrics.
+//   - TrustedSubnetMiddleware: Refuses /metrics to callers outside the trusted subnet.
+//   - RateLimitMiddleware: Limits the requests per user and per IP, separately for the
+//     create (POST /, POST /api/shorten, POST /api/shorten/batch), redirect (GET and POST /{id})
+//     and delete (DELETE /api/user/urls) routes.
+func RouterInit(svc service.APIServiceI, logger *zap.Logger, limits RateLimits, reg *metrics.Registry) *chi.Mux {
 	router := chi.NewRouter()
 
 	// Register middleware that will be used across all routes.
+	router.Use(middleware.TracingMiddleware)
+	router.Use(middleware.MetricsMiddleware(reg))
 	router.Use(middleware.GzipCompressMiddleware)
 	router.Use(middleware.GzipDecompressMiddleware)
 	router.Use(middleware.LoggingMiddleware(logger))
-	router.Use(middleware.EnsureUserCookie)
-
-	// Define routes and associate them with specific handler functions.
-	router.Get("/ping", svc.Ping)
-	router.Get("/{id}", svc.GetOriginal)
-	router.Get("/api/user/urls", svc.GetUserURLs)
-	router.Post("/", svc.PostShorter)
-	router.Post("/api/shorten", svc.PostShorterJSON)
-	router.Post("/api/shorten/batch", svc.ShortenBatchHandler)
-	router.Delete("/api/user/urls", svc.DeleteURLsHandler)
+
+	// The metrics are served to the trusted subnet only, without a user session.
+	router.With(middleware.TrustedSubnetMiddleware).Get("/metrics", reg.Handler().ServeHTTP)
+
+	router.Group(func(router chi.Router) {
+		router.Use(middleware.EnsureUserCookie)
+
+		// Each group of rate limited routes shares one set of buckets.
+		create := router.With(middleware.RateLimitMiddleware(limits.Create))
+		redirect := router.With(middleware.RateLimitMiddleware(limits.Redirect))
+		remove := router.With(middleware.RateLimitMiddleware(limits.Delete))
+
+		// Define routes and associate them with specific handler functions.
+		router.Get("/ping", svc.Ping)
+		redirect.Get("/{id}", svc.GetOriginal)
+		router.Get("/api/user/urls", svc.GetUserURLs)
+		router.Get("/api/user/urls/{id}/stats", svc.GetURLStats)
+		router.Get("/api/user/jobs/{id}", svc.GetJob)
+		router.Get("/api/internal/stats", svc.GetInternalStats)
+		router.Get("/api/internal/policy", svc.GetPolicy)
+		router.Get("/api/qr/{id}", svc.GetQRCode)
+		create.Post("/", svc.PostShorter)
+		redirect.Post("/{id}", svc.GetOriginal)
+		create.Post("/api/shorten", svc.PostShorterJSON)
+		create.Post("/api/shorten/batch", svc.ShortenBatchHandler)
+		router.Post("/api/internal/policy", svc.PostPolicyEntry)
+		router.Delete("/api/internal/policy", svc.DeletePolicyEntry)
+		remove.Delete("/api/user/urls", svc.DeleteURLsHandler)
+	})
 
 	return router
 }
@@ -36,4 +36,32 @@ type APIServiceI interface {
 	// DeleteURLsHandler handles the deletion of one or more URLs specified in the request body.
 	// It writes the result status to the HTTP response.
 	DeleteURLsHandler(w http.ResponseWriter, r *http.Request)
+
+	// GetURLStats retrieves the redirect statistics of a shortened URL owned by the authenticated user.
+	// It writes the statistics or an error message in JSON format to the HTTP response.
+	GetURLStats(w http.ResponseWriter, r *http.Request)
+
+	// GetInternalStats retrieves the total number of shortened URLs and users for callers from the trusted subnet.
+	// It writes the counters or an error message in JSON format to the HTTP response.
+	GetInternalStats(w http.ResponseWriter, r *http.Request)
+
+	// GetPolicy lists the entries of the URL policy for callers from the trusted subnet.
+	// It writes the policy or an error message in JSON format to the HTTP response.
+	GetPolicy(w http.ResponseWriter, r *http.Request)
+
+	// PostPolicyEntry adds an entry to the URL policy for callers from the trusted subnet.
+	// It writes the updated policy or an error message to the HTTP response.
+	PostPolicyEntry(w http.ResponseWriter, r *http.Request)
+
+	// DeletePolicyEntry removes an entry from the URL policy for callers from the trusted subnet.
+	// It writes the updated policy or an error message to the HTTP response.
+	DeletePolicyEntry(w http.ResponseWriter, r *http.Request)
+
+	// GetJob retrieves the status of a deletion job of the authenticated user.
+	// It writes the job or an error message in JSON format to the HTTP response.
+	GetJob(w http.ResponseWriter, r *http.Request)
+
+	// GetQRCode renders a QR code encoding the short URL of a given ID.
+	// It writes the PNG or SVG image or an error message to the HTTP response.
+	GetQRCode(w http.ResponseWriter, r *http.Request)
 }
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/google/uuid"
+)
+
+// PlanURLBatch decides how each URL of a batch saved by userID is stored.
+//
+// Parameters:
+//
+//	userID: The user saving the batch.
+//	items: The URLs of the batch.
+//	existing: The short URLs of the original URLs that are already stored, keyed by original URL.
+//	deleted: The short URLs of the original URLs that are stored but marked as deleted, keyed by original URL.
+//	taken: Reports whether a short URL is already in use.
+//
+// Returns:
+//
+//	The records to store, and the outcome of each item in the order of items. Items whose original URL
+//	is in existing or repeats an earlier item report the short URL it is stored under with Existed set;
+//	items whose alias is taken or repeats an earlier alias report config.ErrAliasTaken. Items whose
+//	original URL is in deleted restore it for userID: under the requested alias if there is one, or else
+//	under its previous short URL. Generated short paths are neither taken nor repeated within the batch.
+func PlanURLBatch(userID uuid.UUID, items []models.BatchURL, existing, deleted map[string]string, taken func(string) bool) ([]models.URLData, []models.BatchURLResult) {
+	records := make([]models.URLData, 0, len(items))
+	results := make([]models.BatchURLResult, len(items))
+	planned := make(map[string]string, len(items)) // planned maps the original URLs of records to their short URLs.
+	claimed := make(map[string]bool, len(items))   // claimed holds the short URLs of records.
+
+	for i, item := range items {
+		if shortURL, ok := existing[item.OriginalURL]; ok {
+			results[i] = models.BatchURLResult{ShortURL: config.BaseURL + "/" + shortURL, Existed: true}
+			continue
+		}
+		if shortURL, ok := planned[item.OriginalURL]; ok {
+			results[i] = models.BatchURLResult{ShortURL: config.BaseURL + "/" + shortURL, Existed: true}
+			continue
+		}
+
+		previous, restored := deleted[item.OriginalURL]
+		shortURL := item.Opts.CustomAlias
+		switch {
+		case restored && (shortURL == "" || shortURL == previous):
+			shortURL = previous
+		case shortURL != "":
+			if claimed[shortURL] || taken(shortURL) {
+				results[i] = models.BatchURLResult{Err: config.ErrAliasTaken}
+				continue
+			}
+		default:
+			shortURL = GenerateShortPath()
+			for claimed[shortURL] || taken(shortURL) {
+				shortURL = GenerateShortPath()
+			}
+		}
+
+		planned[item.OriginalURL] = shortURL
+		claimed[shortURL] = true
+		records = append(records, models.URLData{
+			UUID:         userID,
+			ShortURL:     shortURL,
+			OriginalURL:  item.OriginalURL,
+			ExpiresAt:    item.Opts.ExpiresAt,
+			PasswordHash: item.Opts.PasswordHash,
+		})
+		results[i] = models.BatchURLResult{ShortURL: config.BaseURL + "/" + shortURL}
+	}
+	return records, results
+}
+
+// AbortURLBatch cancels a planned batch if any of its items failed.
+//
+// Parameters:
+//
+//	records: The records planned to be stored, as returned by PlanURLBatch.
+//	results: The outcome of each item, as returned by PlanURLBatch.
+//
+// Returns:
+//
+//	True if an item failed, in which case none of records may be stored and the results of the items
+//	that would have been stored by them are changed to config.ErrBatchAborted; otherwise, false.
+func AbortURLBatch(records []models.URLData, results []models.BatchURLResult) bool {
+	failed := false
+	for _, result := range results {
+		if result.Err != nil {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return false
+	}
+
+	planned := make(map[string]bool, len(records))
+	for _, record := range records {
+		planned[config.BaseURL+"/"+record.ShortURL] = true
+	}
+	for i, result := range results {
+		if result.Err == nil && planned[result.ShortURL] {
+			results[i] = models.BatchURLResult{Err: config.ErrBatchAborted}
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+)
+
+// ClientIP extracts the address of the client that sent a request: the host part of the
+// connection's remote address. Any client can forge forwarding headers, so they are honoured only
+// on connections from a reverse proxy in config.TrustedProxy. Such a request is attributed to its
+// X-Real-IP header, or else to the last X-Forwarded-For entry that is not a trusted proxy: the
+// entries before it were sent by the client itself.
+//
+// Parameters:
+//
+//	r: the HTTP request to inspect.
+//
+// Returns:
+//
+//	The client IP address as a string, or an empty string if it cannot be determined.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !IsTrustedIP(ip, config.TrustedProxy) {
+		return ip
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !IsTrustedIP(hop, config.TrustedProxy) {
+			break
+		}
+	}
+	return ip
+}
+
+// HashIP returns a keyed SHA-256 hash of an IP address so that redirect events can be
+// told apart per client without storing the address itself.
+//
+// Parameters:
+//
+//	ip: the client IP address.
+//
+// Returns:
+//
+//	The hex-encoded HMAC of the address keyed with config.JwtKeySecret, or an empty string for an empty address.
+func HashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(config.JwtKeySecret))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AggregateClicks builds the statistics of a short URL from its redirect events.
+//
+// Parameters:
+//
+//	shortURL: the short URL the events belong to.
+//	clicks: the redirect events of that short URL.
+//
+// Returns:
+//
+//	The total number of events and the number of events per UTC day, oldest day first.
+func AggregateClicks(shortURL string, clicks []models.Click) models.URLStats {
+	perDay := make(map[string]int64)
+	for _, click := range clicks {
+		perDay[click.ClickedAt.UTC().Format(config.StatsDateLayout)]++
+	}
+
+	stats := models.URLStats{
+		ShortURL:    shortURL,
+		TotalClicks: int64(len(clicks)),
+		Daily:       make([]models.DailyClicks, 0, len(perDay)),
+	}
+	for date, count := range perDay {
+		stats.Daily = append(stats.Daily, models.DailyClicks{Date: date, Clicks: count})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+	return stats
+}
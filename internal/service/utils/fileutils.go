@@ -1,161 +1,75 @@
 // Package utils provides utility functions for file-based operations related to URL management,
-// including saving, loading, and marking URLs as deleted within JSON files.
+// such as saving and loading the redirect events of the file storage in JSON files.
 package utils
 
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"os"
 
-	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/fsutil"
 	"github.com/gleb-korostelev/short-url.git/internal/models"
-	"github.com/gleb-korostelev/short-url.git/tools/logger"
 )
 
-// SaveURLs writes a URLData object to a file specified in the configuration.
-// It appends each new URLData entry as a new line in JSON format.
+// SaveClicks appends redirect events to a file, one JSON object per line.
 //
 // Parameters:
 //
-//	save: The URLData object to save to the file.
+//	path: The path to the file containing the redirect events.
+//	clicks: The redirect events to append.
 //
 // Returns:
 //
 //	An error if the file cannot be opened or the data cannot be written; nil otherwise.
-func SaveURLs(save models.URLData) error {
-	file, err := os.OpenFile(config.BaseFilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+func SaveClicks(path string, clicks []models.Click) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
 	writer := bufio.NewWriter(file)
-	data, err := json.Marshal(save)
-	if err != nil {
-		return err
-	}
-	_, err = writer.WriteString(string(data) + "\n")
-	if err != nil {
-		return err
-	}
-	return writer.Flush()
-}
-
-// LoadURLs retrieves the original URL corresponding to a given short URL from a file.
-// It scans through each line of the file, looking for a match that is not marked as deleted.
-//
-// Parameters:
-//
-//	path: The path to the file containing the URL data.
-//	shortURL: The short URL to search for.
-//
-// Returns:
-//
-//	The original URL if found and not deleted, or an error if the URL is not found or an error occurs during file processing.
-func LoadURLs(path string, shortURL string) (string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var urlData models.URLData
-		if err := json.Unmarshal([]byte(scanner.Text()), &urlData); err != nil {
-			return "", err
+	for _, click := range clicks {
+		data, err := json.Marshal(click)
+		if err != nil {
+			return err
 		}
-		if urlData.ShortURL == shortURL && !urlData.DeletedFlag {
-			return urlData.OriginalURL, nil
+		if _, err := writer.WriteString(string(data) + "\n"); err != nil {
+			return err
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return "", err
-	}
-	return "", config.ErrNotFound
+	return writer.Flush()
 }
 
-// LoadUserURLs retrieves all URLs associated with a specific user ID from a file.
-// It only includes URLs that are not marked as deleted.
+// LoadClicks retrieves the redirect events of a short URL from a file.
+// A missing file is treated as empty. A last line cut short by a crash in the middle of an append
+// is truncated away, as the file and in-memory storages do with their logs, so that later appends
+// start on a line of their own; only a line that cannot be read elsewhere in the file is an error.
+// The caller must serialize LoadClicks with the appends to the file.
 //
 // Parameters:
 //
-//	path: The path to the file containing the URL data.
-//	userID: The user ID to search for.
+//	path: The path to the file containing the redirect events.
+//	shortURL: The short URL whose events are returned.
 //
 // Returns:
 //
-//	A list of UserURLs if found, or an error if an error occurs during file processing.
-func LoadUserURLs(path string, userID string) ([]models.UserURLs, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	var urls []models.UserURLs
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var urlData models.URLData
-		var data models.UserURLs
-		if err := json.Unmarshal([]byte(scanner.Text()), &urlData); err != nil {
-			return nil, err
+//	The redirect events of the short URL, or an error if the file cannot be processed.
+func LoadClicks(path string, shortURL string) ([]models.Click, error) {
+	var clicks []models.Click
+	_, err := fsutil.ReplayLines(path, func(line int, data []byte) error {
+		var click models.Click
+		if err := json.Unmarshal(data, &click); err != nil {
+			return fmt.Errorf("clicks file %s:%d: %w", path, line, err)
 		}
-		if urlData.UUID.String() == userID && !urlData.DeletedFlag {
-			data.OriginalURL = urlData.OriginalURL
-			data.ShortURL = config.BaseURL + "/" + urlData.ShortURL
-			urls = append(urls, data)
+		if click.ShortURL == shortURL {
+			clicks = append(clicks, click)
 		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return urls, nil
-}
-
-// MarkURLsAsDeletedInFile marks specific URLs as deleted for a given user ID in a file.
-// It rewrites the entire file to update the deleted flags of the specified URLs.
-//
-// Parameters:
-//
-//	path: The file path where URL data is stored.
-//	userID: The user ID whose URLs are to be marked as deleted.
-//	shortURLs: A list of short URLs to be marked as deleted.
-//
-// Returns:
-//
-//	An error if the file cannot be processed; nil otherwise.
-func MarkURLsAsDeletedInFile(path, userID string, shortURLs []string) error {
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		return nil
+	})
 	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	writer := bufio.NewWriter(file)
-
-	for scanner.Scan() {
-		var urlData models.URLData
-		if err := json.Unmarshal([]byte(scanner.Text()), &urlData); err != nil {
-			return err
-		}
-		if urlData.UUID.String() == userID && CheckURL(urlData.ShortURL, shortURLs) {
-			urlData.DeletedFlag = true
-		}
-		data, err := json.Marshal(urlData)
-		if err != nil {
-			logger.Errorf("error marshalling json: %w", err)
-			return err
-		}
-		_, err = writer.WriteString(string(data) + "\n")
-		if err != nil {
-			logger.Errorf("error writing file: %w", err)
-			return err
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return err
+		return nil, err
 	}
-
-	return writer.Flush()
+	return clicks, nil
 }
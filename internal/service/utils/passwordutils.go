@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashPassword hashes the password of a protected link with bcrypt.
+//
+// Parameters:
+//
+//	password: the plain text password chosen when the link is shortened.
+//
+// Returns:
+//
+//	The bcrypt hash, or an error wrapping config.ErrInvalidPassword if the password is empty
+//	or longer than config.PasswordMaxLength bytes.
+func HashPassword(password string) (string, error) {
+	if password == "" || len(password) > config.PasswordMaxLength {
+		return "", fmt.Errorf("%w: length must be between 1 and %d bytes", config.ErrInvalidPassword, config.PasswordMaxLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword compares a password given for a protected link with the stored hash.
+//
+// Parameters:
+//
+//	hash: the bcrypt hash stored with the link.
+//	password: the plain text password supplied by the client.
+//
+// Returns:
+//
+//	nil if the password matches, config.ErrWrongPassword if it does not, or another error
+//	if the hash is malformed.
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return config.ErrWrongPassword
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"golang.org/x/net/idna"
+)
+
+// defaultPorts maps the schemes whose default port is dropped by NormalizeURL to that port.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URLError describes why a URL cannot be shortened. It wraps config.ErrInvalidURL.
+type URLError struct {
+	Code   string // Code is the machine-readable reason, one of the config.URLError* constants.
+	Detail string // Detail is the human-readable reason.
+}
+
+// Error returns the human-readable reason prefixed with config.ErrInvalidURL.
+func (e *URLError) Error() string {
+	return config.ErrInvalidURL.Error() + ": " + e.Detail
+}
+
+// Unwrap returns config.ErrInvalidURL, so that errors.Is recognises every URLError.
+func (e *URLError) Unwrap() error {
+	return config.ErrInvalidURL
+}
+
+// urlError creates a URLError with a formatted detail.
+func urlError(code, format string, args ...any) *URLError {
+	return &URLError{Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+// NormalizeURL validates a URL to be shortened and returns its normalized form, under which
+// it is stored and compared with the URLs already shortened.
+//
+// Parameters:
+//
+//	rawURL: The original URL to shorten; surrounding whitespace is ignored.
+//
+// Returns:
+//
+//	The URL with a lowercase scheme and host, an ASCII (IDNA) host, no default port and, if
+//	config.StripURLFragments is set, no fragment. A *URLError is returned if the URL is empty,
+//	longer than config.MaxURLLength, not absolute, uses a scheme missing from config.URLSchemes,
+//	or has an invalid host or port.
+func NormalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", urlError(config.URLErrorEmpty, "URL is empty")
+	}
+	if len(rawURL) > config.MaxURLLength {
+		return "", urlError(config.URLErrorTooLong, "URL is longer than %d bytes", config.MaxURLLength)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" {
+		return "", urlError(config.URLErrorMalformed, "%q is not an absolute URL", rawURL)
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if !schemeAllowed(parsed.Scheme) {
+		return "", urlError(config.URLErrorScheme, "scheme %q is not allowed", parsed.Scheme)
+	}
+	if parsed.Opaque != "" {
+		return "", urlError(config.URLErrorMalformed, "%q is not an absolute URL", rawURL)
+	}
+
+	host, err := normalizeHost(parsed.Hostname())
+	if err != nil {
+		return "", err
+	}
+	port := parsed.Port()
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", urlError(config.URLErrorPort, "port %q is not valid", port)
+		}
+	}
+	if port == defaultPorts[parsed.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		parsed.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		parsed.Host = "[" + host + "]"
+	default:
+		parsed.Host = host
+	}
+
+	if config.StripURLFragments {
+		parsed.Fragment = ""
+		parsed.RawFragment = ""
+	}
+
+	normalized := parsed.String()
+	if len(normalized) > config.MaxURLLength {
+		return "", urlError(config.URLErrorTooLong, "URL is longer than %d bytes", config.MaxURLLength)
+	}
+	return normalized, nil
+}
+
+// normalizeHost lowercases a host name and converts an internationalized domain name to its
+// ASCII form. IP addresses are returned in their canonical form.
+func normalizeHost(host string) (string, error) {
+	if host == "" {
+		return "", urlError(config.URLErrorHost, "host is missing")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	ascii, err := idna.Lookup.ToASCII(strings.ToLower(host))
+	if err != nil {
+		return "", urlError(config.URLErrorHost, "host %q is not a valid domain name", host)
+	}
+	return ascii, nil
+}
+
+// schemeAllowed reports whether scheme is listed in config.URLSchemes.
+func schemeAllowed(scheme string) bool {
+	for _, allowed := range strings.Split(config.URLSchemes, ",") {
+		if strings.EqualFold(strings.TrimSpace(allowed), scheme) {
+			return true
+		}
+	}
+	return false
+}
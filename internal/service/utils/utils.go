@@ -1,9 +1,14 @@
 package utils
 
 import (
+	"fmt"
 	"math/rand"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
 )
 
 // GenerateShortPath generates a random string of a predefined length using a specified
@@ -45,3 +50,130 @@ func CheckURL(check string, findlist []string) bool {
 	}
 	return false
 }
+
+// ValidateAlias checks that a user-supplied custom alias can be used as a short URL.
+//
+// Parameters:
+//
+//	alias: The requested short code.
+//
+// Returns:
+//
+//	An error wrapping config.ErrInvalidAlias if the alias is too short or too long,
+//	contains characters outside config.Letters and config.AliasExtraChars, or matches
+//	one of config.ReservedAliases (case-insensitively); nil otherwise.
+func ValidateAlias(alias string) error {
+	if len(alias) < config.AliasMinLength || len(alias) > config.AliasMaxLength {
+		return fmt.Errorf("%w: length must be between %d and %d characters",
+			config.ErrInvalidAlias, config.AliasMinLength, config.AliasMaxLength)
+	}
+	for _, r := range alias {
+		if !strings.ContainsRune(config.Letters+config.AliasExtraChars, r) {
+			return fmt.Errorf("%w: character %q is not allowed", config.ErrInvalidAlias, r)
+		}
+	}
+	for _, reserved := range config.ReservedAliases {
+		if strings.EqualFold(alias, reserved) {
+			return fmt.Errorf("%w: %q is reserved", config.ErrInvalidAlias, alias)
+		}
+	}
+	return nil
+}
+
+// ResolveExpiry turns the optional absolute expiry and relative TTL of a shortening request
+// into a single expiration time.
+//
+// Parameters:
+//
+//	expiresAt: The requested absolute expiry, or nil.
+//	ttlSeconds: The requested lifetime in seconds, or 0.
+//	now: The moment the TTL is counted from and expiresAt is compared against.
+//
+// Returns:
+//
+//	The expiration time, nil if neither value is set, or an error wrapping config.ErrInvalidExpiry
+//	if both values are set, the TTL is negative, or expiresAt is not in the future.
+func ResolveExpiry(expiresAt *time.Time, ttlSeconds int64, now time.Time) (*time.Time, error) {
+	switch {
+	case expiresAt != nil && ttlSeconds != 0:
+		return nil, fmt.Errorf("%w: expires_at and ttl_seconds are mutually exclusive", config.ErrInvalidExpiry)
+	case ttlSeconds < 0:
+		return nil, fmt.Errorf("%w: ttl_seconds must be positive", config.ErrInvalidExpiry)
+	case ttlSeconds > 0:
+		expiry := now.Add(time.Duration(ttlSeconds) * time.Second).UTC()
+		return &expiry, nil
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", config.ErrInvalidExpiry)
+		}
+		expiry := expiresAt.UTC()
+		return &expiry, nil
+	}
+	return nil, nil
+}
+
+// BuildShortenOptions validates the optional per-link settings of a shortening request
+// and converts them into the options passed to the storage.
+//
+// Parameters:
+//
+//	alias: The requested custom alias, or an empty string.
+//	expiresAt: The requested absolute expiry, or nil.
+//	ttlSeconds: The requested lifetime in seconds, or 0.
+//	password: The requested link password, or an empty string for an unprotected link.
+//
+// Returns:
+//
+//	The storage options carrying the bcrypt hash of the password, or an error wrapping
+//	config.ErrInvalidAlias, config.ErrInvalidExpiry or config.ErrInvalidPassword if any of
+//	the settings is unacceptable.
+func BuildShortenOptions(alias string, expiresAt *time.Time, ttlSeconds int64, password string) (models.ShortenOptions, error) {
+	var opts models.ShortenOptions
+	if alias != "" {
+		if err := ValidateAlias(alias); err != nil {
+			return opts, err
+		}
+		opts.CustomAlias = alias
+	}
+
+	expiry, err := ResolveExpiry(expiresAt, ttlSeconds, time.Now())
+	if err != nil {
+		return opts, err
+	}
+	opts.ExpiresAt = expiry
+
+	if password != "" {
+		hash, err := HashPassword(password)
+		if err != nil {
+			return opts, err
+		}
+		opts.PasswordHash = hash
+	}
+	return opts, nil
+}
+
+// IsTrustedIP reports whether an IP address belongs to a subnet given in CIDR notation.
+//
+// Parameters:
+//
+//	ip: The IP address to check, typically taken from the X-Real-IP header.
+//	subnet: The trusted subnet in CIDR notation, for example "192.168.1.0/24".
+//
+// Returns:
+//
+//	True if both values are well-formed and the address is inside the subnet; otherwise, false.
+//	An empty subnet trusts no one.
+func IsTrustedIP(ip, subnet string) bool {
+	if subnet == "" {
+		return false
+	}
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return false
+	}
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return false
+	}
+	return network.Contains(addr)
+}
@@ -0,0 +1,287 @@
+// Package cached implements a storage.Storage decorator that serves GetOriginalLink from a
+// cache.URLCache. Lookups that fail because a link is unknown, deleted or expired are cached too,
+// for a shorter time, so that scans of random codes do not reach the underlying storage either.
+// Password protected links are only cached as such; GetProtectedLink always reaches the storage.
+// Links with an expiration time are cached no longer than they live, and dropped from the cache
+// when the expiration sweeper marks them as deleted.
+package cached
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/cache"
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+const (
+	// keyPrefix namespaces the cache keys of short URLs.
+	keyPrefix = "short-url:"
+
+	// hitPrefix marks a cached original URL.
+	hitPrefix = "="
+
+	// Cached lookup failures.
+	missNotFound = "!not-found"
+	missGone     = "!gone"
+	missExpired  = "!expired"
+	missPassword = "!password"
+
+	// generationStripes is the number of generation counters the short URLs are spread over.
+	generationStripes = 256
+)
+
+// service wraps a storage.Storage and caches the results of GetOriginalLink.
+// Every other method is delegated to the wrapped storage; the ones that can change
+// what a short URL resolves to also drop it from the cache.
+type service struct {
+	storage.Storage
+
+	cache       cache.URLCache // cache holds the results of recent lookups.
+	ttl         time.Duration  // ttl is the lifetime of a cached original URL.
+	negativeTTL time.Duration  // negativeTTL is the lifetime of a cached lookup failure.
+
+	mu       sync.Mutex
+	expiring map[string]expiringEntry // expiring holds the cached links that have an expiration time.
+
+	// generations count the invalidations of the short URLs hashed to each stripe, so that a lookup
+	// that raced with an invalidation does not cache what it read before.
+	generations [generationStripes]atomic.Uint64
+	seed        maphash.Seed // seed hashes the short URLs to their stripe.
+}
+
+// expiringEntry records a cached original URL whose link expires.
+type expiringEntry struct {
+	expiresAt time.Time // expiresAt is the expiration time of the link.
+	cachedTil time.Time // cachedTil is when the cache entry times out on its own.
+}
+
+// NewCachedStorage creates a storage that serves redirects from c before falling back to next.
+// Original URLs are cached for ttl and failed lookups for negativeTTL. A cache that is
+// unavailable is logged and bypassed. The original URL of a link that expires is cached until
+// its expiration time at the latest.
+func NewCachedStorage(next storage.Storage, c cache.URLCache, ttl, negativeTTL time.Duration) storage.Storage {
+	return &service{
+		Storage:     next,
+		cache:       c,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		expiring:    make(map[string]expiringEntry),
+		seed:        maphash.MakeSeed(),
+	}
+}
+
+// GetOriginalLink returns the cached result for shortURL, or looks it up in the wrapped storage
+// and caches the original URL or the reason the lookup failed. The result is not cached if the
+// short URL was invalidated during the lookup.
+func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
+	key := keyPrefix + shortURL
+	value, found, err := s.cache.Get(ctx, key)
+	if err != nil {
+		logger.Errorf("Error reading URL cache: %v", err)
+	}
+	if found {
+		if originalURL, ok := strings.CutPrefix(value, hitPrefix); ok {
+			return originalURL, nil
+		}
+		switch value {
+		case missNotFound:
+			return "", config.ErrNotFound
+		case missGone:
+			return "", config.ErrGone
+		case missExpired:
+			return "", config.ErrExpired
+		case missPassword:
+			return "", config.ErrPasswordRequired
+		}
+	}
+
+	generation := s.generation(shortURL).Load()
+	originalURL, expiresAt, err := s.Storage.GetExpiringLink(ctx, shortURL)
+	switch {
+	case err == nil:
+		s.fillHit(ctx, shortURL, generation, originalURL, expiresAt)
+	case errors.Is(err, config.ErrNotFound):
+		s.fill(ctx, shortURL, generation, missNotFound, s.negativeTTL)
+	case errors.Is(err, config.ErrGone):
+		s.fill(ctx, shortURL, generation, missGone, s.negativeTTL)
+	case errors.Is(err, config.ErrExpired):
+		s.fill(ctx, shortURL, generation, missExpired, s.negativeTTL)
+	case errors.Is(err, config.ErrPasswordRequired):
+		// Protection does not change for the lifetime of a link, so the full ttl applies.
+		s.fill(ctx, shortURL, generation, missPassword, s.ttl)
+	}
+	return originalURL, err
+}
+
+// SaveUniqueURL saves the URL in the wrapped storage and drops any cached failure for its short URL.
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
+	shortURL, status, err := s.Storage.SaveUniqueURL(ctx, originalURL, userID, opts)
+	if err == nil {
+		s.invalidate(ctx, opts.CustomAlias, strings.TrimPrefix(shortURL, config.BaseURL+"/"))
+	}
+	return shortURL, status, err
+}
+
+// SaveURL saves the URL in the wrapped storage and drops any cached failure for its short URL.
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
+	shortURL, err := s.Storage.SaveURL(ctx, originalURL, userID, opts)
+	if err == nil {
+		s.invalidate(ctx, opts.CustomAlias, strings.TrimPrefix(shortURL, config.BaseURL+"/"))
+	}
+	return shortURL, err
+}
+
+// SaveURLsBatch saves the URLs in the wrapped storage and drops any cached failure for their short URLs.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	results, err := s.Storage.SaveURLsBatch(ctx, userID, items, atomic)
+	if err != nil {
+		return nil, err
+	}
+	shortURLs := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Err == nil && !result.Existed {
+			shortURLs = append(shortURLs, strings.TrimPrefix(result.ShortURL, config.BaseURL+"/"))
+		}
+	}
+	s.invalidate(ctx, shortURLs...)
+	return results, nil
+}
+
+// MarkURLsAsDeleted deletes the URLs in the wrapped storage and drops them from the cache.
+func (s *service) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
+	err := s.Storage.MarkURLsAsDeleted(ctx, userID, shortURLs)
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, shortURLs...)
+	return nil
+}
+
+// MarkURLsAsDeletedBatch deletes the URLs in the wrapped storage and drops them from the cache.
+func (s *service) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	err := s.Storage.MarkURLsAsDeletedBatch(ctx, deletions)
+	if err != nil {
+		return err
+	}
+	shortURLs := make([]string, len(deletions))
+	for i, deletion := range deletions {
+		shortURLs[i] = deletion.ShortURL
+	}
+	s.invalidate(ctx, shortURLs...)
+	return nil
+}
+
+// MarkExpiredURLsAsDeleted marks the expired URLs as deleted in the wrapped storage and drops
+// the cached original URLs of the links that have expired.
+func (s *service) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	marked, err := s.Storage.MarkExpiredURLsAsDeleted(ctx)
+	if err != nil {
+		return marked, err
+	}
+
+	now := time.Now()
+	var shortURLs []string
+	s.mu.Lock()
+	for shortURL, entry := range s.expiring {
+		if !entry.expiresAt.After(now) {
+			shortURLs = append(shortURLs, shortURL)
+		}
+		if !entry.expiresAt.After(now) || !entry.cachedTil.After(now) {
+			delete(s.expiring, shortURL)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(shortURLs) > 0 {
+		s.invalidate(ctx, shortURLs...)
+	}
+	return marked, nil
+}
+
+// ImportURL stores the record in the wrapped storage and drops any cached result for its short URL.
+func (s *service) ImportURL(ctx context.Context, data models.URLData) error {
+	err := s.Storage.ImportURL(ctx, data)
+	if err == nil {
+		s.invalidate(ctx, data.ShortURL)
+	}
+	return err
+}
+
+// Close closes the cache and the wrapped storage.
+func (s *service) Close() error {
+	if err := s.cache.Close(); err != nil {
+		logger.Errorf("Error closing URL cache: %v", err)
+	}
+	return s.Storage.Close()
+}
+
+// set stores a value in the cache, logging failures.
+func (s *service) set(ctx context.Context, key, value string, ttl time.Duration) {
+	if err := s.cache.Set(ctx, key, value, ttl); err != nil {
+		logger.Errorf("Error writing URL cache: %v", err)
+	}
+}
+
+// fillHit caches the original URL of shortURL as fill does. If the link expires, the entry lives
+// no longer than the link and is recorded so that MarkExpiredURLsAsDeleted can drop it.
+func (s *service) fillHit(ctx context.Context, shortURL string, generation uint64, originalURL string, expiresAt *time.Time) {
+	ttl := s.ttl
+	if expiresAt != nil {
+		remaining := time.Until(*expiresAt)
+		if remaining <= 0 {
+			return
+		}
+		ttl = min(ttl, remaining)
+
+		s.mu.Lock()
+		s.expiring[shortURL] = expiringEntry{expiresAt: *expiresAt, cachedTil: time.Now().Add(ttl)}
+		s.mu.Unlock()
+	}
+	s.fill(ctx, shortURL, generation, hitPrefix+originalURL, ttl)
+}
+
+// fill caches the result of a lookup of shortURL that started at the given generation of its
+// stripe. The result is dropped if the short URL may have been invalidated since: it is not
+// written if the generation changed before, and deleted again if it changed while writing.
+func (s *service) fill(ctx context.Context, shortURL string, generation uint64, value string, ttl time.Duration) {
+	counter := s.generation(shortURL)
+	if counter.Load() != generation {
+		return
+	}
+	key := keyPrefix + shortURL
+	s.set(ctx, key, value, ttl)
+	if counter.Load() != generation {
+		if err := s.cache.Delete(ctx, key); err != nil {
+			logger.Errorf("Error invalidating URL cache: %v", err)
+		}
+	}
+}
+
+// generation returns the counter of the invalidations of the stripe of shortURL.
+func (s *service) generation(shortURL string) *atomic.Uint64 {
+	return &s.generations[maphash.String(s.seed, shortURL)%generationStripes]
+}
+
+// invalidate drops the given short URLs from the cache, logging failures. Their generation is
+// advanced first, so that lookups in progress do not cache them again.
+func (s *service) invalidate(ctx context.Context, shortURLs ...string) {
+	keys := make([]string, 0, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		if shortURL != "" {
+			s.generation(shortURL).Add(1)
+			keys = append(keys, keyPrefix+shortURL)
+		}
+	}
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		logger.Errorf("Error invalidating URL cache: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package cached_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/cache"
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/cached"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOriginalLinkCachesHits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := cached.NewCachedStorage(mockStore, cache.NewLRUCache(10), time.Minute, time.Minute)
+
+	mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("http://example.com", nil, nil).Times(1)
+
+	for i := 0; i < 3; i++ {
+		originalURL, err := store.GetOriginalLink(ctx, "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://example.com", originalURL)
+	}
+}
+
+func TestGetOriginalLinkCachesMisses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := cached.NewCachedStorage(mockStore, cache.NewLRUCache(10), time.Minute, time.Minute)
+
+	tests := []struct {
+		name        string
+		shortURL    string
+		storeErr    error
+		expectedErr error
+	}{
+		{name: "Not Found", shortURL: "missing", storeErr: config.ErrNotFound, expectedErr: config.ErrNotFound},
+		{name: "Gone", shortURL: "gone", storeErr: config.ErrGone, expectedErr: config.ErrGone},
+		{name: "Expired", shortURL: "expired", storeErr: config.ErrExpired, expectedErr: config.ErrExpired},
+		{name: "Password Required", shortURL: "locked", storeErr: config.ErrPasswordRequired, expectedErr: config.ErrPasswordRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore.EXPECT().GetExpiringLink(gomock.Any(), tt.shortURL).Return("", nil, tt.storeErr).Times(1)
+
+			for i := 0; i < 2; i++ {
+				_, err := store.GetOriginalLink(ctx, tt.shortURL)
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestGetOriginalLinkDoesNotCacheFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := cached.NewCachedStorage(mockStore, cache.NewLRUCache(10), time.Minute, time.Minute)
+
+	dbErr := errors.New("connection refused")
+	mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("", nil, dbErr)
+	mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("http://example.com", nil, nil)
+
+	_, err := store.GetOriginalLink(ctx, "abc")
+	assert.ErrorIs(t, err, dbErr)
+
+	originalURL, err := store.GetOriginalLink(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com", originalURL)
+}
+
+func TestMarkURLsAsDeletedInvalidates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := cached.NewCachedStorage(mockStore, cache.NewLRUCache(10), time.Minute, time.Minute)
+
+	gomock.InOrder(
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("http://example.com", nil, nil),
+		mockStore.EXPECT().MarkURLsAsDeleted(gomock.Any(), "user", []string{"abc"}).Return(nil),
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("", nil, config.ErrGone),
+	)
+
+	_, err := store.GetOriginalLink(ctx, "abc")
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.MarkURLsAsDeleted(ctx, "user", []string{"abc"}))
+
+	_, err = store.GetOriginalLink(ctx, "abc")
+	assert.ErrorIs(t, err, config.ErrGone)
+}
+
+func TestGetOriginalLinkDropsFillRacingInvalidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := cached.NewCachedStorage(mockStore, cache.NewLRUCache(10), time.Minute, time.Minute)
+
+	gomock.InOrder(
+		// The link is deleted while the first lookup is in progress.
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").DoAndReturn(
+			func(ctx context.Context, _ string) (string, *time.Time, error) {
+				assert.NoError(t, store.MarkURLsAsDeleted(ctx, "user", []string{"abc"}))
+				return "http://example.com", nil, nil
+			}),
+		mockStore.EXPECT().MarkURLsAsDeleted(gomock.Any(), "user", []string{"abc"}).Return(nil),
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("", nil, config.ErrGone),
+	)
+
+	originalURL, err := store.GetOriginalLink(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com", originalURL)
+
+	_, err = store.GetOriginalLink(ctx, "abc")
+	assert.ErrorIs(t, err, config.ErrGone)
+}
+
+func TestGetOriginalLinkCapsTTLAtExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := cached.NewCachedStorage(mockStore, cache.NewLRUCache(10), time.Minute, time.Minute)
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	gomock.InOrder(
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("http://example.com", &expiresAt, nil),
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("", nil, config.ErrExpired),
+	)
+
+	for i := 0; i < 2; i++ {
+		originalURL, err := store.GetOriginalLink(ctx, "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://example.com", originalURL)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	_, err := store.GetOriginalLink(ctx, "abc")
+	assert.ErrorIs(t, err, config.ErrExpired)
+}
+
+// staticCache is a cache.URLCache whose entries never time out.
+type staticCache map[string]string
+
+func (c staticCache) Get(_ context.Context, key string) (string, bool, error) {
+	value, ok := c[key]
+	return value, ok, nil
+}
+
+func (c staticCache) Set(_ context.Context, key, value string, _ time.Duration) error {
+	c[key] = value
+	return nil
+}
+
+func (c staticCache) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(c, key)
+	}
+	return nil
+}
+
+func (c staticCache) Close() error {
+	return nil
+}
+
+func TestMarkExpiredURLsAsDeletedInvalidates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := cached.NewCachedStorage(mockStore, staticCache{}, time.Minute, time.Minute)
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	later := time.Now().Add(time.Hour)
+	gomock.InOrder(
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("http://example.com", &expiresAt, nil),
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "def").Return("http://example.org", &later, nil),
+		mockStore.EXPECT().MarkExpiredURLsAsDeleted(gomock.Any()).Return(1, nil),
+		mockStore.EXPECT().GetExpiringLink(gomock.Any(), "abc").Return("", nil, config.ErrGone),
+	)
+
+	_, err := store.GetOriginalLink(ctx, "abc")
+	assert.NoError(t, err)
+	_, err = store.GetOriginalLink(ctx, "def")
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	marked, err := store.MarkExpiredURLsAsDeleted(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	_, err = store.GetOriginalLink(ctx, "abc")
+	assert.ErrorIs(t, err, config.ErrGone)
+	originalURL, err := store.GetOriginalLink(ctx, "def")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.org", originalURL)
+}
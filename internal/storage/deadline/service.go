@@ -0,0 +1,152 @@
+// Package deadline implements a storage.Storage decorator that bounds every storage operation
+// with a deadline, so that a slow backend cannot hold a request or a worker indefinitely.
+// Operations that only read and operations that write have separate deadlines.
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+)
+
+// service wraps a storage.Storage and derives a context with a deadline for each of its operations.
+// ExportURLs streams the whole storage and is not bounded; Close is delegated as is.
+type service struct {
+	storage.Storage
+
+	read  time.Duration // read bounds the operations that only read; zero disables the deadline.
+	write time.Duration // write bounds the operations that write; zero disables the deadline.
+}
+
+// NewDeadlineStorage creates a storage that runs the reading operations of next within read
+// and the writing ones within write. A deadline already set on the caller's context is kept
+// if it is earlier. A zero duration leaves the corresponding operations unbounded.
+func NewDeadlineStorage(next storage.Storage, read, write time.Duration) storage.Storage {
+	return &service{
+		Storage: next,
+		read:    read,
+		write:   write,
+	}
+}
+
+// withTimeout derives the context of an operation bounded by timeout.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// SaveUniqueURL runs SaveUniqueURL of the wrapped storage within the write deadline.
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.SaveUniqueURL(ctx, originalURL, userID, opts)
+}
+
+// SaveURL runs SaveURL of the wrapped storage within the write deadline.
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.SaveURL(ctx, originalURL, userID, opts)
+}
+
+// SaveURLsBatch runs SaveURLsBatch of the wrapped storage within the write deadline.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.SaveURLsBatch(ctx, userID, items, atomic)
+}
+
+// GetOriginalLink runs GetOriginalLink of the wrapped storage within the read deadline.
+func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.GetOriginalLink(ctx, shortURL)
+}
+
+// GetExpiringLink runs GetExpiringLink of the wrapped storage within the read deadline.
+func (s *service) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.GetExpiringLink(ctx, shortURL)
+}
+
+// GetProtectedLink runs GetProtectedLink of the wrapped storage within the read deadline.
+func (s *service) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.GetProtectedLink(ctx, shortURL)
+}
+
+// Ping runs Ping of the wrapped storage within the read deadline.
+func (s *service) Ping(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.Ping(ctx)
+}
+
+// GetAllURLS runs GetAllURLS of the wrapped storage within the read deadline.
+func (s *service) GetAllURLS(ctx context.Context, userID, baseURL string) ([]models.UserURLs, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.GetAllURLS(ctx, userID, baseURL)
+}
+
+// MarkURLsAsDeleted runs MarkURLsAsDeleted of the wrapped storage within the write deadline.
+func (s *service) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.MarkURLsAsDeleted(ctx, userID, shortURLs)
+}
+
+// MarkURLsAsDeletedBatch runs MarkURLsAsDeletedBatch of the wrapped storage within the write deadline.
+func (s *service) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.MarkURLsAsDeletedBatch(ctx, deletions)
+}
+
+// MarkExpiredURLsAsDeleted runs MarkExpiredURLsAsDeleted of the wrapped storage within the write deadline.
+func (s *service) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.MarkExpiredURLsAsDeleted(ctx)
+}
+
+// SaveClicks runs SaveClicks of the wrapped storage within the write deadline.
+func (s *service) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.SaveClicks(ctx, clicks)
+}
+
+// GetURLStats runs GetURLStats of the wrapped storage within the read deadline.
+func (s *service) GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.GetURLStats(ctx, userID, shortURL)
+}
+
+// CountURLs runs CountURLs of the wrapped storage within the read deadline.
+func (s *service) CountURLs(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.CountURLs(ctx)
+}
+
+// CountUsers runs CountUsers of the wrapped storage within the read deadline.
+func (s *service) CountUsers(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.read)
+	defer cancel()
+	return s.Storage.CountUsers(ctx)
+}
+
+// ImportURL runs ImportURL of the wrapped storage within the write deadline.
+func (s *service) ImportURL(ctx context.Context, data models.URLData) error {
+	ctx, cancel := withTimeout(ctx, s.write)
+	defer cancel()
+	return s.Storage.ImportURL(ctx, data)
+}
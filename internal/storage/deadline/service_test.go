@@ -0,0 +1,71 @@
+package deadline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/deadline"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadlineStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := deadline.NewDeadlineStorage(mockStore, time.Second, time.Minute)
+
+	// remaining reports how long the context passed to the wrapped storage had left.
+	remaining := func(ctx context.Context) time.Duration {
+		d, ok := ctx.Deadline()
+		if !ok {
+			return 0
+		}
+		return time.Until(d)
+	}
+
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "abc").DoAndReturn(func(ctx context.Context, _ string) (string, error) {
+		assert.InDelta(t, time.Second, remaining(ctx), float64(100*time.Millisecond))
+		return "http://example.com", nil
+	})
+	mockStore.EXPECT().SaveURL(gomock.Any(), "http://example.com", "user", gomock.Any()).DoAndReturn(func(ctx context.Context, _, _ string, _ models.ShortenOptions) (string, error) {
+		assert.InDelta(t, time.Minute, remaining(ctx), float64(100*time.Millisecond))
+		return "http://localhost:8080/abc", nil
+	})
+	mockStore.EXPECT().CountURLs(gomock.Any()).DoAndReturn(func(ctx context.Context) (int, error) {
+		// The earlier deadline of the caller is kept.
+		assert.Less(t, remaining(ctx), 100*time.Millisecond)
+		return 1, nil
+	})
+
+	_, err := store.GetOriginalLink(context.Background(), "abc")
+	assert.NoError(t, err)
+	_, err = store.SaveURL(context.Background(), "http://example.com", "user", models.ShortenOptions{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = store.CountURLs(ctx)
+	assert.NoError(t, err)
+}
+
+func TestDeadlineStorageDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := deadline.NewDeadlineStorage(mockStore, 0, 0)
+
+	mockStore.EXPECT().Ping(gomock.Any()).DoAndReturn(func(ctx context.Context) (int, error) {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return 200, nil
+	})
+
+	_, err := store.Ping(context.Background())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,12 @@
+package filecache
+
+import "github.com/gleb-korostelev/short-url.git/internal/storage"
+
+// Compact compacts the log of a file storage at once, as the background maintenance does once
+// enough records are superseded.
+func Compact(store storage.Storage) error {
+	s := store.(*service)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
@@ -0,0 +1,187 @@
+package filecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/fsutil"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// The file is an append-only log of models.URLData records, one JSON object per line. A later
+// record of a short URL supersedes the earlier ones: a URL is saved by appending its record and
+// deleted by appending a tombstone, which is the record again with DeletedFlag set. Replaying the
+// log in order rebuilds the index of the latest record of every short URL, from which all lookups
+// are served; compaction rewrites the log without the superseded records.
+
+// replay builds the index from the log, if it exists, and truncates a torn last line.
+// A last line cut short by a crash recorded a change that was never acknowledged, so it is dropped;
+// any other line that cannot be read is reported as config.ErrCorruptLog.
+func (s *service) replay() error {
+	size, err := fsutil.ReplayLines(s.path, func(line int, data []byte) error {
+		var record models.URLData
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("%w: %s:%d: %v", config.ErrCorruptLog, s.path, line, err)
+		}
+		s.apply(record)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.size = size
+	return nil
+}
+
+// apply makes record the latest record of its short URL in the index. It replaces the record of its
+// original URL as well, which moves when a deleted original URL is restored under another alias.
+// The caller must hold s.mu for writing or have exclusive access to s.
+func (s *service) apply(record models.URLData) {
+	if moved, exists := s.originals[record.OriginalURL]; exists && moved != record.ShortURL {
+		delete(s.urls, moved)
+		s.order = slices.DeleteFunc(s.order, func(shortURL string) bool { return shortURL == moved })
+		s.stale++
+	}
+	if prev, exists := s.urls[record.ShortURL]; exists {
+		s.stale++
+		if s.originals[prev.OriginalURL] == record.ShortURL {
+			delete(s.originals, prev.OriginalURL)
+		}
+	} else {
+		s.order = append(s.order, record.ShortURL)
+	}
+	s.urls[record.ShortURL] = record
+	s.originals[record.OriginalURL] = record.ShortURL
+}
+
+// append writes records to the log with a single write, fsyncs it according to the sync mode and
+// applies the records to the index. A failed write is truncated away, so that later records are
+// never appended to a torn line. The caller must hold s.mu for writing.
+func (s *service) append(records ...models.URLData) error {
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	var buf []byte
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, data...)
+		buf = append(buf, '\n')
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if _, err := s.file.Write(buf); err != nil {
+		if err := s.file.Truncate(s.size); err != nil {
+			logger.Errorf("Error truncating failed write to file storage %s: %v", s.path, err)
+		}
+		return err
+	}
+	s.size += int64(len(buf))
+	for _, record := range records {
+		s.apply(record)
+	}
+
+	switch s.syncMode {
+	case config.FileSyncAlways:
+		return s.file.Sync()
+	case config.FileSyncPeriodic:
+		s.dirty = true
+	}
+	return nil
+}
+
+// compact rewrites the log with the latest record of every short URL only, in the order the short
+// URLs were first saved. The log is replaced atomically, so that a crash leaves either the old log
+// or the new one. The caller must hold s.mu for writing.
+func (s *service) compact() error {
+	var size int64
+	err := fsutil.WriteFile(s.path, 0644, func(w io.Writer) error {
+		for _, shortURL := range s.order {
+			data, err := json.Marshal(s.urls[shortURL])
+			if err != nil {
+				return err
+			}
+			n, err := w.Write(append(data, '\n'))
+			if err != nil {
+				return err
+			}
+			size += int64(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The old file is gone, so appending to it would lose the records: without the new one the
+	// storage refuses further writes.
+	s.file.Close()
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		s.file = nil
+		return err
+	}
+	logger.Infof("Compacted file storage %s from %d to %d records", s.path, len(s.urls)+s.stale, len(s.urls))
+	s.size = size
+	s.stale = 0
+	s.dirty = false
+	return nil
+}
+
+// maintain fsyncs the log every config.FileSyncInterval in config.FileSyncPeriodic mode and checks
+// every config.FileCompactInterval whether it should be compacted, until Close is called.
+func (s *service) maintain() {
+	defer close(s.done)
+	syncTicker := time.NewTicker(config.FileSyncInterval)
+	defer syncTicker.Stop()
+	compactTicker := time.NewTicker(config.FileCompactInterval)
+	defer compactTicker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-syncTicker.C:
+			s.flush()
+		case <-compactTicker.C:
+			s.compactIfStale()
+		}
+	}
+}
+
+// flush fsyncs the log if records were appended since it was last fsynced.
+func (s *service) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty || s.file == nil {
+		return
+	}
+	if err := s.file.Sync(); err != nil {
+		logger.Errorf("Error syncing file storage %s: %v", s.path, err)
+		return
+	}
+	s.dirty = false
+}
+
+// compactIfStale compacts the log once it holds at least config.FileCompactMinStale superseded
+// records and they outnumber the current ones.
+func (s *service) compactIfStale() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil || s.stale < config.FileCompactMinStale || s.stale < len(s.urls) {
+		return
+	}
+	if err := s.compact(); err != nil {
+		logger.Errorf("Error compacting file storage %s: %v", s.path, err)
+	}
+}
@@ -1,10 +1,16 @@
 // Package filecache implements the storage.Storage interface using a file-based system
-// to manage URL data. This package allows storing, retrieving, and deleting URLs directly on the filesystem.
+// to manage URL data. URLs are recorded in an append-only log file and served from an in-memory
+// index rebuilt from the log at startup, so that lookups never scan the file.
 package filecache
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/models"
@@ -16,72 +22,246 @@ import (
 
 // service implements the storage.Storage interface to provide file-based URL management.
 type service struct {
-	path string // path represents the file path where URL data is stored.
+	path     string // path represents the file path where URL data is stored.
+	syncMode string // syncMode is the fsync mode of the log: config.FileSyncAlways, config.FileSyncPeriodic or config.FileSyncNever.
+
+	mu        sync.RWMutex              // mu protects the index and the log file.
+	file      *os.File                  // file is the log opened for appending; nil once closed.
+	size      int64                     // size is the length of the log.
+	urls      map[string]models.URLData // urls holds the latest record of every short URL.
+	order     []string                  // order lists the short URLs in the order they were first saved.
+	originals map[string]string         // originals maps the original URL of every record, deleted or not, to its short URL.
+	stale     int                       // stale counts the records of the log superseded by a later one.
+	dirty     bool                      // dirty is set when records were appended since the log was last fsynced.
+
+	clicksMu sync.Mutex    // clicksMu serializes the reads of the clicks file with the appends to it.
+	stop     chan struct{} // stop is closed by Close to end the background maintenance.
+	done     chan struct{} // done is closed once the background maintenance has ended.
+	stopOnce sync.Once     // stopOnce closes stop once.
 }
 
-// NewFileStorage creates a new instance of a file-based storage service.
-// It accepts a file path where URL data will be stored and manipulated.
-func NewFileStorage(path string) storage.Storage {
-	return &service{
-		path: path,
+// NewFileStorage creates a file-based storage service recording URLs in the log at path, fsynced
+// according to syncMode. An existing log is replayed first; a torn last line left by a crash is
+// truncated away. It returns config.ErrUnknownFileSync for an unsupported sync mode and
+// config.ErrCorruptLog if another line of the log cannot be read.
+//
+// The log is fsynced and compacted in the background until Close is called.
+func NewFileStorage(path, syncMode string) (storage.Storage, error) {
+	if syncMode != config.FileSyncAlways && syncMode != config.FileSyncPeriodic && syncMode != config.FileSyncNever {
+		return nil, fmt.Errorf("%w: %q", config.ErrUnknownFileSync, syncMode)
 	}
+	s := &service{
+		path:      path,
+		syncMode:  syncMode,
+		urls:      make(map[string]models.URLData),
+		originals: make(map[string]string),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	go s.maintain()
+	return s, nil
 }
 
-// SaveUniqueURL saves a URL to the file and generates a unique short URL.
+// SaveUniqueURL saves a URL to the file and generates a unique short URL, or uses the requested
+// custom alias. As with the database storage, an original URL that is already recorded results in
+// its existing short URL with HTTP 409, and one that is recorded but marked as deleted is restored
+// for the new owner, keeping its short URL. A custom alias that is already recorded in the file
+// results in config.ErrAliasTaken with HTTP 409.
 // It returns the created short URL, an HTTP status code, and any error encountered.
-func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string) (string, int, error) {
-	shortURL := utils.GenerateShortPath()
-
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
 	uuid, err := uuid.Parse(userID)
 	if err != nil {
 		logger.Errorf("Error with parsing userID in file %v", err)
 		return "", http.StatusBadRequest, err
 	}
 
-	var save models.URLData
-	save.OriginalURL = originalURL
-	save.ShortURL = shortURL
-	save.UUID = uuid
-	save.DeletedFlag = false
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	err = utils.SaveURLs(save)
+	shortURL, err := s.save(uuid, originalURL, opts)
+	if errors.Is(err, config.ErrExists) {
+		return config.BaseURL + "/" + shortURL, http.StatusConflict, nil
+	}
+	if errors.Is(err, config.ErrAliasTaken) {
+		return "", http.StatusConflict, err
+	}
 	if err != nil {
-		return "", http.StatusBadRequest, err
+		logger.Errorf("Error with saving in file %v", err)
+		return "", http.StatusInternalServerError, err
 	}
 	return config.BaseURL + "/" + shortURL, http.StatusCreated, nil
 }
 
-// SaveURL saves a URL without ensuring uniqueness.
-func (s *service) SaveURL(ctx context.Context, originalURL string, userID string) (string, error) {
-	shortURL := utils.GenerateShortPath()
-
+// SaveURL performs the same operation as SaveUniqueURL without returning the HTTP status code.
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
 	uuid, err := uuid.Parse(userID)
 	if err != nil {
 		logger.Errorf("Error with parsing userID in file %v", err)
 		return "", err
 	}
 
-	var save models.URLData
-	save.OriginalURL = originalURL
-	save.ShortURL = shortURL
-	save.UUID = uuid
-	save.DeletedFlag = false
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	err = utils.SaveURLs(save)
-	if err != nil {
+	shortURL, err := s.save(uuid, originalURL, opts)
+	if err != nil && !errors.Is(err, config.ErrExists) {
 		logger.Errorf("Error with saving in file %v", err)
 		return "", err
 	}
 	return config.BaseURL + "/" + shortURL, nil
 }
 
+// save records originalURL for userID under the requested alias, or under a generated short path.
+// If the original URL is already recorded and not deleted, its short URL is returned with
+// config.ErrExists; if it is deleted, it is restored for userID under the requested alias, or under
+// its previous short URL when no alias is given. The caller must hold s.mu for writing.
+func (s *service) save(userID uuid.UUID, originalURL string, opts models.ShortenOptions) (string, error) {
+	record := models.URLData{
+		UUID:         userID,
+		OriginalURL:  originalURL,
+		ExpiresAt:    opts.ExpiresAt,
+		PasswordHash: opts.PasswordHash,
+	}
+	shortURL, exists := s.originals[originalURL]
+	switch {
+	case exists && !s.urls[shortURL].DeletedFlag:
+		return shortURL, config.ErrExists
+	case exists && (opts.CustomAlias == "" || opts.CustomAlias == shortURL):
+		record.ShortURL = shortURL
+	default:
+		shortURL, err := s.newShortURL(opts.CustomAlias)
+		if err != nil {
+			return "", err
+		}
+		record.ShortURL = shortURL
+	}
+	if err := s.append(record); err != nil {
+		return "", err
+	}
+	return record.ShortURL, nil
+}
+
+// SaveURLsBatch saves several URLs to the file with a single append.
+// Original URLs already recorded and not deleted are reported as existing instead of being saved again,
+// and those recorded but marked as deleted are restored for the user, under the requested alias if any.
+// An atomic batch with a failed item leaves the file unchanged.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	uuid, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Errorf("Error with parsing userID in file %v", err)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := make(map[string]string)
+	deleted := make(map[string]string)
+	for _, item := range items {
+		shortURL, exists := s.originals[item.OriginalURL]
+		switch {
+		case !exists:
+		case s.urls[shortURL].DeletedFlag:
+			deleted[item.OriginalURL] = shortURL
+		default:
+			existing[item.OriginalURL] = shortURL
+		}
+	}
+
+	records, results := utils.PlanURLBatch(uuid, items, existing, deleted, s.taken)
+	if atomic && utils.AbortURLBatch(records, results) {
+		return results, nil
+	}
+	if err := s.append(records...); err != nil {
+		logger.Errorf("Error with saving in file %v", err)
+		return nil, err
+	}
+	return results, nil
+}
+
+// newShortURL returns the requested alias if it is not yet recorded in the file, or
+// config.ErrAliasTaken if it is. Without an alias a random short path that is not recorded
+// in the file is generated. The caller must hold s.mu.
+func (s *service) newShortURL(alias string) (string, error) {
+	if alias != "" {
+		if s.taken(alias) {
+			return "", config.ErrAliasTaken
+		}
+		return alias, nil
+	}
+	for {
+		if shortURL := utils.GenerateShortPath(); !s.taken(shortURL) {
+			return shortURL, nil
+		}
+	}
+}
+
+// taken reports whether a short URL is recorded in the file, deleted or not.
+// The caller must hold s.mu.
+func (s *service) taken(shortURL string) bool {
+	_, exists := s.urls[shortURL]
+	return exists
+}
+
+// lookup returns the record of a short URL that still redirects. Unknown short URLs are reported
+// as config.ErrNotFound, deleted ones as config.ErrGone and expired ones as config.ErrExpired.
+func (s *service) lookup(shortURL string) (models.URLData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	urlData, exists := s.urls[shortURL]
+	switch {
+	case !exists:
+		return models.URLData{}, config.ErrNotFound
+	case urlData.DeletedFlag:
+		return models.URLData{}, config.ErrGone
+	case urlData.Expired(time.Now()):
+		return models.URLData{}, config.ErrExpired
+	}
+	return urlData, nil
+}
+
 // GetOriginalLink retrieves the original URL from the file for a given short URL.
+// Password protected links are reported as config.ErrPasswordRequired.
 func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
-	originalURL, err := utils.LoadURLs(s.path, shortURL)
+	urlData, err := s.lookup(shortURL)
 	if err != nil {
 		return "", err
 	}
-	return originalURL, nil
+	if urlData.PasswordHash != "" {
+		return "", config.ErrPasswordRequired
+	}
+	return urlData.OriginalURL, nil
+}
+
+// GetExpiringLink retrieves the original URL and expiration time from the file for a given short URL.
+// Password protected links are reported as config.ErrPasswordRequired.
+func (s *service) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	urlData, err := s.lookup(shortURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if urlData.PasswordHash != "" {
+		return "", nil, config.ErrPasswordRequired
+	}
+	return urlData.OriginalURL, urlData.ExpiresAt, nil
+}
+
+// GetProtectedLink retrieves the original URL and password hash from the file for a given short URL.
+func (s *service) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	urlData, err := s.lookup(shortURL)
+	if err != nil {
+		return "", "", err
+	}
+	return urlData.OriginalURL, urlData.PasswordHash, nil
 }
 
 // Ping simulates a connectivity test to the storage. Since this is a file-based system,
@@ -91,23 +271,178 @@ func (s *service) Ping(ctx context.Context) (int, error) {
 	return http.StatusInternalServerError, config.ErrWrongMode
 }
 
-// Close is a placeholder to satisfy the storage.Storage interface. It performs no operation.
+// Close stops the background maintenance, fsyncs the log and closes it.
 func (s *service) Close() error {
-	return nil
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := errors.Join(s.file.Sync(), s.file.Close())
+	s.file = nil
+	return err
 }
 
 // GetAllURLS retrieves all URLs associated with a user ID.
 func (s *service) GetAllURLS(ctx context.Context, userID, baseURL string) ([]models.UserURLs, error) {
-	res, err := utils.LoadUserURLs(config.BaseFilePath, userID)
-	if err != nil {
-		logger.Errorf("Failed to get all user URLs %v", err)
-		return nil, err
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var urls []models.UserURLs
+	for _, shortURL := range s.order {
+		urlData := s.urls[shortURL]
+		if urlData.UUID.String() == userID && !urlData.DeletedFlag {
+			urls = append(urls, models.UserURLs{
+				ShortURL:    baseURL + "/" + urlData.ShortURL,
+				OriginalURL: urlData.OriginalURL,
+			})
+		}
 	}
-	return res, nil
+	return urls, nil
 }
 
-// MarkURLsAsDeleted marks specified URLs as deleted in the file system for a given user ID.
+// MarkURLsAsDeleted marks specified URLs as deleted for a given user ID by appending their tombstones to the file.
 func (s *service) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
-	err := utils.MarkURLsAsDeletedInFile(config.BaseFilePath, userID, shortURLs)
-	return err
+	deletions := make([]models.URLDeletion, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		deletions[i] = models.URLDeletion{UserID: userID, ShortURL: shortURL}
+	}
+	return s.MarkURLsAsDeletedBatch(ctx, deletions)
+}
+
+// MarkURLsAsDeletedBatch marks the URLs of several users as deleted by appending their tombstones
+// to the file with a single write. URLs of other users and URLs already deleted are left alone.
+func (s *service) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tombstones []models.URLData
+	marked := make(map[string]bool, len(deletions))
+	for _, deletion := range deletions {
+		urlData, exists := s.urls[deletion.ShortURL]
+		if !exists || urlData.DeletedFlag || marked[deletion.ShortURL] || urlData.UUID.String() != deletion.UserID {
+			continue
+		}
+		urlData.DeletedFlag = true
+		tombstones = append(tombstones, urlData)
+		marked[deletion.ShortURL] = true
+	}
+	return s.append(tombstones...)
+}
+
+// MarkExpiredURLsAsDeleted marks every URL whose expiration time has passed as deleted
+// by appending their tombstones to the file.
+func (s *service) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var tombstones []models.URLData
+	for _, shortURL := range s.order {
+		urlData := s.urls[shortURL]
+		if !urlData.DeletedFlag && urlData.Expired(now) {
+			urlData.DeletedFlag = true
+			tombstones = append(tombstones, urlData)
+		}
+	}
+	if err := s.append(tombstones...); err != nil {
+		return 0, err
+	}
+	return len(tombstones), nil
+}
+
+// SaveClicks appends a batch of redirect events to the clicks file next to the URL file.
+func (s *service) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+	return utils.SaveClicks(s.clicksPath(), clicks)
+}
+
+// GetURLStats aggregates the redirect events of a short URL owned by the given user.
+func (s *service) GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error) {
+	s.mu.RLock()
+	urlData, exists := s.urls[shortURL]
+	s.mu.RUnlock()
+	if !exists {
+		return models.URLStats{}, config.ErrNotFound
+	}
+	if urlData.UUID.String() != userID {
+		return models.URLStats{}, config.ErrForbidden
+	}
+	s.clicksMu.Lock()
+	clicks, err := utils.LoadClicks(s.clicksPath(), shortURL)
+	s.clicksMu.Unlock()
+	if err != nil {
+		logger.Errorf("Failed to load clicks %v", err)
+		return models.URLStats{}, err
+	}
+	return utils.AggregateClicks(shortURL, clicks), nil
+}
+
+// CountURLs returns the number of URLs in the file that are not marked as deleted.
+func (s *service) CountURLs(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	urls := 0
+	for _, urlData := range s.urls {
+		if !urlData.DeletedFlag {
+			urls++
+		}
+	}
+	return urls, nil
+}
+
+// CountUsers returns the number of distinct users owning a URL in the file that is not marked as deleted.
+func (s *service) CountUsers(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make(map[uuid.UUID]struct{})
+	for _, urlData := range s.urls {
+		if !urlData.DeletedFlag {
+			users[urlData.UUID] = struct{}{}
+		}
+	}
+	return len(users), nil
+}
+
+// ExportURLs calls fn for the latest record of every URL in the file, in the order they were saved.
+// The records are collected first, so that fn does not hold up the other operations.
+func (s *service) ExportURLs(ctx context.Context, fn func(models.URLData) error) error {
+	s.mu.RLock()
+	records := make([]models.URLData, len(s.order))
+	for i, shortURL := range s.order {
+		records[i] = s.urls[shortURL]
+	}
+	s.mu.RUnlock()
+
+	for _, record := range records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportURL appends a URL record to the file as is, rejecting short and original URLs that are already present.
+func (s *service) ImportURL(ctx context.Context, data models.URLData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.taken(data.ShortURL) {
+		return config.ErrAliasTaken
+	}
+	if _, exists := s.originals[data.OriginalURL]; exists {
+		return config.ErrExists
+	}
+	return s.append(data)
+}
+
+// clicksPath returns the path of the file holding redirect events, derived from the URL file path.
+func (s *service) clicksPath() string {
+	return s.path + ".clicks"
 }
@@ -0,0 +1,196 @@
+package filecache_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/filecache"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUser = "0a6ed83c-7d2e-4bfb-9e2f-0b5ab1f7e5a4"
+
+// openStorage opens the file storage at path, closing it when the test ends.
+func openStorage(t *testing.T, path string) storage.Storage {
+	t.Helper()
+	store, err := filecache.NewFileStorage(path, config.FileSyncAlways)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// saveURL saves originalURL under alias for testUser.
+func saveURL(t *testing.T, store storage.Storage, originalURL, alias string) {
+	t.Helper()
+	_, err := store.SaveURL(context.Background(), originalURL, testUser, models.ShortenOptions{CustomAlias: alias})
+	require.NoError(t, err)
+}
+
+// countLines returns the number of lines of the file at path.
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestFileStorageReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.json")
+
+	store := openStorage(t, path)
+	saveURL(t, store, "https://example.com/a", "aaa")
+	saveURL(t, store, "https://example.com/b", "bbb")
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, testUser, []string{"aaa"}))
+	// Deleting URLs of another user or URLs already deleted appends nothing.
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, uuid.NewString(), []string{"bbb"}))
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, testUser, []string{"aaa"}))
+	require.NoError(t, store.Close())
+
+	// The deletion is recorded as a tombstone after the record.
+	assert.Equal(t, 3, countLines(t, path))
+
+	store = openStorage(t, path)
+	_, err := store.GetOriginalLink(ctx, "aaa")
+	assert.ErrorIs(t, err, config.ErrGone)
+	originalURL, err := store.GetOriginalLink(ctx, "bbb")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", originalURL)
+	_, err = store.GetOriginalLink(ctx, "ccc")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+
+	urls, err := store.GetAllURLS(ctx, testUser, "http://localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserURLs{{ShortURL: "http://localhost:8080/bbb", OriginalURL: "https://example.com/b"}}, urls)
+
+	// Deleted aliases stay taken.
+	_, err = store.SaveURL(ctx, "https://example.com/c", testUser, models.ShortenOptions{CustomAlias: "aaa"})
+	assert.ErrorIs(t, err, config.ErrAliasTaken)
+}
+
+func TestFileStorageRecovery(t *testing.T) {
+	record := `{"UUID":"` + testUser + `","ShortURL":"aaa","OriginalURL":"https://example.com/a","DeletedFlag":false}` + "\n"
+
+	tests := []struct {
+		name         string
+		content      string
+		expectedErr  error
+		expectedFile string
+	}{
+		{
+			name:         "Torn Last Line Is Truncated",
+			content:      record + `{"UUID":"` + testUser + `","ShortURL":"bb`,
+			expectedFile: record,
+		},
+		{
+			name:         "Complete Log Is Kept",
+			content:      record,
+			expectedFile: record,
+		},
+		{
+			name:        "Corrupt Line Is Reported",
+			content:     "not json\n" + record,
+			expectedErr: config.ErrCorruptLog,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "urls.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			store, err := filecache.NewFileStorage(path, config.FileSyncNever)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			defer store.Close()
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedFile, string(data))
+
+			// New records start on a line of their own.
+			saveURL(t, store, "https://example.com/b", "bbb")
+			originalURL, err := store.GetOriginalLink(context.Background(), "aaa")
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/a", originalURL)
+		})
+	}
+}
+
+func TestFileStorageTornClicks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.json")
+	store := openStorage(t, path)
+	saveURL(t, store, "https://example.com/a", "aaa")
+	require.NoError(t, store.SaveClicks(ctx, []models.Click{{ShortURL: "aaa"}}))
+
+	// Simulate a crash in the middle of appending an event.
+	file, err := os.OpenFile(path+".clicks", os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"ShortURL":"aa`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	stats, err := store.GetURLStats(ctx, testUser, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalClicks)
+
+	// Later events start on a line of their own.
+	require.NoError(t, store.SaveClicks(ctx, []models.Click{{ShortURL: "aaa"}}))
+	stats, err = store.GetURLStats(ctx, testUser, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.TotalClicks)
+}
+
+func TestFileStorageCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.json")
+
+	store := openStorage(t, path)
+	saveURL(t, store, "https://example.com/a", "aaa")
+	saveURL(t, store, "https://example.com/b", "bbb")
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, testUser, []string{"aaa"}))
+	assert.Equal(t, 3, countLines(t, path))
+
+	require.NoError(t, filecache.Compact(store))
+	assert.Equal(t, 2, countLines(t, path))
+	matches, err := filepath.Glob(path + ".*.tmp")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	// The storage keeps appending to the compacted log.
+	saveURL(t, store, "https://example.com/c", "ccc")
+	require.NoError(t, store.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"ShortURL":"aaa"`)
+	assert.Contains(t, lines[0], `"DeletedFlag":true`)
+	assert.Contains(t, lines[2], `"ShortURL":"ccc"`)
+
+	store = openStorage(t, path)
+	_, err = store.GetOriginalLink(ctx, "aaa")
+	assert.ErrorIs(t, err, config.ErrGone)
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestNewFileStorageUnknownSync(t *testing.T) {
+	_, err := filecache.NewFileStorage(filepath.Join(t.TempDir(), "urls.json"), "sometimes")
+	assert.ErrorIs(t, err, config.ErrUnknownFileSync)
+}
@@ -0,0 +1,23 @@
+package inmemory_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/inmemory"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/storagetest"
+)
+
+func TestMemoryStorageConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return inmemory.NewMemoryStorage(make(map[string]models.URLData))
+	})
+}
+
+func TestPersistentMemoryStorageConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return openStorage(t, filepath.Join(t.TempDir(), "urls.snapshot"))
+	})
+}
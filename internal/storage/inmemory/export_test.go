@@ -0,0 +1,9 @@
+package inmemory
+
+import "github.com/gleb-korostelev/short-url.git/internal/storage"
+
+// Snapshot saves a snapshot of a persistent in-memory storage at once, as the background
+// maintenance does every snapshot interval.
+func Snapshot(store storage.Storage) error {
+	return store.(*service).snapshot()
+}
@@ -1,12 +1,17 @@
 // Package inmemory implements the storage.Storage interface using an in-memory data store.
-// It provides fast access to URL data stored directly in memory, suitable for scenarios
-// where persistence across service restarts is not required.
+// It provides fast access to URL data stored directly in memory. The data is lost on restart
+// unless the storage is opened with a snapshot file: it is then saved to the snapshot
+// periodically and on Close, and the changes made in between are kept in a write-ahead journal.
 package inmemory
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"net/http"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/models"
@@ -18,85 +23,265 @@ import (
 
 // service provides an in-memory storage mechanism for URL data.
 // It uses a map to store URL data, keyed by short URL strings, and a mutex to manage concurrent access.
+// Every change goes through commit, which also journals it if the storage has a snapshot file.
 type service struct {
-	cache map[string]models.URLData // cache stores the URL data in-memory.
-	mu    sync.RWMutex              // mu protects the cache from concurrent read/write access.
+	cache     map[string]models.URLData // cache stores the URL data in-memory.
+	originals map[string]string         // originals maps every original URL in the cache, deleted or not, to its short URL.
+	clicks    map[string][]models.Click // clicks stores the redirect events keyed by short URL.
+	mu        sync.RWMutex              // mu protects the fields below from concurrent read/write access.
+
+	seq     uint64        // seq is the number of the last change.
+	snapSeq uint64        // snapSeq is the number of the last change saved in the snapshot.
+	journal *os.File      // journal is opened for appending, nil if memory only or closed.
+	writer  *bufio.Writer // writer buffers the entries appended to the journal.
+	size    int64         // size is the length of the journal, buffered entries included.
+	dirty   bool          // dirty is set when entries were appended since the journal was last fsynced.
+
+	path     string        // path is the snapshot file; empty keeps the data in memory only.
+	interval time.Duration // interval is the time between two periodic snapshots; zero snapshots on Close only.
+	window   time.Duration // window is how long an entry may stay unsynced in the journal; zero fsyncs every entry.
+	snapMu   sync.Mutex    // snapMu serializes snapshots.
+	stop     chan struct{} // stop is closed by Close to end the background maintenance.
+	done     chan struct{} // done is closed once the background maintenance has ended.
+	stopOnce sync.Once     // stopOnce closes stop once.
 }
 
-// NewMemoryStorage initializes a new in-memory storage service with a given initial cache.
+// NewMemoryStorage initializes a new in-memory storage service with a given initial cache,
+// which it takes ownership of. Its data is lost when the process exits.
 func NewMemoryStorage(cache map[string]models.URLData) storage.Storage {
+	originals := make(map[string]string, len(cache))
+	for shortURL, info := range cache {
+		originals[info.OriginalURL] = shortURL
+	}
 	return &service{
-		cache: cache,
+		cache:     cache,
+		originals: originals,
+		clicks:    make(map[string][]models.Click),
 	}
 }
 
+// OpenMemoryStorage creates an in-memory storage persisted to the snapshot file at path, which is
+// rewritten every interval and on Close. Changes made between two snapshots are appended to a
+// journal named after path with config.SnapshotJournalSuffix and fsynced every window, so that a
+// crash loses at most the changes of the last window; a zero window fsyncs every change.
+//
+// An existing snapshot is loaded first and the journal entries made after it are replayed; a torn
+// last line left by a crash is truncated away. It returns config.ErrCorruptSnapshot if the
+// snapshot or another line of the journal cannot be read.
+func OpenMemoryStorage(path string, interval, window time.Duration) (storage.Storage, error) {
+	s := &service{
+		cache:     make(map[string]models.URLData),
+		originals: make(map[string]string),
+		clicks:    make(map[string][]models.Click),
+		path:      path,
+		interval:  interval,
+		window:    window,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	journal, err := os.OpenFile(s.journalPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.journal = journal
+	s.writer = bufio.NewWriter(journal)
+	go s.maintain()
+	return s, nil
+}
+
 // SaveUniqueURL saves a new URL into the in-memory storage, ensuring the short URL is unique.
-// It generates a short URL, checks for uniqueness within the existing entries, and saves the URL data.
+// It generates a short URL (or uses the requested custom alias), checks for uniqueness within the
+// existing entries, and saves the URL data. As with the database storage, an original URL that is
+// already stored results in its existing short URL with HTTP 409, and one that is stored but marked
+// as deleted is restored for the new owner, keeping its short URL. A custom alias that is already in
+// use results in config.ErrAliasTaken with HTTP 409.
 // Returns the complete URL, HTTP status code, and error if any.
-func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string) (string, int, error) {
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
 	uuid, err := uuid.Parse(userID)
 	if err != nil {
 		logger.Errorf("Error with parsing userId in memory %v", err)
 		return "", http.StatusBadRequest, err
 	}
 
-	shortURL := utils.GenerateShortPath()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for _, exists := s.cache[shortURL]; exists; {
-		shortURL = utils.GenerateShortPath()
+	shortURL, err := s.save(uuid, originalURL, opts)
+	if errors.Is(err, config.ErrExists) {
+		return config.BaseURL + "/" + shortURL, http.StatusConflict, nil
+	}
+	if errors.Is(err, config.ErrAliasTaken) {
+		return "", http.StatusConflict, err
+	}
+	if err != nil {
+		return "", http.StatusInternalServerError, err
 	}
-
-	var data models.URLData
-	data.ShortURL = shortURL
-	data.OriginalURL = originalURL
-	data.UUID = uuid
-	data.DeletedFlag = false
-	s.cache[data.ShortURL] = data
-
-	s.mu.RLock()
-	defer s.mu.RUnlock()
 	return config.BaseURL + "/" + shortURL, http.StatusCreated, nil
 }
 
 // SaveURL performs a similar operation to SaveUniqueURL but does not return an HTTP status.
-func (s *service) SaveURL(ctx context.Context, originalURL string, userID string) (string, error) {
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
 	uuid, err := uuid.Parse(userID)
 	if err != nil {
 		logger.Errorf("Error with parsing userId in memory %v", err)
 		return "", err
 	}
 
-	shortURL := utils.GenerateShortPath()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for _, exists := s.cache[shortURL]; exists; {
-		shortURL = utils.GenerateShortPath()
+	shortURL, err := s.save(uuid, originalURL, opts)
+	if err != nil && !errors.Is(err, config.ErrExists) {
+		return "", err
 	}
+	return config.BaseURL + "/" + shortURL, nil
+}
 
-	var data models.URLData
-	data.ShortURL = shortURL
-	data.OriginalURL = originalURL
-	data.UUID = uuid
-	data.DeletedFlag = false
-	s.cache[data.ShortURL] = data
+// save stores originalURL for userID under the requested alias, or under a generated short path.
+// If the original URL is already stored and not deleted, its short URL is returned with
+// config.ErrExists; if it is deleted, it is restored for userID under the requested alias, or under
+// its previous short URL when no alias is given. The caller must hold s.mu for writing.
+func (s *service) save(userID uuid.UUID, originalURL string, opts models.ShortenOptions) (string, error) {
+	data := models.URLData{
+		UUID:         userID,
+		OriginalURL:  originalURL,
+		ExpiresAt:    opts.ExpiresAt,
+		PasswordHash: opts.PasswordHash,
+	}
+	shortURL, exists := s.originals[originalURL]
+	switch {
+	case exists && !s.cache[shortURL].DeletedFlag:
+		return shortURL, config.ErrExists
+	case exists && (opts.CustomAlias == "" || opts.CustomAlias == shortURL):
+		data.ShortURL = shortURL
+	default:
+		shortURL, err := s.newShortURL(opts.CustomAlias)
+		if err != nil {
+			return "", err
+		}
+		data.ShortURL = shortURL
+	}
+	if err := s.commit([]models.URLData{data}, nil); err != nil {
+		return "", err
+	}
+	return data.ShortURL, nil
+}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return config.BaseURL + "/" + shortURL, nil
+// SaveURLsBatch saves several URLs into the in-memory storage under a single lock.
+// An atomic batch with a failed item leaves the storage unchanged.
+// Original URLs already stored and not deleted are reported as existing instead of being saved again,
+// and those stored but marked as deleted are restored for the user, under the requested alias if any.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	uuid, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Errorf("Error with parsing userId in memory %v", err)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := make(map[string]string)
+	deleted := make(map[string]string)
+	for _, item := range items {
+		shortURL, exists := s.originals[item.OriginalURL]
+		switch {
+		case !exists:
+		case s.cache[shortURL].DeletedFlag:
+			deleted[item.OriginalURL] = shortURL
+		default:
+			existing[item.OriginalURL] = shortURL
+		}
+	}
+
+	records, results := utils.PlanURLBatch(uuid, items, existing, deleted, func(shortURL string) bool {
+		_, exists := s.cache[shortURL]
+		return exists
+	})
+	if atomic && utils.AbortURLBatch(records, results) {
+		return results, nil
+	}
+	if err := s.commit(records, nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// newShortURL picks the short URL for a new entry. A non-empty alias is returned as is unless it
+// is already present in the cache, in which case config.ErrAliasTaken is returned. Otherwise a
+// random short path not yet present in the cache is generated. The caller must hold s.mu.
+func (s *service) newShortURL(alias string) (string, error) {
+	if alias != "" {
+		if _, exists := s.cache[alias]; exists {
+			return "", config.ErrAliasTaken
+		}
+		return alias, nil
+	}
+
+	shortURL := utils.GenerateShortPath()
+	for _, exists := s.cache[shortURL]; exists; _, exists = s.cache[shortURL] {
+		shortURL = utils.GenerateShortPath()
+	}
+	return shortURL, nil
 }
 
-// GetOriginalLink retrieves the original URL from a given short URL, checking if it's marked as deleted.
+// GetOriginalLink retrieves the original URL from a given short URL, checking if it's marked as deleted,
+// expired or password protected.
 func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
+	originalURL, passwordHash, err := s.GetProtectedLink(ctx, shortURL)
+	if err != nil {
+		return "", err
+	}
+	if passwordHash != "" {
+		return "", config.ErrPasswordRequired
+	}
+	return originalURL, nil
+}
+
+// GetExpiringLink retrieves the original URL and expiration time of a given short URL, checking if it's
+// marked as deleted, expired or password protected.
+func (s *service) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	foundCache, err := s.lookup(shortURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if foundCache.PasswordHash != "" {
+		return "", nil, config.ErrPasswordRequired
+	}
+	return foundCache.OriginalURL, foundCache.ExpiresAt, nil
+}
+
+// GetProtectedLink retrieves the original URL and password hash of a given short URL, checking if it's
+// marked as deleted or expired.
+func (s *service) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	foundCache, err := s.lookup(shortURL)
+	if err != nil {
+		return "", "", err
+	}
+	return foundCache.OriginalURL, foundCache.PasswordHash, nil
+}
+
+// lookup returns the entry of a short URL that is neither deleted nor expired.
+func (s *service) lookup(shortURL string) (models.URLData, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	foundCache, exists := s.cache[shortURL]
 	if !exists {
-		return "", config.ErrNotFound
+		return models.URLData{}, config.ErrNotFound
 	}
 	if foundCache.DeletedFlag {
-		return "", config.ErrGone
+		return models.URLData{}, config.ErrGone
 	}
-	return foundCache.OriginalURL, nil
-
+	if foundCache.Expired(time.Now()) {
+		return models.URLData{}, config.ErrExpired
+	}
+	return foundCache, nil
 }
 
 // Ping checks the operation status of the in-memory storage, typically returning an error as it does not involve connectivity.
@@ -105,9 +290,32 @@ func (s *service) Ping(ctx context.Context) (int, error) {
 	return http.StatusInternalServerError, config.ErrWrongMode
 }
 
-// Close performs cleanup if necessary; in this implementation, it is a no-operation.
+// Close saves a final snapshot and closes the journal of a persistent storage; for a storage kept
+// in memory only it is a no-operation. If the snapshot fails, the journal is still fsynced so that
+// the changes are restored on the next start.
 func (s *service) Close() error {
-	return nil
+	if s.path == "" {
+		return nil
+	}
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+
+	err := s.snapshot()
+	if errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.journal == nil {
+		return err
+	}
+	if err != nil {
+		err = errors.Join(err, s.sync())
+	}
+	err = errors.Join(err, s.journal.Close())
+	s.journal = nil
+	return err
 }
 
 // GetAllURLs retrieves all URLs associated with a specific user ID, filtering out deleted entries.
@@ -129,15 +337,130 @@ func (s *service) GetAllURLS(ctx context.Context, userID, baseURL string) ([]mod
 
 // MarkURLsAsDeleted marks specified URLs as deleted for a given user ID.
 func (s *service) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted []models.URLData
 	for _, info := range s.cache {
 		if info.UUID.String() == userID && utils.CheckURL(info.ShortURL, shortURLs) {
 			info.DeletedFlag = true
-			s.cache[info.ShortURL] = info
+			deleted = append(deleted, info)
+		}
+	}
+	return s.commit(deleted, nil)
+}
+
+// MarkURLsAsDeletedBatch marks the URLs of several users as deleted.
+func (s *service) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted []models.URLData
+	for _, deletion := range deletions {
+		info, ok := s.cache[deletion.ShortURL]
+		if ok && info.UUID.String() == deletion.UserID {
+			info.DeletedFlag = true
+			deleted = append(deleted, info)
+		}
+	}
+	return s.commit(deleted, nil)
+}
+
+// MarkExpiredURLsAsDeleted marks every URL whose expiration time has passed as deleted.
+func (s *service) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var expired []models.URLData
+	for _, info := range s.cache {
+		if !info.DeletedFlag && info.Expired(now) {
+			info.DeletedFlag = true
+			expired = append(expired, info)
+		}
+	}
+	if err := s.commit(expired, nil); err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+// SaveClicks appends a batch of redirect events to the in-memory click log.
+func (s *service) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit(nil, clicks)
+}
+
+// GetURLStats aggregates the redirect events of a short URL owned by the given user.
+func (s *service) GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, exists := s.cache[shortURL]
+	if !exists {
+		return models.URLStats{}, config.ErrNotFound
+	}
+	if info.UUID.String() != userID {
+		return models.URLStats{}, config.ErrForbidden
+	}
+	return utils.AggregateClicks(shortURL, s.clicks[shortURL]), nil
+}
+
+// CountURLs returns the number of URLs in the cache that are not marked as deleted.
+func (s *service) CountURLs(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, info := range s.cache {
+		if !info.DeletedFlag {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountUsers returns the number of distinct users owning a URL in the cache that is not marked as deleted.
+func (s *service) CountUsers(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make(map[string]struct{})
+	for _, info := range s.cache {
+		if !info.DeletedFlag {
+			users[info.UUID.String()] = struct{}{}
 		}
+	}
+	return len(users), nil
+}
 
+// ExportURLs calls fn for a snapshot of every URL record in the cache.
+// The snapshot is taken under the read lock so fn may call back into the storage.
+func (s *service) ExportURLs(ctx context.Context, fn func(models.URLData) error) error {
+	s.mu.RLock()
+	records := make([]models.URLData, 0, len(s.cache))
+	for _, info := range s.cache {
+		records = append(records, info)
 	}
+	s.mu.RUnlock()
 
+	for _, record := range records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// ImportURL stores a URL record in the cache as is, rejecting short and original URLs that are already present.
+func (s *service) ImportURL(ctx context.Context, data models.URLData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.cache[data.ShortURL]; exists {
+		return config.ErrAliasTaken
+	}
+	if _, exists := s.originals[data.OriginalURL]; exists {
+		return config.ErrExists
+	}
+	return s.commit([]models.URLData{data}, nil)
+}
@@ -0,0 +1,164 @@
+package inmemory_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUser = "0a6ed83c-7d2e-4bfb-9e2f-0b5ab1f7e5a4"
+
+// openStorage opens the in-memory storage snapshotted at path, fsyncing every change and taking
+// snapshots on demand only, and closes it when the test ends.
+func openStorage(t *testing.T, path string) storage.Storage {
+	t.Helper()
+	store, err := inmemory.OpenMemoryStorage(path, 0, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// saveURL saves originalURL under alias for testUser.
+func saveURL(t *testing.T, store storage.Storage, originalURL, alias string) {
+	t.Helper()
+	_, err := store.SaveURL(context.Background(), originalURL, testUser, models.ShortenOptions{CustomAlias: alias})
+	require.NoError(t, err)
+}
+
+// saveClick records a redirect to shortURL.
+func saveClick(t *testing.T, store storage.Storage, shortURL string) {
+	t.Helper()
+	require.NoError(t, store.SaveClicks(context.Background(), []models.Click{{ShortURL: shortURL, ClickedAt: time.Now()}}))
+}
+
+// totalClicks returns the number of redirects recorded for shortURL.
+func totalClicks(t *testing.T, store storage.Storage, shortURL string) int64 {
+	t.Helper()
+	stats, err := store.GetURLStats(context.Background(), testUser, shortURL)
+	require.NoError(t, err)
+	return stats.TotalClicks
+}
+
+func TestMemoryStorageSnapshotOnClose(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.snapshot")
+
+	store := openStorage(t, path)
+	saveURL(t, store, "https://example.com/a", "aaa")
+	saveURL(t, store, "https://example.com/b", "bbb")
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, testUser, []string{"aaa"}))
+	saveClick(t, store, "bbb")
+	saveClick(t, store, "bbb")
+	require.NoError(t, store.Close())
+
+	// The snapshot holds every change, so the journal is left empty.
+	info, err := os.Stat(path + config.SnapshotJournalSuffix)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	store = openStorage(t, path)
+	_, err = store.GetOriginalLink(ctx, "aaa")
+	assert.ErrorIs(t, err, config.ErrGone)
+	originalURL, err := store.GetOriginalLink(ctx, "bbb")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", originalURL)
+	assert.Equal(t, int64(2), totalClicks(t, store, "bbb"))
+
+	// Deleted aliases stay taken.
+	_, err = store.SaveURL(ctx, "https://example.com/c", testUser, models.ShortenOptions{CustomAlias: "aaa"})
+	assert.ErrorIs(t, err, config.ErrAliasTaken)
+}
+
+func TestMemoryStorageCrashRecovery(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.snapshot")
+	journal := path + config.SnapshotJournalSuffix
+
+	// The first storage is never closed, as if the process crashed.
+	crashed := openStorage(t, path)
+	saveURL(t, crashed, "https://example.com/a", "aaa")
+	require.NoError(t, inmemory.Snapshot(crashed))
+	saveURL(t, crashed, "https://example.com/b", "bbb")
+	saveClick(t, crashed, "bbb")
+
+	// A change cut short by the crash leaves a torn last line, which is truncated away.
+	complete, err := os.ReadFile(journal)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(journal, append(complete, `{"seq":4,"urls":[{"Short`...), 0644))
+
+	store := openStorage(t, path)
+	for shortURL, expected := range map[string]string{"aaa": "https://example.com/a", "bbb": "https://example.com/b"} {
+		originalURL, err := store.GetOriginalLink(ctx, shortURL)
+		require.NoError(t, err)
+		assert.Equal(t, expected, originalURL)
+	}
+	assert.Equal(t, int64(1), totalClicks(t, store, "bbb"))
+	data, err := os.ReadFile(journal)
+	require.NoError(t, err)
+	assert.Equal(t, string(complete), string(data))
+}
+
+func TestMemoryStorageUntrimmedJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.snapshot")
+	journal := path + config.SnapshotJournalSuffix
+
+	crashed := openStorage(t, path)
+	saveURL(t, crashed, "https://example.com/a", "aaa")
+	saveClick(t, crashed, "aaa")
+	untrimmed, err := os.ReadFile(journal)
+	require.NoError(t, err)
+	require.NoError(t, inmemory.Snapshot(crashed))
+
+	// A crash right after the snapshot was saved leaves the entries it holds in the journal;
+	// they are skipped instead of being applied twice.
+	require.NoError(t, os.WriteFile(journal, untrimmed, 0644))
+
+	store := openStorage(t, path)
+	assert.Equal(t, int64(1), totalClicks(t, store, "aaa"))
+}
+
+func TestOpenMemoryStorageCorrupt(t *testing.T) {
+	entry := `{"seq":1,"urls":[{"UUID":"` + testUser + `","ShortURL":"aaa","OriginalURL":"https://example.com/a","DeletedFlag":false}]}` + "\n"
+
+	tests := []struct {
+		name     string
+		snapshot string
+		journal  string
+	}{
+		{
+			name:     "Snapshot Is Not Gzip",
+			snapshot: `{"seq":0,"urls":[],"clicks":[]}`,
+		},
+		{
+			name:    "Corrupt Journal Line",
+			journal: "not json\n" + entry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "urls.snapshot")
+			if tt.snapshot != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tt.snapshot), 0644))
+			}
+			if tt.journal != "" {
+				require.NoError(t, os.WriteFile(path+config.SnapshotJournalSuffix, []byte(tt.journal), 0644))
+			}
+
+			_, err := inmemory.OpenMemoryStorage(path, 0, 0)
+			assert.ErrorIs(t, err, config.ErrCorruptSnapshot)
+		})
+	}
+}
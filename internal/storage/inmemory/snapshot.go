@@ -0,0 +1,313 @@
+package inmemory
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/fsutil"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// A persistent storage keeps two files: a gzip'd JSON snapshot of the whole state and, beside it,
+// a write-ahead journal of the changes made since. Every change is numbered and appended to the
+// journal as a JSON line before it is applied; the snapshot records the number of the last change
+// it contains. Loading the snapshot and replaying the later journal entries restores the state,
+// so a snapshot that is written but whose journal was not trimmed yet is harmless.
+
+// snapshotState is the content of the snapshot file.
+type snapshotState struct {
+	Seq    uint64           `json:"seq"`    // Seq is the number of the last change the snapshot contains.
+	URLs   []models.URLData `json:"urls"`   // URLs are the records of every short URL.
+	Clicks []models.Click   `json:"clicks"` // Clicks are the recorded redirect events.
+}
+
+// journalEntry is a change recorded in the journal: the new records of some short URLs and the
+// redirect events to append.
+type journalEntry struct {
+	Seq    uint64           `json:"seq"`              // Seq numbers the change, starting after the snapshot's.
+	URLs   []models.URLData `json:"urls,omitempty"`   // URLs replace the records of their short URLs.
+	Clicks []models.Click   `json:"clicks,omitempty"` // Clicks are appended to the recorded events.
+}
+
+// journalPath returns the path of the journal beside the snapshot.
+func (s *service) journalPath() string {
+	return s.path + config.SnapshotJournalSuffix
+}
+
+// load reads the snapshot file, if it exists. It returns config.ErrCorruptSnapshot if the file
+// cannot be decompressed or decoded.
+func (s *service) load() error {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var state snapshotState
+	reader, err := gzip.NewReader(file)
+	if err == nil {
+		err = json.NewDecoder(reader).Decode(&state)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", config.ErrCorruptSnapshot, s.path, err)
+	}
+	s.apply(journalEntry{URLs: state.URLs, Clicks: state.Clicks})
+	s.seq = state.Seq
+	s.snapSeq = state.Seq
+	return nil
+}
+
+// replay applies the journal entries that are more recent than the snapshot, if the journal exists,
+// and truncates a torn last line. A last line cut short by a crash recorded a change that was never
+// acknowledged, so it is dropped; any other line that cannot be read is reported as
+// config.ErrCorruptSnapshot.
+func (s *service) replay() error {
+	size, err := fsutil.ReplayLines(s.journalPath(), func(line int, data []byte) error {
+		var entry journalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("%w: %s:%d: %v", config.ErrCorruptSnapshot, s.journalPath(), line, err)
+		}
+		if entry.Seq <= s.seq {
+			return nil
+		}
+		s.apply(entry)
+		s.seq = entry.Seq
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.size = size
+	return nil
+}
+
+// apply makes the records of entry the current ones and appends its redirect events. A record
+// replaces the one of its original URL as well, which moves when a deleted original URL is restored
+// under another alias. The caller must hold s.mu for writing or have exclusive access to s.
+func (s *service) apply(entry journalEntry) {
+	for _, record := range entry.URLs {
+		if moved, exists := s.originals[record.OriginalURL]; exists && moved != record.ShortURL {
+			delete(s.cache, moved)
+		}
+		if prev, exists := s.cache[record.ShortURL]; exists && s.originals[prev.OriginalURL] == record.ShortURL {
+			delete(s.originals, prev.OriginalURL)
+		}
+		s.cache[record.ShortURL] = record
+		s.originals[record.OriginalURL] = record.ShortURL
+	}
+	for _, click := range entry.Clicks {
+		s.clicks[click.ShortURL] = append(s.clicks[click.ShortURL], click)
+	}
+}
+
+// commit applies a change made of the new records of some short URLs and the redirect events to
+// append. A persistent storage appends the change to the journal first and fsyncs it within the
+// journal window. The caller must hold s.mu for writing.
+func (s *service) commit(records []models.URLData, clicks []models.Click) error {
+	if len(records) == 0 && len(clicks) == 0 {
+		return nil
+	}
+	entry := journalEntry{Seq: s.seq + 1, URLs: records, Clicks: clicks}
+	if s.path != "" {
+		if err := s.record(entry); err != nil {
+			return err
+		}
+	}
+	s.seq = entry.Seq
+	s.apply(entry)
+	return nil
+}
+
+// record appends entry to the journal buffer, which is flushed and fsynced at once if the journal
+// window is zero and by the background maintenance otherwise. The caller must hold s.mu for writing.
+func (s *service) record(entry journalEntry) error {
+	if s.journal == nil {
+		return os.ErrClosed
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := s.writer.Write(data); err != nil {
+		return err
+	}
+	s.size += int64(len(data))
+	if s.window == 0 {
+		return s.sync()
+	}
+	s.dirty = true
+	return nil
+}
+
+// sync flushes the journal buffer and fsyncs the journal. The caller must hold s.mu for writing.
+func (s *service) sync() error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if err := s.journal.Sync(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// snapshot writes the current state to the snapshot file and removes the journal entries it
+// contains, unless nothing changed since the last snapshot. The state is copied under the lock
+// but written without holding it, so that the storage keeps serving requests meanwhile; the
+// changes made in the meantime stay in the journal.
+func (s *service) snapshot() error {
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+
+	s.mu.Lock()
+	if s.journal == nil {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	if s.seq == s.snapSeq {
+		s.mu.Unlock()
+		return nil
+	}
+	if err := s.sync(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	state := snapshotState{
+		Seq:  s.seq,
+		URLs: make([]models.URLData, 0, len(s.cache)),
+	}
+	for _, record := range s.cache {
+		state.URLs = append(state.URLs, record)
+	}
+	for _, clicks := range s.clicks {
+		state.Clicks = append(state.Clicks, clicks...)
+	}
+	offset := s.size // offset is where the entries missing from the snapshot start in the journal.
+	s.mu.Unlock()
+
+	if err := s.writeSnapshot(state); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapSeq = state.Seq
+	if err := s.trimJournal(offset); err != nil {
+		return err
+	}
+	logger.Infof("Saved snapshot %s of %d URLs", s.path, len(state.URLs))
+	return nil
+}
+
+// writeSnapshot atomically replaces the snapshot file with state, so that a crash leaves either
+// the old snapshot or the new one.
+func (s *service) writeSnapshot(state snapshotState) error {
+	return fsutil.WriteFile(s.path, 0644, func(w io.Writer) error {
+		writer := gzip.NewWriter(w)
+		if err := json.NewEncoder(writer).Encode(state); err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+// trimJournal rewrites the journal without its first offset bytes, which hold the entries saved in
+// the snapshot. Like the snapshot, the journal is replaced atomically. The caller must hold s.mu for
+// writing.
+func (s *service) trimJournal(offset int64) error {
+	if err := s.sync(); err != nil {
+		return err
+	}
+	file, err := os.Open(s.journalPath())
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err == nil {
+		var tail []byte
+		tail, err = io.ReadAll(file)
+		if err == nil {
+			err = s.rewriteJournal(tail)
+		}
+	}
+	file.Close()
+	return err
+}
+
+// rewriteJournal replaces the journal with data and reopens it. The caller must hold s.mu for writing.
+func (s *service) rewriteJournal(data []byte) error {
+	path := s.journalPath()
+	err := fsutil.WriteFile(path, 0644, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The old journal is gone, so appending to it would lose the changes: without the new one the
+	// storage refuses further writes.
+	s.journal.Close()
+	s.journal, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		s.journal = nil
+		return err
+	}
+	s.writer = bufio.NewWriter(s.journal)
+	s.size = int64(len(data))
+	return nil
+}
+
+// maintain fsyncs the journal every journal window and takes a snapshot every snapshot interval,
+// until Close is called.
+func (s *service) maintain() {
+	defer close(s.done)
+	var syncC, snapshotC <-chan time.Time
+	if s.window > 0 {
+		ticker := time.NewTicker(s.window)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	if s.interval > 0 {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		snapshotC = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-syncC:
+			s.flush()
+		case <-snapshotC:
+			if err := s.snapshot(); err != nil {
+				logger.Errorf("Error saving in-memory storage snapshot %s: %v", s.path, err)
+			}
+		}
+	}
+}
+
+// flush fsyncs the journal if changes were recorded since it was last fsynced.
+func (s *service) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty || s.journal == nil {
+		return
+	}
+	if err := s.sync(); err != nil {
+		logger.Errorf("Error syncing in-memory storage journal %s: %v", s.journalPath(), err)
+	}
+}
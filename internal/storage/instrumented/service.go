@@ -0,0 +1,191 @@
+// Package instrumented implements a storage.Storage decorator that measures the latency of
+// every storage operation and counts the operations that fail, labelled by backend and method.
+// Every operation is also traced as a span named after the method.
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+)
+
+// service wraps a storage.Storage and records the duration and outcome of its operations.
+// Close is delegated to the wrapped storage without being measured.
+type service struct {
+	storage.Storage
+
+	backend  string                // backend is the value of the backend label, such as "postgres".
+	duration *metrics.HistogramVec // duration records the latency of operations per backend and method.
+	errors   *metrics.CounterVec   // errors counts the operations that returned an error per backend and method.
+}
+
+// NewInstrumentedStorage creates a storage that records the operations of next in reg,
+// labelled with the given backend name.
+func NewInstrumentedStorage(next storage.Storage, backend string, reg *metrics.Registry) storage.Storage {
+	return &service{
+		Storage: next,
+		backend: backend,
+		duration: reg.NewHistogramVec("storage_operation_duration_seconds",
+			"Latency of storage operations in seconds.", metrics.DefaultBuckets, "backend", "method"),
+		errors: reg.NewCounterVec("storage_operation_errors_total",
+			"Number of storage operations that returned an error.", "backend", "method"),
+	}
+}
+
+// begin starts the span of an operation, a child of the span of ctx, and returns the context
+// carrying it along with the start time of the operation.
+func (s *service) begin(ctx context.Context, method string) (context.Context, *tracing.Span, time.Time) {
+	ctx, span := tracing.Start(ctx, "storage."+method, tracing.WithAttributes("storage.backend", s.backend))
+	return ctx, span, time.Now()
+}
+
+// observe records an operation that started at start and finished with err, and ends its span.
+func (s *service) observe(span *tracing.Span, method string, start time.Time, err error) {
+	span.RecordError(err)
+	span.End()
+	s.duration.Observe(time.Since(start).Seconds(), s.backend, method)
+	if err != nil {
+		s.errors.Inc(s.backend, method)
+	}
+}
+
+// SaveUniqueURL measures SaveUniqueURL of the wrapped storage.
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
+	ctx, span, start := s.begin(ctx, "SaveUniqueURL")
+	shortURL, status, err := s.Storage.SaveUniqueURL(ctx, originalURL, userID, opts)
+	s.observe(span, "SaveUniqueURL", start, err)
+	return shortURL, status, err
+}
+
+// SaveURL measures SaveURL of the wrapped storage.
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
+	ctx, span, start := s.begin(ctx, "SaveURL")
+	shortURL, err := s.Storage.SaveURL(ctx, originalURL, userID, opts)
+	s.observe(span, "SaveURL", start, err)
+	return shortURL, err
+}
+
+// SaveURLsBatch measures SaveURLsBatch of the wrapped storage.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	ctx, span, start := s.begin(ctx, "SaveURLsBatch")
+	span.SetAttribute("storage.batch_size", len(items))
+	results, err := s.Storage.SaveURLsBatch(ctx, userID, items, atomic)
+	s.observe(span, "SaveURLsBatch", start, err)
+	return results, err
+}
+
+// GetOriginalLink measures GetOriginalLink of the wrapped storage.
+func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
+	ctx, span, start := s.begin(ctx, "GetOriginalLink")
+	originalURL, err := s.Storage.GetOriginalLink(ctx, shortURL)
+	s.observe(span, "GetOriginalLink", start, err)
+	return originalURL, err
+}
+
+// GetExpiringLink measures GetExpiringLink of the wrapped storage.
+func (s *service) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	ctx, span, start := s.begin(ctx, "GetExpiringLink")
+	originalURL, expiresAt, err := s.Storage.GetExpiringLink(ctx, shortURL)
+	s.observe(span, "GetExpiringLink", start, err)
+	return originalURL, expiresAt, err
+}
+
+// GetProtectedLink measures GetProtectedLink of the wrapped storage.
+func (s *service) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	ctx, span, start := s.begin(ctx, "GetProtectedLink")
+	originalURL, passwordHash, err := s.Storage.GetProtectedLink(ctx, shortURL)
+	s.observe(span, "GetProtectedLink", start, err)
+	return originalURL, passwordHash, err
+}
+
+// Ping measures Ping of the wrapped storage.
+func (s *service) Ping(ctx context.Context) (int, error) {
+	ctx, span, start := s.begin(ctx, "Ping")
+	status, err := s.Storage.Ping(ctx)
+	s.observe(span, "Ping", start, err)
+	return status, err
+}
+
+// GetAllURLS measures GetAllURLS of the wrapped storage.
+func (s *service) GetAllURLS(ctx context.Context, userID, baseURL string) ([]models.UserURLs, error) {
+	ctx, span, start := s.begin(ctx, "GetAllURLS")
+	urls, err := s.Storage.GetAllURLS(ctx, userID, baseURL)
+	s.observe(span, "GetAllURLS", start, err)
+	return urls, err
+}
+
+// MarkURLsAsDeleted measures MarkURLsAsDeleted of the wrapped storage.
+func (s *service) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
+	ctx, span, start := s.begin(ctx, "MarkURLsAsDeleted")
+	err := s.Storage.MarkURLsAsDeleted(ctx, userID, shortURLs)
+	s.observe(span, "MarkURLsAsDeleted", start, err)
+	return err
+}
+
+// MarkURLsAsDeletedBatch measures MarkURLsAsDeletedBatch of the wrapped storage.
+func (s *service) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	ctx, span, start := s.begin(ctx, "MarkURLsAsDeletedBatch")
+	err := s.Storage.MarkURLsAsDeletedBatch(ctx, deletions)
+	s.observe(span, "MarkURLsAsDeletedBatch", start, err)
+	return err
+}
+
+// MarkExpiredURLsAsDeleted measures MarkExpiredURLsAsDeleted of the wrapped storage.
+func (s *service) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	ctx, span, start := s.begin(ctx, "MarkExpiredURLsAsDeleted")
+	marked, err := s.Storage.MarkExpiredURLsAsDeleted(ctx)
+	s.observe(span, "MarkExpiredURLsAsDeleted", start, err)
+	return marked, err
+}
+
+// SaveClicks measures SaveClicks of the wrapped storage.
+func (s *service) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	ctx, span, start := s.begin(ctx, "SaveClicks")
+	err := s.Storage.SaveClicks(ctx, clicks)
+	s.observe(span, "SaveClicks", start, err)
+	return err
+}
+
+// GetURLStats measures GetURLStats of the wrapped storage.
+func (s *service) GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error) {
+	ctx, span, start := s.begin(ctx, "GetURLStats")
+	stats, err := s.Storage.GetURLStats(ctx, userID, shortURL)
+	s.observe(span, "GetURLStats", start, err)
+	return stats, err
+}
+
+// CountURLs measures CountURLs of the wrapped storage.
+func (s *service) CountURLs(ctx context.Context) (int, error) {
+	ctx, span, start := s.begin(ctx, "CountURLs")
+	count, err := s.Storage.CountURLs(ctx)
+	s.observe(span, "CountURLs", start, err)
+	return count, err
+}
+
+// CountUsers measures CountUsers of the wrapped storage.
+func (s *service) CountUsers(ctx context.Context) (int, error) {
+	ctx, span, start := s.begin(ctx, "CountUsers")
+	count, err := s.Storage.CountUsers(ctx)
+	s.observe(span, "CountUsers", start, err)
+	return count, err
+}
+
+// ExportURLs measures ExportURLs of the wrapped storage, including the time spent in fn.
+func (s *service) ExportURLs(ctx context.Context, fn func(models.URLData) error) error {
+	ctx, span, start := s.begin(ctx, "ExportURLs")
+	err := s.Storage.ExportURLs(ctx, fn)
+	s.observe(span, "ExportURLs", start, err)
+	return err
+}
+
+// ImportURL measures ImportURL of the wrapped storage.
+func (s *service) ImportURL(ctx context.Context, data models.URLData) error {
+	ctx, span, start := s.begin(ctx, "ImportURL")
+	err := s.Storage.ImportURL(ctx, data)
+	s.observe(span, "ImportURL", start, err)
+	return err
+}
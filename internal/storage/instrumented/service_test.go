@@ -0,0 +1,47 @@
+package instrumented_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/instrumented"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	reg := metrics.NewRegistry()
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := instrumented.NewInstrumentedStorage(mockStore, "memory", reg)
+
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "abc").Return("http://example.com", nil)
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "missing").Return("", config.ErrNotFound)
+	mockStore.EXPECT().CountURLs(gomock.Any()).Return(3, nil)
+
+	originalURL, err := store.GetOriginalLink(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com", originalURL)
+
+	_, err = store.GetOriginalLink(ctx, "missing")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+
+	count, err := store.CountURLs(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	var sb strings.Builder
+	assert.NoError(t, reg.WriteText(&sb))
+	text := sb.String()
+	assert.Contains(t, text, `storage_operation_duration_seconds_count{backend="memory",method="GetOriginalLink"} 2`)
+	assert.Contains(t, text, `storage_operation_duration_seconds_count{backend="memory",method="CountURLs"} 1`)
+	assert.Contains(t, text, `storage_operation_errors_total{backend="memory",method="GetOriginalLink"} 1`)
+	assert.NotContains(t, text, `storage_operation_errors_total{backend="memory",method="CountURLs"}`)
+}
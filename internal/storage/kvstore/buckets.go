@@ -0,0 +1,190 @@
+package kvstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The database holds four top-level buckets:
+//   - urls maps every short URL to its models.URLData record encoded as JSON; deleted records are
+//     kept with DeletedFlag set, so that their short URL stays taken;
+//   - originals maps every original URL to its short URL, deleted or not, giving original URLs the
+//     uniqueness the database storage enforces with a constraint;
+//   - users holds a nested bucket per user ID whose keys are the short URLs the user owns;
+//   - clicks holds a nested bucket per short URL whose values are its redirect events encoded as
+//     JSON, keyed by a big-endian sequence number so that they are kept in the order they were saved.
+var (
+	urlsBucket      = []byte("urls")
+	originalsBucket = []byte("originals")
+	usersBucket     = []byte("users")
+	clicksBucket    = []byte("clicks")
+)
+
+// getURL returns the record of a short URL and whether it is stored.
+func getURL(tx *bolt.Tx, shortURL string) (models.URLData, bool, error) {
+	var record models.URLData
+	data := tx.Bucket(urlsBucket).Get([]byte(shortURL))
+	if data == nil {
+		return record, false, nil
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, false, fmt.Errorf("decoding record of %q: %w", shortURL, err)
+	}
+	return record, true, nil
+}
+
+// getByOriginal returns the record of an original URL, deleted or not, and whether it is stored.
+func getByOriginal(tx *bolt.Tx, originalURL string) (models.URLData, bool, error) {
+	shortURL := tx.Bucket(originalsBucket).Get([]byte(originalURL))
+	if shortURL == nil {
+		return models.URLData{}, false, nil
+	}
+	return getURL(tx, string(shortURL))
+}
+
+// isTaken reports whether a short URL is stored, deleted or not.
+func isTaken(tx *bolt.Tx, shortURL string) bool {
+	return tx.Bucket(urlsBucket).Get([]byte(shortURL)) != nil
+}
+
+// generateShortURL returns a random short path that is not taken.
+func generateShortURL(tx *bolt.Tx) string {
+	shortURL := utils.GenerateShortPath()
+	for isTaken(tx, shortURL) {
+		shortURL = utils.GenerateShortPath()
+	}
+	return shortURL
+}
+
+// putURL stores record and updates the indexes of original URLs and of user URLs, moving the short
+// URL to its new owner if it changed hands. The record replaces the one of its original URL as well,
+// which moves when a deleted original URL is restored under another alias.
+func putURL(tx *bolt.Tx, record models.URLData) error {
+	moved, found, err := getByOriginal(tx, record.OriginalURL)
+	if err != nil {
+		return err
+	}
+	if found && moved.ShortURL != record.ShortURL {
+		if err := tx.Bucket(urlsBucket).Delete([]byte(moved.ShortURL)); err != nil {
+			return err
+		}
+		if owned := tx.Bucket(usersBucket).Bucket([]byte(moved.UUID.String())); owned != nil {
+			if err := owned.Delete([]byte(moved.ShortURL)); err != nil {
+				return err
+			}
+		}
+	}
+
+	previous, found, err := getURL(tx, record.ShortURL)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(urlsBucket).Put([]byte(record.ShortURL), data); err != nil {
+		return err
+	}
+
+	if found && previous.OriginalURL != record.OriginalURL {
+		if err := tx.Bucket(originalsBucket).Delete([]byte(previous.OriginalURL)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(originalsBucket).Put([]byte(record.OriginalURL), []byte(record.ShortURL)); err != nil {
+		return err
+	}
+
+	users := tx.Bucket(usersBucket)
+	if found && previous.UUID != record.UUID {
+		if owned := users.Bucket([]byte(previous.UUID.String())); owned != nil {
+			if err := owned.Delete([]byte(record.ShortURL)); err != nil {
+				return err
+			}
+		}
+	}
+	owned, err := users.CreateBucketIfNotExists([]byte(record.UUID.String()))
+	if err != nil {
+		return err
+	}
+	return owned.Put([]byte(record.ShortURL), []byte{})
+}
+
+// forEachURL calls fn for every stored record in the order of their short URLs.
+func forEachURL(tx *bolt.Tx, fn func(models.URLData) error) error {
+	return tx.Bucket(urlsBucket).ForEach(func(shortURL, data []byte) error {
+		var record models.URLData
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("decoding record of %q: %w", shortURL, err)
+		}
+		return fn(record)
+	})
+}
+
+// readURLPage returns up to limit records whose short URL sorts after the given one, or the first
+// records if after is nil, together with the short URL of the last record returned.
+func readURLPage(tx *bolt.Tx, after []byte, limit int) ([]models.URLData, []byte, error) {
+	cursor := tx.Bucket(urlsBucket).Cursor()
+	key, data := cursor.First()
+	if after != nil {
+		key, data = cursor.Seek(after)
+		if key != nil && bytes.Equal(key, after) {
+			key, data = cursor.Next()
+		}
+	}
+
+	var page []models.URLData
+	for ; key != nil && len(page) < limit; key, data = cursor.Next() {
+		var record models.URLData
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, nil, fmt.Errorf("decoding record of %q: %w", key, err)
+		}
+		page = append(page, record)
+		after = bytes.Clone(key)
+	}
+	return page, after, nil
+}
+
+// putClick appends a redirect event to the events of its short URL.
+func putClick(tx *bolt.Tx, click models.Click) error {
+	clicks, err := tx.Bucket(clicksBucket).CreateBucketIfNotExists([]byte(click.ShortURL))
+	if err != nil {
+		return err
+	}
+	seq, err := clicks.NextSequence()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(click)
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return clicks.Put(key, data)
+}
+
+// getClicks returns the redirect events of a short URL in the order they were saved.
+func getClicks(tx *bolt.Tx, shortURL string) ([]models.Click, error) {
+	bucket := tx.Bucket(clicksBucket).Bucket([]byte(shortURL))
+	if bucket == nil {
+		return nil, nil
+	}
+	var clicks []models.Click
+	err := bucket.ForEach(func(_, data []byte) error {
+		var click models.Click
+		if err := json.Unmarshal(data, &click); err != nil {
+			return err
+		}
+		clicks = append(clicks, click)
+		return nil
+	})
+	return clicks, err
+}
@@ -0,0 +1,15 @@
+package kvstore_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/storagetest"
+)
+
+func TestKVStorageConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return openStorage(t, filepath.Join(t.TempDir(), "urls.db"))
+	})
+}
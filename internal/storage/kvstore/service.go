@@ -0,0 +1,467 @@
+// Package kvstore implements the storage.Storage interface on top of an embedded key-value store,
+// bbolt, kept in a single file. It gives durable storage to single-binary deployments that run
+// without a database: every change is a transaction that is fsynced before it returns.
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/service/utils"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// exportPageSize is the number of records read by each transaction of ExportURLs.
+const exportPageSize = 1000
+
+// service provides URL storage management using a bbolt database.
+type service struct {
+	db *bolt.DB // db is the open bbolt database holding the buckets described in buckets.go.
+}
+
+// NewKVStorage opens the key-value storage in the file at path, creating it and its buckets if needed.
+// The file is locked while the storage is open; if another process holds it, opening fails after
+// config.KVOpenTimeout.
+func NewKVStorage(path string) (storage.Storage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: config.KVOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{urlsBucket, originalsBucket, usersBucket, clicksBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &service{db: db}, nil
+}
+
+// SaveUniqueURL saves a URL and generates a unique short URL, or uses the requested custom alias.
+// As with the database storage, an original URL that is already stored results in its existing
+// short URL with HTTP 409, and one that is stored but marked as deleted is restored for the new
+// owner, keeping its short URL. A custom alias that is already taken results in config.ErrAliasTaken
+// with HTTP 409.
+// It returns the complete URL, an HTTP status code, and any error encountered.
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
+	uuid, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Errorf("Error with parsing userID in key-value storage %v", err)
+		return "", http.StatusBadRequest, err
+	}
+
+	shortURL, err := s.createShortURL(uuid, originalURL, opts)
+	if errors.Is(err, config.ErrExists) {
+		return config.BaseURL + "/" + shortURL, http.StatusConflict, nil
+	}
+	if errors.Is(err, config.ErrAliasTaken) {
+		return "", http.StatusConflict, err
+	}
+	if err != nil {
+		logger.Errorf("Error saving URL in key-value storage: %v", err)
+		return "", http.StatusInternalServerError, err
+	}
+	return config.BaseURL + "/" + shortURL, http.StatusCreated, nil
+}
+
+// SaveURL performs the same operation as SaveUniqueURL without returning the HTTP status code.
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
+	uuid, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Errorf("Error with parsing userID in key-value storage %v", err)
+		return "", err
+	}
+
+	shortURL, err := s.createShortURL(uuid, originalURL, opts)
+	if err != nil && !errors.Is(err, config.ErrExists) {
+		return "", err
+	}
+	return config.BaseURL + "/" + shortURL, nil
+}
+
+// createShortURL stores originalURL for userID in a single transaction. If the original URL is
+// already stored and not deleted, its short URL is returned with config.ErrExists; if it is deleted,
+// it is restored under the requested alias, or under its previous short URL when no alias is given.
+// Otherwise it is stored under the requested alias, or under a generated short path that is not taken.
+func (s *service) createShortURL(userID uuid.UUID, originalURL string, opts models.ShortenOptions) (string, error) {
+	var shortURL string
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		previous, found, err := getByOriginal(tx, originalURL)
+		if err != nil {
+			return err
+		}
+		if found && !previous.DeletedFlag {
+			shortURL = previous.ShortURL
+			return config.ErrExists
+		}
+
+		record := models.URLData{
+			UUID:         userID,
+			OriginalURL:  originalURL,
+			ExpiresAt:    opts.ExpiresAt,
+			PasswordHash: opts.PasswordHash,
+		}
+		switch {
+		case found && (opts.CustomAlias == "" || opts.CustomAlias == previous.ShortURL):
+			record.ShortURL = previous.ShortURL
+		case opts.CustomAlias != "":
+			if isTaken(tx, opts.CustomAlias) {
+				return config.ErrAliasTaken
+			}
+			record.ShortURL = opts.CustomAlias
+		default:
+			record.ShortURL = generateShortURL(tx)
+		}
+		shortURL = record.ShortURL
+		return putURL(tx, record)
+	})
+	return shortURL, err
+}
+
+// SaveURLsBatch saves several URLs in a single transaction, so that a failure stores none of them.
+// Original URLs that are stored but marked as deleted are restored for the user, under the requested
+// alias if any. An atomic batch with a failed item stores nothing.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	uuid, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Errorf("Error with parsing userID in key-value storage %v", err)
+		return nil, err
+	}
+
+	var results []models.BatchURLResult
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		existing := make(map[string]string)
+		deleted := make(map[string]string) // deleted holds the short URLs of the deleted original URLs of the batch.
+		for _, item := range items {
+			record, found, err := getByOriginal(tx, item.OriginalURL)
+			if err != nil {
+				return err
+			}
+			switch {
+			case !found:
+			case record.DeletedFlag:
+				deleted[item.OriginalURL] = record.ShortURL
+			default:
+				existing[item.OriginalURL] = record.ShortURL
+			}
+		}
+
+		var records []models.URLData
+		records, results = utils.PlanURLBatch(uuid, items, existing, deleted, func(shortURL string) bool { return isTaken(tx, shortURL) })
+		if atomic && utils.AbortURLBatch(records, results) {
+			return nil
+		}
+		for _, record := range records {
+			if err := putURL(tx, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Errorf("Error saving a batch of %d URLs: %v", len(items), err)
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetOriginalLink retrieves the original URL of a short URL, checking if it's marked as deleted,
+// expired or password protected.
+func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
+	originalURL, passwordHash, err := s.GetProtectedLink(ctx, shortURL)
+	if err != nil {
+		return "", err
+	}
+	if passwordHash != "" {
+		return "", config.ErrPasswordRequired
+	}
+	return originalURL, nil
+}
+
+// GetExpiringLink retrieves the original URL and expiration time of a short URL, checking if it's
+// marked as deleted, expired or password protected.
+func (s *service) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	record, err := s.lookup(shortURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if record.PasswordHash != "" {
+		return "", nil, config.ErrPasswordRequired
+	}
+	return record.OriginalURL, record.ExpiresAt, nil
+}
+
+// GetProtectedLink retrieves the original URL and password hash of a short URL, checking if it's
+// marked as deleted or expired.
+func (s *service) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	record, err := s.lookup(shortURL)
+	if err != nil {
+		return "", "", err
+	}
+	return record.OriginalURL, record.PasswordHash, nil
+}
+
+// lookup returns the record of a short URL that is neither deleted nor expired.
+func (s *service) lookup(shortURL string) (models.URLData, error) {
+	var record models.URLData
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var found bool
+		var err error
+		record, found, err = getURL(tx, shortURL)
+		if err == nil && !found {
+			err = config.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return models.URLData{}, err
+	}
+	if record.DeletedFlag {
+		return models.URLData{}, config.ErrGone
+	}
+	if record.Expired(time.Now()) {
+		return models.URLData{}, config.ErrExpired
+	}
+	return record, nil
+}
+
+// Ping checks that the database file is open and readable.
+func (s *service) Ping(ctx context.Context) (int, error) {
+	if err := s.db.View(func(tx *bolt.Tx) error { return nil }); err != nil {
+		logger.Errorf("Failed to ping the key-value storage: %v", err)
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// Close closes the database file, waiting for running transactions to end.
+func (s *service) Close() error {
+	return s.db.Close()
+}
+
+// GetAllURLS retrieves the URLs of a user that are not marked as deleted, using the index of the
+// short URLs of every user.
+func (s *service) GetAllURLS(ctx context.Context, userID, baseURL string) ([]models.UserURLs, error) {
+	var urls []models.UserURLs
+	err := s.db.View(func(tx *bolt.Tx) error {
+		shortURLs := tx.Bucket(usersBucket).Bucket([]byte(userID))
+		if shortURLs == nil {
+			return nil
+		}
+		return shortURLs.ForEach(func(shortURL, _ []byte) error {
+			record, found, err := getURL(tx, string(shortURL))
+			if err != nil || !found || record.DeletedFlag {
+				return err
+			}
+			urls = append(urls, models.UserURLs{
+				ShortURL:    baseURL + "/" + record.ShortURL,
+				OriginalURL: record.OriginalURL,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		logger.Errorf("Error retrieving all user URLs: %v", err)
+		return nil, err
+	}
+	return urls, nil
+}
+
+// MarkURLsAsDeleted marks specified URLs as deleted for a given user ID.
+func (s *service) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
+	deletions := make([]models.URLDeletion, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		deletions[i] = models.URLDeletion{UserID: userID, ShortURL: shortURL}
+	}
+	return s.MarkURLsAsDeletedBatch(ctx, deletions)
+}
+
+// MarkURLsAsDeletedBatch marks the URLs of several users as deleted in a single transaction.
+func (s *service) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	marked := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, deletion := range deletions {
+			record, found, err := getURL(tx, deletion.ShortURL)
+			if err != nil {
+				return err
+			}
+			if !found || record.DeletedFlag || record.UUID.String() != deletion.UserID {
+				continue
+			}
+			record.DeletedFlag = true
+			if err := putURL(tx, record); err != nil {
+				return err
+			}
+			marked++
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Errorf("Error marking URLs as deleted: %v", err)
+		return err
+	}
+	logger.Infof("%d of %d URLs were marked as deleted.", marked, len(deletions))
+	return nil
+}
+
+// MarkExpiredURLsAsDeleted marks every URL whose expiration time has passed as deleted.
+func (s *service) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	now := time.Now()
+	var expired []models.URLData
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		// A bucket must not be changed while it is iterated, so the records are updated afterwards.
+		err := forEachURL(tx, func(record models.URLData) error {
+			if !record.DeletedFlag && record.Expired(now) {
+				record.DeletedFlag = true
+				expired = append(expired, record)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, record := range expired {
+			if err := putURL(tx, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Errorf("Error marking expired URLs as deleted: %v", err)
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+// SaveClicks stores a batch of redirect events in a single transaction.
+func (s *service) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, click := range clicks {
+			if err := putClick(tx, click); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Errorf("Error saving clicks: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetURLStats aggregates the redirect events of a short URL owned by the given user.
+func (s *service) GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error) {
+	var stats models.URLStats
+	err := s.db.View(func(tx *bolt.Tx) error {
+		record, found, err := getURL(tx, shortURL)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return config.ErrNotFound
+		}
+		if record.UUID.String() != userID {
+			return config.ErrForbidden
+		}
+		clicks, err := getClicks(tx, shortURL)
+		if err != nil {
+			return err
+		}
+		stats = utils.AggregateClicks(shortURL, clicks)
+		return nil
+	})
+	return stats, err
+}
+
+// CountURLs returns the number of URLs that are not marked as deleted.
+func (s *service) CountURLs(ctx context.Context) (int, error) {
+	count := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachURL(tx, func(record models.URLData) error {
+			if !record.DeletedFlag {
+				count++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logger.Errorf("Error counting URLs: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountUsers returns the number of distinct users owning a URL that is not marked as deleted.
+func (s *service) CountUsers(ctx context.Context) (int, error) {
+	users := make(map[uuid.UUID]struct{})
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachURL(tx, func(record models.URLData) error {
+			if !record.DeletedFlag {
+				users[record.UUID] = struct{}{}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logger.Errorf("Error counting users: %v", err)
+		return 0, err
+	}
+	return len(users), nil
+}
+
+// ExportURLs calls fn for every URL record in the order of their short URLs. The records are read
+// exportPageSize at a time, each page in its own transaction, and fn is called outside of it, so
+// that fn may call back into the storage.
+func (s *service) ExportURLs(ctx context.Context, fn func(models.URLData) error) error {
+	var after []byte // after is the last short URL exported; nil before the first page.
+	for {
+		var page []models.URLData
+		err := s.db.View(func(tx *bolt.Tx) error {
+			var err error
+			page, after, err = readURLPage(tx, after, exportPageSize)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, record := range page {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// ImportURL stores a URL record as is, rejecting short and original URLs that are already present
+// with config.ErrAliasTaken and config.ErrExists respectively.
+func (s *service) ImportURL(ctx context.Context, data models.URLData) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if isTaken(tx, data.ShortURL) {
+			return config.ErrAliasTaken
+		}
+		if tx.Bucket(originalsBucket).Get([]byte(data.OriginalURL)) != nil {
+			return config.ErrExists
+		}
+		return putURL(tx, data)
+	})
+}
@@ -0,0 +1,225 @@
+package kvstore_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/kvstore"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUser  = "0a6ed83c-7d2e-4bfb-9e2f-0b5ab1f7e5a4"
+	otherUser = "7b1f5d0e-2c4a-4f8e-9d3b-6a2e8c1f0b94"
+)
+
+// openStorage opens the key-value storage at path, closing it when the test ends.
+func openStorage(t *testing.T, path string) storage.Storage {
+	t.Helper()
+	store, err := kvstore.NewKVStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestKVStorageSaveUniqueURL(t *testing.T) {
+	ctx := context.Background()
+	store := openStorage(t, filepath.Join(t.TempDir(), "urls.db"))
+
+	_, status, err := store.SaveUniqueURL(ctx, "https://example.com/a", testUser, models.ShortenOptions{CustomAlias: "aaa"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, status)
+	_, status, err = store.SaveUniqueURL(ctx, "https://example.com/d", testUser, models.ShortenOptions{CustomAlias: "ddd"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, status)
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, testUser, []string{"ddd"}))
+
+	tests := []struct {
+		name           string
+		originalURL    string
+		userID         string
+		alias          string
+		expectedStatus int
+		expectedURL    string
+		expectedErr    error
+	}{
+		{
+			name:           "Stored Original URL Returns Existing Short URL",
+			originalURL:    "https://example.com/a",
+			userID:         otherUser,
+			expectedStatus: http.StatusConflict,
+			expectedURL:    config.BaseURL + "/aaa",
+		},
+		{
+			name:           "Taken Alias",
+			originalURL:    "https://example.com/b",
+			userID:         testUser,
+			alias:          "aaa",
+			expectedStatus: http.StatusConflict,
+			expectedErr:    config.ErrAliasTaken,
+		},
+		{
+			name:           "Deleted Original URL With Taken Alias",
+			originalURL:    "https://example.com/d",
+			userID:         otherUser,
+			alias:          "aaa",
+			expectedStatus: http.StatusConflict,
+			expectedErr:    config.ErrAliasTaken,
+		},
+		{
+			name:           "Deleted Original URL Is Restored Under The Requested Alias",
+			originalURL:    "https://example.com/d",
+			userID:         otherUser,
+			alias:          "new",
+			expectedStatus: http.StatusCreated,
+			expectedURL:    config.BaseURL + "/new",
+		},
+		{
+			name:           "Invalid User ID",
+			originalURL:    "https://example.com/c",
+			userID:         "not-a-uuid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortURL, status, err := store.SaveUniqueURL(ctx, tt.originalURL, tt.userID, models.ShortenOptions{CustomAlias: tt.alias})
+			assert.Equal(t, tt.expectedStatus, status)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+			if tt.expectedURL != "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedURL, shortURL)
+			}
+		})
+	}
+
+	// The restored URL moved to its new owner and its alias.
+	urls, err := store.GetAllURLS(ctx, otherUser, "http://localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserURLs{{ShortURL: "http://localhost:8080/new", OriginalURL: "https://example.com/d"}}, urls)
+	_, err = store.GetOriginalLink(ctx, "ddd")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+	urls, err = store.GetAllURLS(ctx, testUser, "http://localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserURLs{{ShortURL: "http://localhost:8080/aaa", OriginalURL: "https://example.com/a"}}, urls)
+}
+
+func TestKVStorageSaveURLsBatch(t *testing.T) {
+	ctx := context.Background()
+	store := openStorage(t, filepath.Join(t.TempDir(), "urls.db"))
+
+	_, err := store.SaveURL(ctx, "https://example.com/a", testUser, models.ShortenOptions{CustomAlias: "aaa"})
+	require.NoError(t, err)
+	_, err = store.SaveURL(ctx, "https://example.com/d", testUser, models.ShortenOptions{CustomAlias: "ddd"})
+	require.NoError(t, err)
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, testUser, []string{"ddd"}))
+
+	items := []models.BatchURL{
+		{OriginalURL: "https://example.com/a"},
+		{OriginalURL: "https://example.com/b", Opts: models.ShortenOptions{CustomAlias: "bbb"}},
+		{OriginalURL: "https://example.com/c", Opts: models.ShortenOptions{CustomAlias: "aaa"}},
+		{OriginalURL: "https://example.com/d"},
+	}
+
+	results, err := store.SaveURLsBatch(ctx, testUser, items, true)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.ErrorIs(t, results[2].Err, config.ErrAliasTaken)
+	assert.ErrorIs(t, results[1].Err, config.ErrBatchAborted)
+	_, err = store.GetOriginalLink(ctx, "bbb")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+
+	results, err = store.SaveURLsBatch(ctx, testUser, items, false)
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchURLResult{ShortURL: config.BaseURL + "/aaa", Existed: true}, results[0])
+	assert.Equal(t, models.BatchURLResult{ShortURL: config.BaseURL + "/bbb"}, results[1])
+	assert.ErrorIs(t, results[2].Err, config.ErrAliasTaken)
+	assert.Equal(t, models.BatchURLResult{ShortURL: config.BaseURL + "/ddd"}, results[3])
+
+	originalURL, err := store.GetOriginalLink(ctx, "ddd")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/d", originalURL)
+}
+
+func TestKVStorageReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.db")
+	past := time.Now().Add(-time.Minute)
+
+	store, err := kvstore.NewKVStorage(path)
+	require.NoError(t, err)
+	_, err = store.SaveURL(ctx, "https://example.com/a", testUser, models.ShortenOptions{CustomAlias: "aaa"})
+	require.NoError(t, err)
+	_, err = store.SaveURL(ctx, "https://example.com/e", testUser, models.ShortenOptions{CustomAlias: "eee", ExpiresAt: &past})
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClicks(ctx, []models.Click{
+		{ShortURL: "aaa", ClickedAt: time.Now()},
+		{ShortURL: "aaa", ClickedAt: time.Now()},
+	}))
+	require.NoError(t, store.Close())
+
+	store = openStorage(t, path)
+	originalURL, err := store.GetOriginalLink(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", originalURL)
+	_, err = store.GetOriginalLink(ctx, "eee")
+	assert.ErrorIs(t, err, config.ErrExpired)
+
+	stats, err := store.GetURLStats(ctx, testUser, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.TotalClicks)
+	_, err = store.GetURLStats(ctx, otherUser, "aaa")
+	assert.ErrorIs(t, err, config.ErrForbidden)
+
+	marked, err := store.MarkExpiredURLsAsDeleted(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, marked)
+	_, err = store.GetOriginalLink(ctx, "eee")
+	assert.ErrorIs(t, err, config.ErrGone)
+
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestKVStorageExportImport(t *testing.T) {
+	ctx := context.Background()
+	store := openStorage(t, filepath.Join(t.TempDir(), "urls.db"))
+
+	// More records than fit in a page of the export.
+	const total = 2500
+	owner := uuid.MustParse(testUser)
+	for i := 0; i < total; i++ {
+		require.NoError(t, store.ImportURL(ctx, models.URLData{
+			UUID:        owner,
+			ShortURL:    fmt.Sprintf("u%05d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+		}))
+	}
+	err := store.ImportURL(ctx, models.URLData{UUID: owner, ShortURL: "u00000", OriginalURL: "https://example.com/other"})
+	assert.ErrorIs(t, err, config.ErrAliasTaken)
+	err = store.ImportURL(ctx, models.URLData{UUID: owner, ShortURL: "other", OriginalURL: "https://example.com/0"})
+	assert.ErrorIs(t, err, config.ErrExists)
+
+	var exported []string
+	require.NoError(t, store.ExportURLs(ctx, func(record models.URLData) error {
+		exported = append(exported, record.ShortURL)
+		return nil
+	}))
+	require.Len(t, exported, total)
+	for i, shortURL := range exported {
+		assert.Equal(t, fmt.Sprintf("u%05d", i), shortURL)
+	}
+}
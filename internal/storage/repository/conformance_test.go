@@ -0,0 +1,34 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/repository"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDBStorageConformance runs the conformance suite against the PostgreSQL database of
+// TEST_DATABASE_DSN, emptying its tables before each subtest. It is skipped if the variable is unset.
+func TestDBStorageConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	config.DBDSN = dsn
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		data, err := dbimpl.InitDB()
+		require.NoError(t, err)
+		_, err = data.Exec(context.Background(), "TRUNCATE shortened_urls, url_clicks")
+		require.NoError(t, err)
+		store := repository.NewDBStorage(data)
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
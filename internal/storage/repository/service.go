@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/config"
 	"github.com/gleb-korostelev/short-url.git/internal/db"
@@ -18,6 +19,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// maxGenerateAttempts bounds how many random short paths are tried before giving up on a save.
+const maxGenerateAttempts = 5
+
 // service provides URL storage management using a database.
 type service struct {
 	data db.DB // data is the interface for interacting with the database.
@@ -31,44 +35,45 @@ func NewDBStorage(data db.DB) storage.Storage {
 }
 
 // SaveUniqueURL saves a new URL into the database, ensuring it is unique.
-// It generates a short URL and attempts to store it along with the original URL in the database.
+// It generates a short URL (or uses the requested custom alias) and attempts to store it along with
+// the original URL in the database. A custom alias that is already taken results in
+// config.ErrAliasTaken with HTTP 409.
 // Returns the complete URL, HTTP status code, and any error encountered.
-func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string) (string, int, error) {
-	shortURL := utils.GenerateShortPath()
-
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
 	uuid, err := uuid.Parse(userID)
 	if err != nil {
 		logger.Errorf("Error parsing userID: %v", err)
 		return "", http.StatusInternalServerError, err
 	}
 
-	err = dbimpl.CreateShortURL(s.data, uuid.String(), shortURL, originalURL)
+	shortURL, err := s.createShortURL(ctx, uuid.String(), originalURL, opts)
 	if err != nil {
 		if errors.Is(err, config.ErrExists) {
-			existingShortURL, err := dbimpl.GetShortURLByOriginalURL(s.data, originalURL)
+			existingShortURL, err := dbimpl.GetShortURLByOriginalURL(ctx, s.data, originalURL)
 			if err != nil {
 				return "", http.StatusInternalServerError, err
 			}
 			return config.BaseURL + "/" + existingShortURL, http.StatusConflict, nil
 		}
+		if errors.Is(err, config.ErrAliasTaken) {
+			return "", http.StatusConflict, err
+		}
 		return "", http.StatusInternalServerError, err
 	}
 	return config.BaseURL + "/" + shortURL, http.StatusCreated, nil
 }
 
 // SaveURL performs the same operation as SaveUniqueURL without returning the HTTP status code.
-func (s *service) SaveURL(ctx context.Context, originalURL string, userID string) (string, error) {
-	shortURL := utils.GenerateShortPath()
-
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
 	uuid, err := uuid.Parse(userID)
 	if err != nil {
 		logger.Errorf("Error with parsing userId in database %v", err)
 		return "", err
 	}
-	err = dbimpl.CreateShortURL(s.data, uuid.String(), shortURL, originalURL)
+	shortURL, err := s.createShortURL(ctx, uuid.String(), originalURL, opts)
 	if err != nil {
 		if errors.Is(err, config.ErrExists) {
-			existingShortURL, err := dbimpl.GetShortURLByOriginalURL(s.data, originalURL)
+			existingShortURL, err := dbimpl.GetShortURLByOriginalURL(ctx, s.data, originalURL)
 			if err != nil {
 				return "", err
 			}
@@ -79,9 +84,109 @@ func (s *service) SaveURL(ctx context.Context, originalURL string, userID string
 	return config.BaseURL + "/" + shortURL, nil
 }
 
+// SaveURLsBatch saves several URLs in a single transaction. The stored original URLs and taken aliases
+// of the batch are looked up first and the remaining URLs are then inserted with a single statement.
+// The transaction is retried up to maxGenerateAttempts times if a short URL is taken concurrently or
+// a generated short path collides with an existing one. An atomic batch with a failed item inserts nothing.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	uuid, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Errorf("Error with parsing userId in database %v", err)
+		return nil, err
+	}
+
+	originalURLs := make([]string, len(items))
+	var aliases []string
+	for i, item := range items {
+		originalURLs[i] = item.OriginalURL
+		if item.Opts.CustomAlias != "" {
+			aliases = append(aliases, item.Opts.CustomAlias)
+		}
+	}
+
+	var results []models.BatchURLResult
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		err = dbimpl.WithTx(ctx, s.data, func(tx db.Tx) error {
+			existing, deleted, err := dbimpl.GetStoredShortURLs(ctx, tx, originalURLs)
+			if err != nil {
+				return err
+			}
+			taken, err := dbimpl.GetTakenShortURLs(ctx, tx, aliases)
+			if err != nil {
+				return err
+			}
+
+			var records []models.URLData
+			records, results = utils.PlanURLBatch(uuid, items, existing, deleted, func(shortURL string) bool { return taken[shortURL] })
+			if atomic && utils.AbortURLBatch(records, results) {
+				return nil
+			}
+			created, err := dbimpl.CreateShortURLs(ctx, tx, records)
+			if err != nil {
+				return err
+			}
+
+			// Original URLs stored concurrently since the lookup were left unchanged by the insert.
+			var missing []string
+			for _, record := range records {
+				if _, ok := created[record.OriginalURL]; !ok {
+					missing = append(missing, record.OriginalURL)
+				}
+			}
+			concurrent := make(map[string]string)
+			if len(missing) > 0 {
+				if concurrent, _, err = dbimpl.GetStoredShortURLs(ctx, tx, missing); err != nil {
+					return err
+				}
+			}
+
+			// Report the short URLs the statement stored the original URLs under.
+			for i, item := range items {
+				if results[i].Err != nil {
+					continue
+				}
+				if shortURL, ok := created[item.OriginalURL]; ok {
+					results[i].ShortURL = config.BaseURL + "/" + shortURL
+				} else if shortURL, ok := concurrent[item.OriginalURL]; ok {
+					results[i] = models.BatchURLResult{ShortURL: config.BaseURL + "/" + shortURL, Existed: true}
+				}
+			}
+			return nil
+		})
+		if !errors.Is(err, config.ErrAliasTaken) {
+			break
+		}
+	}
+	if err != nil {
+		logger.Errorf("Error saving a batch of %d URLs: %v", len(items), err)
+		return nil, err
+	}
+	return results, nil
+}
+
+// createShortURL stores the original URL under the requested alias, or under a generated short path
+// when no alias is given, and returns the short URL it is stored under: an original URL marked as
+// deleted is restored under the alias, or under its previous one when no alias is given. Generated paths that happen to collide with existing
+// ones are regenerated up to maxGenerateAttempts times; a colliding alias is reported as config.ErrAliasTaken.
+func (s *service) createShortURL(ctx context.Context, userID, originalURL string, opts models.ShortenOptions) (string, error) {
+	if opts.CustomAlias != "" {
+		return dbimpl.CreateShortURL(ctx, s.data, userID, opts.CustomAlias, originalURL, opts.ExpiresAt, opts.PasswordHash, true)
+	}
+
+	var err error
+	for i := 0; i < maxGenerateAttempts; i++ {
+		var shortURL string
+		shortURL, err = dbimpl.CreateShortURL(ctx, s.data, userID, utils.GenerateShortPath(), originalURL, opts.ExpiresAt, opts.PasswordHash, false)
+		if !errors.Is(err, config.ErrAliasTaken) {
+			return shortURL, err
+		}
+	}
+	return "", err
+}
+
 // GetOriginalLink retrieves the original URL from the database for a given short URL.
 func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
-	originalURL, err := dbimpl.GetOriginalURL(s.data, shortURL)
+	originalURL, err := dbimpl.GetOriginalURL(ctx, s.data, shortURL)
 	if err != nil {
 		logger.Errorf("Error retrieving original URL: %v", err)
 		return "", err
@@ -89,9 +194,29 @@ func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string,
 	return originalURL, nil
 }
 
+// GetExpiringLink retrieves the original URL and expiration time from the database for a given short URL.
+func (s *service) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	originalURL, expiresAt, err := dbimpl.GetExpiringURL(ctx, s.data, shortURL)
+	if err != nil {
+		logger.Errorf("Error retrieving original URL: %v", err)
+		return "", nil, err
+	}
+	return originalURL, expiresAt, nil
+}
+
+// GetProtectedLink retrieves the original URL and password hash from the database for a given short URL.
+func (s *service) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	originalURL, passwordHash, err := dbimpl.GetProtectedURL(ctx, s.data, shortURL)
+	if err != nil {
+		logger.Errorf("Error retrieving protected URL: %v", err)
+		return "", "", err
+	}
+	return originalURL, passwordHash, nil
+}
+
 // Ping checks the connectivity and status of the database.
 func (s *service) Ping(ctx context.Context) (int, error) {
-	err := s.data.Ping(context.Background())
+	err := s.data.Ping(ctx)
 	if err != nil {
 		logger.Errorf("Failed to ping the database: %v", err)
 		return http.StatusInternalServerError, err
@@ -110,7 +235,7 @@ func (s *service) Close() error {
 
 // GetAllURLs retrieves all URLs associated with a specific user ID from the database.
 func (s *service) GetAllURLS(ctx context.Context, userID, baseURL string) ([]models.UserURLs, error) {
-	res, err := dbimpl.GetOriginalURLsByUserID(s.data, userID, baseURL)
+	res, err := dbimpl.GetOriginalURLsByUserID(ctx, s.data, userID, baseURL)
 	if err != nil {
 		logger.Errorf("Error retrieving all user URLs: %v", err)
 		return nil, err
@@ -120,6 +245,95 @@ func (s *service) GetAllURLS(ctx context.Context, userID, baseURL string) ([]mod
 
 // MarkURLsAsDeleted marks specified URLs as deleted in the database for a given user ID.
 func (s *service) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
-	dbimpl.MarkDeleted(s.data, userID, shortURLs)
+	return dbimpl.MarkDeleted(ctx, s.data, userID, shortURLs)
+}
+
+// MarkURLsAsDeletedBatch marks the URLs of several users as deleted in the database with a single statement.
+func (s *service) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	marked, err := dbimpl.MarkDeletedBatch(ctx, s.data, deletions)
+	if err != nil {
+		logger.Errorf("Error marking URLs as deleted: %v", err)
+		return err
+	}
+	logger.Infof("%d of %d URLs were marked as deleted.", marked, len(deletions))
+	return nil
+}
+
+// MarkExpiredURLsAsDeleted marks every URL whose expiration time has passed as deleted in the database.
+func (s *service) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	marked, err := dbimpl.MarkExpiredDeleted(ctx, s.data)
+	if err != nil {
+		logger.Errorf("Error marking expired URLs as deleted: %v", err)
+		return 0, err
+	}
+	return int(marked), nil
+}
+
+// SaveClicks stores a batch of redirect events in the database.
+func (s *service) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	err := dbimpl.InsertClicks(ctx, s.data, clicks)
+	if err != nil {
+		logger.Errorf("Error saving clicks: %v", err)
+		return err
+	}
 	return nil
 }
+
+// GetURLStats retrieves the redirect statistics of a short URL owned by the given user from the database.
+func (s *service) GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error) {
+	owner, err := dbimpl.GetURLOwner(ctx, s.data, shortURL)
+	if err != nil {
+		return models.URLStats{}, err
+	}
+	if owner != userID {
+		return models.URLStats{}, config.ErrForbidden
+	}
+
+	daily, err := dbimpl.GetDailyClicks(ctx, s.data, shortURL)
+	if err != nil {
+		logger.Errorf("Error retrieving clicks: %v", err)
+		return models.URLStats{}, err
+	}
+	stats := models.URLStats{ShortURL: shortURL, Daily: daily}
+	for _, day := range daily {
+		stats.TotalClicks += day.Clicks
+	}
+	return stats, nil
+}
+
+// CountURLs returns the number of shortened URLs in the database that are not marked as deleted.
+func (s *service) CountURLs(ctx context.Context) (int, error) {
+	count, err := dbimpl.CountURLs(ctx, s.data)
+	if err != nil {
+		logger.Errorf("Error counting URLs: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountUsers returns the number of distinct users in the database owning a URL that is not marked as deleted.
+func (s *service) CountUsers(ctx context.Context) (int, error) {
+	count, err := dbimpl.CountUsers(ctx, s.data)
+	if err != nil {
+		logger.Errorf("Error counting users: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ExportURLs streams every shortened URL row in the database to fn.
+func (s *service) ExportURLs(ctx context.Context, fn func(models.URLData) error) error {
+	return dbimpl.ForEachURL(ctx, s.data, fn)
+}
+
+// ImportURL inserts a URL record into the database as is.
+func (s *service) ImportURL(ctx context.Context, data models.URLData) error {
+	err := dbimpl.InsertURL(ctx, s.data, data)
+	if err != nil && !errors.Is(err, config.ErrAliasTaken) && !errors.Is(err, config.ErrExists) {
+		logger.Errorf("Error importing URL: %v", err)
+	}
+	return err
+}
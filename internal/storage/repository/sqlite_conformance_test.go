@@ -0,0 +1,24 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/db/dbimpl"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/repository"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSQLiteStorageConformance runs the conformance suite against a fresh SQLite database file
+// for each subtest.
+func TestSQLiteStorageConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		data, err := dbimpl.InitSQLite(filepath.Join(t.TempDir(), "urls.sqlite"))
+		require.NoError(t, err)
+		store := repository.NewDBStorage(data)
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
@@ -0,0 +1,128 @@
+// Package screened implements a storage.Storage decorator that enforces a URL policy: URLs
+// rejected by the policy are neither saved nor resolved for a redirect. Resolved URLs are checked
+// again on every lookup, so that links saved before their URL was rejected stop redirecting.
+package screened
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+)
+
+// service wraps a storage.Storage and checks the URLs it saves and resolves against a policy.
+// Imported records are stored as is and every other operation is delegated unchanged.
+type service struct {
+	storage.Storage
+
+	policy policy.Checker // policy decides which URLs are rejected.
+}
+
+// NewScreenedStorage creates a storage that rejects the URLs refused by checker. Rejected URLs
+// are reported with the *policy.BlockedError returned by checker, which wraps config.ErrURLBlocked.
+// It should wrap any caching storage, so that a cached URL is checked as well.
+func NewScreenedStorage(next storage.Storage, checker policy.Checker) storage.Storage {
+	return &service{
+		Storage: next,
+		policy:  checker,
+	}
+}
+
+// SaveUniqueURL saves originalURL in the wrapped storage unless the policy rejects it,
+// in which case HTTP 400 Bad Request and the policy's error are returned.
+func (s *service) SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error) {
+	if err := s.policy.Check(originalURL); err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	return s.Storage.SaveUniqueURL(ctx, originalURL, userID, opts)
+}
+
+// SaveURL saves originalURL in the wrapped storage unless the policy rejects it.
+func (s *service) SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error) {
+	if err := s.policy.Check(originalURL); err != nil {
+		return "", err
+	}
+	return s.Storage.SaveURL(ctx, originalURL, userID, opts)
+}
+
+// SaveURLsBatch saves the items accepted by the policy in the wrapped storage and fails each
+// rejected item with the policy's error. If atomic is set and an item is rejected, nothing is
+// saved and the accepted items fail with config.ErrBatchAborted.
+func (s *service) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	results := make([]models.BatchURLResult, len(items))
+	accepted := make([]models.BatchURL, 0, len(items))
+	acceptedIndex := make([]int, 0, len(items)) // acceptedIndex maps the accepted items to their position in items.
+	for i, item := range items {
+		if err := s.policy.Check(item.OriginalURL); err != nil {
+			results[i].Err = err
+			continue
+		}
+		accepted = append(accepted, item)
+		acceptedIndex = append(acceptedIndex, i)
+	}
+
+	if len(accepted) == len(items) {
+		return s.Storage.SaveURLsBatch(ctx, userID, items, atomic)
+	}
+	if atomic {
+		for _, i := range acceptedIndex {
+			results[i].Err = config.ErrBatchAborted
+		}
+		return results, nil
+	}
+	if len(accepted) == 0 {
+		return results, nil
+	}
+
+	saved, err := s.Storage.SaveURLsBatch(ctx, userID, accepted, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for j, result := range saved {
+		results[acceptedIndex[j]] = result
+	}
+	return results, nil
+}
+
+// GetOriginalLink resolves shortURL in the wrapped storage and returns the policy's error
+// if the original URL is rejected. The error carries the rejected URL.
+func (s *service) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
+	originalURL, err := s.Storage.GetOriginalLink(ctx, shortURL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.policy.Check(originalURL); err != nil {
+		return "", err
+	}
+	return originalURL, nil
+}
+
+// GetExpiringLink resolves shortURL in the wrapped storage and returns the policy's error
+// if the original URL is rejected. The error carries the rejected URL.
+func (s *service) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	originalURL, expiresAt, err := s.Storage.GetExpiringLink(ctx, shortURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.policy.Check(originalURL); err != nil {
+		return "", nil, err
+	}
+	return originalURL, expiresAt, nil
+}
+
+// GetProtectedLink resolves shortURL in the wrapped storage and returns the policy's error
+// if the original URL is rejected. The error carries the rejected URL.
+func (s *service) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	originalURL, passwordHash, err := s.Storage.GetProtectedLink(ctx, shortURL)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.policy.Check(originalURL); err != nil {
+		return "", "", err
+	}
+	return originalURL, passwordHash, nil
+}
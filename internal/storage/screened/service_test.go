@@ -0,0 +1,119 @@
+package screened_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/policy"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/screened"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBlocklist creates an in-memory blocklist with the given entries.
+func newBlocklist(t *testing.T, entries ...string) *policy.Engine {
+	t.Helper()
+	engine, err := policy.NewEngine("", config.PolicyModeBlocklist, time.Hour)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		require.NoError(t, engine.Add(entry))
+	}
+	return engine
+}
+
+func TestScreenedStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	store := screened.NewScreenedStorage(mockStore, newBlocklist(t, "*.evil.com"))
+
+	// Rejected URLs never reach the wrapped storage.
+	_, status, err := store.SaveUniqueURL(context.Background(), "http://www.evil.com", "user", models.ShortenOptions{})
+	assert.ErrorIs(t, err, config.ErrURLBlocked)
+	assert.Equal(t, http.StatusBadRequest, status)
+	_, err = store.SaveURL(context.Background(), "http://www.evil.com", "user", models.ShortenOptions{})
+	assert.ErrorIs(t, err, config.ErrURLBlocked)
+
+	mockStore.EXPECT().SaveURL(gomock.Any(), "http://example.com", "user", gomock.Any()).Return("http://localhost:8080/abc", nil)
+	shortURL, err := store.SaveURL(context.Background(), "http://example.com", "user", models.ShortenOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/abc", shortURL)
+
+	// URLs saved before they were blocked are rejected when resolved.
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "old").Return("http://www.evil.com/x", nil)
+	_, err = store.GetOriginalLink(context.Background(), "old")
+	var blocked *policy.BlockedError
+	require.ErrorAs(t, err, &blocked)
+	assert.Equal(t, "http://www.evil.com/x", blocked.URL)
+
+	mockStore.EXPECT().GetProtectedLink(gomock.Any(), "old").Return("http://www.evil.com/x", "hash", nil)
+	_, _, err = store.GetProtectedLink(context.Background(), "old")
+	assert.ErrorIs(t, err, config.ErrURLBlocked)
+
+	mockStore.EXPECT().GetOriginalLink(gomock.Any(), "gone").Return("", config.ErrGone)
+	_, err = store.GetOriginalLink(context.Background(), "gone")
+	assert.ErrorIs(t, err, config.ErrGone)
+}
+
+func TestScreenedStorageBatch(t *testing.T) {
+	items := []models.BatchURL{
+		{OriginalURL: "http://example.com/a"},
+		{OriginalURL: "http://www.evil.com"},
+		{OriginalURL: "http://example.com/b"},
+	}
+
+	tests := []struct {
+		name      string
+		atomic    bool
+		saved     []models.BatchURL
+		savedRes  []models.BatchURLResult
+		expectErr []error
+	}{
+		{
+			name:      "Rejected Item Fails Alone",
+			saved:     []models.BatchURL{items[0], items[2]},
+			savedRes:  []models.BatchURLResult{{ShortURL: "http://localhost:8080/a"}, {ShortURL: "http://localhost:8080/b", Existed: true}},
+			expectErr: []error{nil, config.ErrURLBlocked, nil},
+		},
+		{
+			name:      "Atomic Batch Is Aborted",
+			atomic:    true,
+			expectErr: []error{config.ErrBatchAborted, config.ErrURLBlocked, config.ErrBatchAborted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mock_db.NewMockStorage(ctrl)
+			if tt.saved != nil {
+				mockStore.EXPECT().SaveURLsBatch(gomock.Any(), "user", tt.saved, tt.atomic).Return(tt.savedRes, nil)
+			}
+			store := screened.NewScreenedStorage(mockStore, newBlocklist(t, "*.evil.com"))
+
+			results, err := store.SaveURLsBatch(context.Background(), "user", items, tt.atomic)
+			require.NoError(t, err)
+			require.Len(t, results, len(items))
+			for i, result := range results {
+				if tt.expectErr[i] == nil {
+					assert.NoError(t, result.Err)
+					continue
+				}
+				assert.ErrorIs(t, result.Err, tt.expectErr[i])
+			}
+			if tt.saved != nil {
+				assert.Equal(t, tt.savedRes[0], results[0])
+				assert.Equal(t, tt.savedRes[1], results[2])
+			}
+		})
+	}
+}
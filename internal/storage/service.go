@@ -5,6 +5,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/gleb-korostelev/short-url.git/internal/models"
 )
@@ -14,17 +15,43 @@ import (
 // retrieval, and lifecycle management in a thread-safe manner.
 type Storage interface {
 	// SaveUniqueURL stores a new URL and associates it with a user ID, ensuring the short URL is unique.
+	// If opts.CustomAlias is set it is used as the short URL; a collision with an existing short URL
+	// is reported as config.ErrAliasTaken instead of overwriting the existing entry.
+	// A non-empty opts.PasswordHash is stored with the URL and makes the link password protected.
 	// Returns the shortened URL, an HTTP status code indicating the result, and any error encountered.
-	SaveUniqueURL(ctx context.Context, originalURL string, userID string) (string, int, error)
+	SaveUniqueURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, int, error)
 
 	// SaveURL stores a new URL without ensuring uniqueness.
 	// It is typically used when the unique handling is managed at a higher level or not required.
-	SaveURL(ctx context.Context, originalURL string, userID string) (string, error)
+	// Custom aliases in opts follow the same collision rules as SaveUniqueURL.
+	SaveURL(ctx context.Context, originalURL string, userID string, opts models.ShortenOptions) (string, error)
+
+	// SaveURLsBatch stores several URLs of a user in a single operation and returns the outcome of each
+	// item, in the order of items. An original URL that is already stored, or repeats an earlier item,
+	// is not stored again: its result holds the existing short URL and has Existed set. A custom alias
+	// that is taken, or repeats an earlier item's alias, fails only that item with config.ErrAliasTaken.
+	// If atomic is set and any item fails, nothing is stored and the items that would have been stored
+	// fail with config.ErrBatchAborted. The returned error reports a failure of the whole batch, in which
+	// case nothing is stored.
+	SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error)
 
 	// GetOriginalLink retrieves the original URL based on its shortened version.
 	// It returns the original URL and any error encountered if the URL does not exist or other issues arise.
+	// Unknown short URLs are reported as config.ErrNotFound, deleted ones as config.ErrGone
+	// and links past their expiration time as config.ErrExpired. Password protected links are not
+	// resolved and are reported as config.ErrPasswordRequired.
 	GetOriginalLink(ctx context.Context, shortURL string) (string, error)
 
+	// GetExpiringLink retrieves the original URL of a short URL as GetOriginalLink does, together with
+	// the moment the link expires, nil if it never expires. It lets callers keeping the original URL,
+	// such as caches, stop using it once the link expires.
+	GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error)
+
+	// GetProtectedLink retrieves the original URL of a short URL together with the bcrypt hash of its
+	// password, which is empty if the link is not protected. The caller is responsible for checking the
+	// password. Errors are reported as by GetOriginalLink, except that config.ErrPasswordRequired is never returned.
+	GetProtectedLink(ctx context.Context, shortURL string) (string, string, error)
+
 	// Ping checks the health or connectivity of the storage medium, often used in database connections.
 	// It returns an HTTP status code and any error encountered during the health check.
 	Ping(ctx context.Context) (int, error)
@@ -39,4 +66,36 @@ type Storage interface {
 	// MarkURLsAsDeleted marks specified URLs as deleted for a given user ID.
 	// This method handles the soft deletion of URLs and returns any error encountered during the operation.
 	MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error
+
+	// MarkURLsAsDeletedBatch marks the URLs of several users as deleted in a single operation.
+	// Each deletion only applies if the short URL belongs to its user, as with MarkURLsAsDeleted.
+	MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error
+
+	// MarkExpiredURLsAsDeleted soft-deletes every URL whose expiration time has passed.
+	// It returns the number of URLs that were marked and any error encountered during the operation.
+	MarkExpiredURLsAsDeleted(ctx context.Context) (int, error)
+
+	// SaveClicks persists a batch of redirect events.
+	// It returns any error encountered while storing the events.
+	SaveClicks(ctx context.Context, clicks []models.Click) error
+
+	// GetURLStats returns the redirect statistics of a short URL owned by the given user.
+	// It returns config.ErrNotFound if the short URL does not exist and config.ErrForbidden
+	// if it belongs to another user.
+	GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error)
+
+	// CountURLs returns the number of shortened URLs that are not marked as deleted.
+	CountURLs(ctx context.Context) (int, error)
+
+	// CountUsers returns the number of distinct users owning at least one URL that is not marked as deleted.
+	CountUsers(ctx context.Context) (int, error)
+
+	// ExportURLs calls fn for every stored URL record, including deleted and expired ones.
+	// Iteration stops at the first error returned by fn, which is then returned.
+	ExportURLs(ctx context.Context, fn func(models.URLData) error) error
+
+	// ImportURL stores a URL record as is, preserving its short URL, owner, deleted flag and expiration.
+	// It returns config.ErrAliasTaken if the short URL is already stored and config.ErrExists
+	// if the original URL is already stored under another short URL.
+	ImportURL(ctx context.Context, data models.URLData) error
 }
@@ -0,0 +1,635 @@
+// Package storagetest provides a conformance suite for storage.Storage implementations. Run checks
+// the semantics every backend must share: the uniqueness of short and original URLs, the conflicts
+// reported when saving them, soft deletion, ownership checks and their behaviour under concurrent use.
+// A backend's tests call Run with a function opening an empty storage.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrency is the number of goroutines racing in the concurrency tests.
+const concurrency = 16
+
+// Opener opens an empty storage for a test and closes it when the test ends.
+type Opener func(t *testing.T) storage.Storage
+
+// Run runs the conformance suite, each subtest against a storage of its own opened by open.
+func Run(t *testing.T, open Opener) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store storage.Storage)
+	}{
+		{name: "SaveUniqueURL", test: testSaveUniqueURL},
+		{name: "SaveURL", test: testSaveURL},
+		{name: "RestoreDeleted", test: testRestoreDeleted},
+		{name: "RestoreDeletedWithAlias", test: testRestoreDeletedWithAlias},
+		{name: "SaveURLsBatch", test: testSaveURLsBatch},
+		{name: "Lookup", test: testLookup},
+		{name: "SoftDeletion", test: testSoftDeletion},
+		{name: "Ownership", test: testOwnership},
+		{name: "Expiration", test: testExpiration},
+		{name: "ImportURL", test: testImportURL},
+		{name: "ConcurrentSaves", test: testConcurrentSaves},
+		{name: "ConcurrentDeletions", test: testConcurrentDeletions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+// shortPath returns the short URL of a URL returned by the storage, without config.BaseURL.
+func shortPath(t *testing.T, fullURL string) string {
+	t.Helper()
+	shortURL, ok := strings.CutPrefix(fullURL, config.BaseURL+"/")
+	require.True(t, ok, "%q does not start with the base URL", fullURL)
+	return shortURL
+}
+
+// save saves originalURL for userID with opts and returns its short URL.
+func save(t *testing.T, store storage.Storage, originalURL, userID string, opts models.ShortenOptions) string {
+	t.Helper()
+	fullURL, err := store.SaveURL(context.Background(), originalURL, userID, opts)
+	require.NoError(t, err)
+	return shortPath(t, fullURL)
+}
+
+func testSaveUniqueURL(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner, other := uuid.NewString(), uuid.NewString()
+
+	fullURL, status, err := store.SaveUniqueURL(ctx, "https://example.com/a", owner, models.ShortenOptions{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, status)
+	shortURL := shortPath(t, fullURL)
+	_, status, err = store.SaveUniqueURL(ctx, "https://example.com/b", owner, models.ShortenOptions{CustomAlias: "alias"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, status)
+
+	tests := []struct {
+		name           string
+		originalURL    string
+		userID         string
+		alias          string
+		expectedStatus int
+		expectedURL    string
+		expectedErr    error
+	}{
+		{
+			name:           "Stored Original URL",
+			originalURL:    "https://example.com/a",
+			userID:         owner,
+			expectedStatus: http.StatusConflict,
+			expectedURL:    config.BaseURL + "/" + shortURL,
+		},
+		{
+			name:           "Original URL Stored By Another User",
+			originalURL:    "https://example.com/a",
+			userID:         other,
+			expectedStatus: http.StatusConflict,
+			expectedURL:    config.BaseURL + "/" + shortURL,
+		},
+		{
+			name:           "Stored Original URL With Another Alias",
+			originalURL:    "https://example.com/b",
+			userID:         owner,
+			alias:          "other",
+			expectedStatus: http.StatusConflict,
+			expectedURL:    config.BaseURL + "/alias",
+		},
+		{
+			name:           "Taken Alias",
+			originalURL:    "https://example.com/c",
+			userID:         other,
+			alias:          "alias",
+			expectedStatus: http.StatusConflict,
+			expectedErr:    config.ErrAliasTaken,
+		},
+		{
+			name:           "Alias Taken By A Generated Short URL",
+			originalURL:    "https://example.com/c",
+			userID:         owner,
+			alias:          shortURL,
+			expectedStatus: http.StatusConflict,
+			expectedErr:    config.ErrAliasTaken,
+		},
+		{
+			name:           "New Original URL",
+			originalURL:    "https://example.com/c",
+			userID:         other,
+			alias:          "new",
+			expectedStatus: http.StatusCreated,
+			expectedURL:    config.BaseURL + "/new",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fullURL, status, err := store.SaveUniqueURL(ctx, tt.originalURL, tt.userID, models.ShortenOptions{CustomAlias: tt.alias})
+			assert.Equal(t, tt.expectedStatus, status)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedURL, fullURL)
+		})
+	}
+
+	// Conflicts store nothing.
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	originalURL, err := store.GetOriginalLink(ctx, "alias")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", originalURL)
+}
+
+func testSaveURL(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+
+	shortURL := save(t, store, "https://example.com/a", owner, models.ShortenOptions{})
+
+	// A stored original URL results in its short URL without an error.
+	fullURL, err := store.SaveURL(ctx, "https://example.com/a", uuid.NewString(), models.ShortenOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, config.BaseURL+"/"+shortURL, fullURL)
+
+	_, err = store.SaveURL(ctx, "https://example.com/b", owner, models.ShortenOptions{CustomAlias: shortURL})
+	assert.ErrorIs(t, err, config.ErrAliasTaken)
+
+	_, err = store.SaveURL(ctx, "https://example.com/b", "not-a-uuid", models.ShortenOptions{})
+	assert.Error(t, err)
+
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testRestoreDeleted(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner, other := uuid.NewString(), uuid.NewString()
+
+	shortURL := save(t, store, "https://example.com/a", owner, models.ShortenOptions{})
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, owner, []string{shortURL}))
+
+	// Saving a deleted original URL restores it for the new owner under its short URL.
+	fullURL, status, err := store.SaveUniqueURL(ctx, "https://example.com/a", other, models.ShortenOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, config.BaseURL+"/"+shortURL, fullURL)
+
+	originalURL, err := store.GetOriginalLink(ctx, shortURL)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", originalURL)
+	urls, err := store.GetAllURLS(ctx, other, config.BaseURL)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserURLs{{ShortURL: fullURL, OriginalURL: "https://example.com/a"}}, urls)
+	urls, err = store.GetAllURLS(ctx, owner, config.BaseURL)
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+
+	// So does saving it again in a batch.
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, other, []string{shortURL}))
+	results, err := store.SaveURLsBatch(ctx, owner, []models.BatchURL{{OriginalURL: "https://example.com/a"}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []models.BatchURLResult{{ShortURL: fullURL}}, results)
+	originalURL, err = store.GetOriginalLink(ctx, shortURL)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", originalURL)
+
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testRestoreDeletedWithAlias(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner, other := uuid.NewString(), uuid.NewString()
+
+	save(t, store, "https://example.com/a", owner, models.ShortenOptions{CustomAlias: "aaa"})
+	save(t, store, "https://example.com/b", owner, models.ShortenOptions{CustomAlias: "bbb"})
+	save(t, store, "https://example.com/c", owner, models.ShortenOptions{CustomAlias: "ccc"})
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, owner, []string{"aaa", "ccc"}))
+
+	// A taken alias leaves the deleted URL as it was.
+	_, status, err := store.SaveUniqueURL(ctx, "https://example.com/a", other, models.ShortenOptions{CustomAlias: "bbb"})
+	assert.ErrorIs(t, err, config.ErrAliasTaken)
+	assert.Equal(t, http.StatusConflict, status)
+	_, err = store.GetOriginalLink(ctx, "aaa")
+	assert.ErrorIs(t, err, config.ErrGone)
+
+	// Saving a deleted original URL with an alias restores it under the alias, freeing its short URL.
+	fullURL, status, err := store.SaveUniqueURL(ctx, "https://example.com/a", other, models.ShortenOptions{CustomAlias: "new"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, config.BaseURL+"/new", fullURL)
+
+	originalURL, err := store.GetOriginalLink(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", originalURL)
+	_, err = store.GetOriginalLink(ctx, "aaa")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+	urls, err := store.GetAllURLS(ctx, other, config.BaseURL)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserURLs{{ShortURL: fullURL, OriginalURL: "https://example.com/a"}}, urls)
+
+	// So does saving it in a batch, and the alias may be its previous short URL.
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, other, []string{"new"}))
+	results, err := store.SaveURLsBatch(ctx, owner, []models.BatchURL{
+		{OriginalURL: "https://example.com/a", Opts: models.ShortenOptions{CustomAlias: "newer"}},
+		{OriginalURL: "https://example.com/c", Opts: models.ShortenOptions{CustomAlias: "ccc"}},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []models.BatchURLResult{
+		{ShortURL: config.BaseURL + "/newer"},
+		{ShortURL: config.BaseURL + "/ccc"},
+	}, results)
+
+	originalURL, err = store.GetOriginalLink(ctx, "newer")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", originalURL)
+	_, err = store.GetOriginalLink(ctx, "new")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+	originalURL, err = store.GetOriginalLink(ctx, "ccc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/c", originalURL)
+
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func testSaveURLsBatch(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+
+	stored := save(t, store, "https://example.com/a", owner, models.ShortenOptions{})
+	items := []models.BatchURL{
+		{OriginalURL: "https://example.com/a"},
+		{OriginalURL: "https://example.com/b", Opts: models.ShortenOptions{CustomAlias: "bbb"}},
+		{OriginalURL: "https://example.com/b"},
+		{OriginalURL: "https://example.com/c", Opts: models.ShortenOptions{CustomAlias: stored}},
+		{OriginalURL: "https://example.com/d", Opts: models.ShortenOptions{CustomAlias: "bbb"}},
+		{OriginalURL: "https://example.com/e"},
+	}
+
+	// An atomic batch with a failed item stores nothing.
+	results, err := store.SaveURLsBatch(ctx, owner, items, true)
+	require.NoError(t, err)
+	require.Len(t, results, len(items))
+	assert.Equal(t, models.BatchURLResult{ShortURL: config.BaseURL + "/" + stored, Existed: true}, results[0])
+	assert.ErrorIs(t, results[1].Err, config.ErrBatchAborted)
+	assert.ErrorIs(t, results[3].Err, config.ErrAliasTaken)
+	assert.ErrorIs(t, results[4].Err, config.ErrAliasTaken)
+	assert.ErrorIs(t, results[5].Err, config.ErrBatchAborted)
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	results, err = store.SaveURLsBatch(ctx, owner, items, false)
+	require.NoError(t, err)
+	require.Len(t, results, len(items))
+	assert.Equal(t, models.BatchURLResult{ShortURL: config.BaseURL + "/" + stored, Existed: true}, results[0])
+	assert.Equal(t, models.BatchURLResult{ShortURL: config.BaseURL + "/bbb"}, results[1])
+	assert.Equal(t, models.BatchURLResult{ShortURL: config.BaseURL + "/bbb", Existed: true}, results[2])
+	assert.ErrorIs(t, results[3].Err, config.ErrAliasTaken)
+	assert.ErrorIs(t, results[4].Err, config.ErrAliasTaken)
+	require.NoError(t, results[5].Err)
+
+	originalURL, err := store.GetOriginalLink(ctx, shortPath(t, results[5].ShortURL))
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/e", originalURL)
+	count, err = store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func testLookup(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	save(t, store, "https://example.com/a", owner, models.ShortenOptions{CustomAlias: "active", ExpiresAt: &future})
+	save(t, store, "https://example.com/b", owner, models.ShortenOptions{CustomAlias: "deleted"})
+	save(t, store, "https://example.com/c", owner, models.ShortenOptions{CustomAlias: "expired", ExpiresAt: &past})
+	save(t, store, "https://example.com/d", owner, models.ShortenOptions{CustomAlias: "protected", PasswordHash: "hash"})
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, owner, []string{"deleted"}))
+
+	tests := []struct {
+		name                 string
+		shortURL             string
+		expectedURL          string
+		expectedErr          error
+		expectedProtectedErr error
+	}{
+		{name: "Active", shortURL: "active", expectedURL: "https://example.com/a"},
+		{name: "Unknown", shortURL: "unknown", expectedErr: config.ErrNotFound, expectedProtectedErr: config.ErrNotFound},
+		{name: "Deleted", shortURL: "deleted", expectedErr: config.ErrGone, expectedProtectedErr: config.ErrGone},
+		{name: "Expired", shortURL: "expired", expectedErr: config.ErrExpired, expectedProtectedErr: config.ErrExpired},
+		{name: "Password Protected", shortURL: "protected", expectedErr: config.ErrPasswordRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalURL, err := store.GetOriginalLink(ctx, tt.shortURL)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedURL, originalURL)
+			}
+
+			_, _, err = store.GetExpiringLink(ctx, tt.shortURL)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			_, _, err = store.GetProtectedLink(ctx, tt.shortURL)
+			if tt.expectedProtectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedProtectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	originalURL, passwordHash, err := store.GetProtectedLink(ctx, "protected")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/d", originalURL)
+	assert.Equal(t, "hash", passwordHash)
+
+	originalURL, expiresAt, err := store.GetExpiringLink(ctx, "active")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", originalURL)
+	require.NotNil(t, expiresAt)
+	assert.WithinDuration(t, future, *expiresAt, time.Millisecond)
+
+	save(t, store, "https://example.com/e", owner, models.ShortenOptions{CustomAlias: "permanent"})
+	_, expiresAt, err = store.GetExpiringLink(ctx, "permanent")
+	require.NoError(t, err)
+	assert.Nil(t, expiresAt)
+}
+
+func testSoftDeletion(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner, other := uuid.NewString(), uuid.NewString()
+
+	save(t, store, "https://example.com/a", owner, models.ShortenOptions{CustomAlias: "aaa"})
+	save(t, store, "https://example.com/b", owner, models.ShortenOptions{CustomAlias: "bbb"})
+
+	// Only the owner deletes a URL, and deleting it again or deleting unknown URLs is harmless.
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, other, []string{"aaa", "bbb"}))
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, owner, []string{"aaa", "unknown"}))
+	require.NoError(t, store.MarkURLsAsDeleted(ctx, owner, []string{"aaa"}))
+
+	_, err := store.GetOriginalLink(ctx, "aaa")
+	assert.ErrorIs(t, err, config.ErrGone)
+	_, err = store.GetOriginalLink(ctx, "bbb")
+	assert.NoError(t, err)
+
+	urls, err := store.GetAllURLS(ctx, owner, config.BaseURL)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserURLs{{ShortURL: config.BaseURL + "/bbb", OriginalURL: "https://example.com/b"}}, urls)
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	users, err := store.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, users)
+
+	// A deleted short URL stays taken.
+	_, err = store.SaveURL(ctx, "https://example.com/c", other, models.ShortenOptions{CustomAlias: "aaa"})
+	assert.ErrorIs(t, err, config.ErrAliasTaken)
+
+	// Deleted URLs are still exported.
+	var exported []string
+	require.NoError(t, store.ExportURLs(ctx, func(record models.URLData) error {
+		exported = append(exported, record.ShortURL)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"aaa", "bbb"}, exported)
+}
+
+func testOwnership(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner, other := uuid.NewString(), uuid.NewString()
+
+	save(t, store, "https://example.com/a", owner, models.ShortenOptions{CustomAlias: "aaa"})
+	save(t, store, "https://example.com/b", other, models.ShortenOptions{CustomAlias: "bbb"})
+	require.NoError(t, store.SaveClicks(ctx, []models.Click{
+		{ShortURL: "aaa", ClickedAt: time.Now()},
+		{ShortURL: "aaa", ClickedAt: time.Now()},
+	}))
+
+	stats, err := store.GetURLStats(ctx, owner, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", stats.ShortURL)
+	assert.Equal(t, int64(2), stats.TotalClicks)
+	_, err = store.GetURLStats(ctx, other, "aaa")
+	assert.ErrorIs(t, err, config.ErrForbidden)
+	_, err = store.GetURLStats(ctx, owner, "unknown")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+
+	// Each deletion of a batch applies only to the URLs of its user.
+	require.NoError(t, store.MarkURLsAsDeletedBatch(ctx, []models.URLDeletion{
+		{UserID: other, ShortURL: "aaa"},
+		{UserID: other, ShortURL: "bbb"},
+	}))
+	_, err = store.GetOriginalLink(ctx, "aaa")
+	assert.NoError(t, err)
+	_, err = store.GetOriginalLink(ctx, "bbb")
+	assert.ErrorIs(t, err, config.ErrGone)
+
+	urls, err := store.GetAllURLS(ctx, owner, config.BaseURL)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserURLs{{ShortURL: config.BaseURL + "/aaa", OriginalURL: "https://example.com/a"}}, urls)
+	urls, err = store.GetAllURLS(ctx, other, config.BaseURL)
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func testExpiration(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+	past := time.Now().Add(-time.Minute)
+
+	save(t, store, "https://example.com/a", owner, models.ShortenOptions{CustomAlias: "aaa", ExpiresAt: &past})
+	save(t, store, "https://example.com/b", owner, models.ShortenOptions{CustomAlias: "bbb"})
+
+	marked, err := store.MarkExpiredURLsAsDeleted(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, marked)
+	marked, err = store.MarkExpiredURLsAsDeleted(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, marked)
+
+	_, err = store.GetOriginalLink(ctx, "aaa")
+	assert.ErrorIs(t, err, config.ErrGone)
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testImportURL(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner := uuid.New()
+
+	require.NoError(t, store.ImportURL(ctx, models.URLData{UUID: owner, ShortURL: "aaa", OriginalURL: "https://example.com/a"}))
+	require.NoError(t, store.ImportURL(ctx, models.URLData{UUID: owner, ShortURL: "ddd", OriginalURL: "https://example.com/d", DeletedFlag: true}))
+
+	tests := []struct {
+		name        string
+		record      models.URLData
+		expectedErr error
+	}{
+		{
+			name:        "Taken Short URL",
+			record:      models.URLData{UUID: owner, ShortURL: "aaa", OriginalURL: "https://example.com/b"},
+			expectedErr: config.ErrAliasTaken,
+		},
+		{
+			name:        "Stored Original URL",
+			record:      models.URLData{UUID: owner, ShortURL: "bbb", OriginalURL: "https://example.com/a"},
+			expectedErr: config.ErrExists,
+		},
+		{
+			name:        "Deleted Original URL",
+			record:      models.URLData{UUID: owner, ShortURL: "bbb", OriginalURL: "https://example.com/d"},
+			expectedErr: config.ErrExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, store.ImportURL(ctx, tt.record), tt.expectedErr)
+		})
+	}
+
+	_, err := store.GetOriginalLink(ctx, "ddd")
+	assert.ErrorIs(t, err, config.ErrGone)
+	_, err = store.GetOriginalLink(ctx, "bbb")
+	assert.ErrorIs(t, err, config.ErrNotFound)
+}
+
+func testConcurrentSaves(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+
+	// Saving the same original URL concurrently stores it once.
+	shortURLs := make([]string, concurrency)
+	statuses := make([]int, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fullURL, status, err := store.SaveUniqueURL(ctx, "https://example.com/same", uuid.NewString(), models.ShortenOptions{})
+			assert.NoError(t, err)
+			shortURLs[i], statuses[i] = fullURL, status
+		}(i)
+	}
+	wg.Wait()
+	created := 0
+	for i := range shortURLs {
+		assert.Equal(t, shortURLs[0], shortURLs[i])
+		if statuses[i] == http.StatusCreated {
+			created++
+		}
+	}
+	assert.Equal(t, 1, created)
+
+	// Claiming the same alias concurrently succeeds once.
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = store.SaveURL(ctx, fmt.Sprintf("https://example.com/%d", i), uuid.NewString(), models.ShortenOptions{CustomAlias: "alias"})
+		}(i)
+	}
+	wg.Wait()
+	saved := 0
+	for _, err := range errs {
+		if err == nil {
+			saved++
+		} else {
+			assert.ErrorIs(t, err, config.ErrAliasTaken)
+		}
+	}
+	assert.Equal(t, 1, saved)
+
+	// Generated short URLs never collide.
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.SaveURL(ctx, fmt.Sprintf("https://example.com/generated/%d", i), uuid.NewString(), models.ShortenOptions{})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2+concurrency, count)
+}
+
+func testConcurrentDeletions(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+
+	shortURLs := make([]string, concurrency)
+	for i := range shortURLs {
+		shortURLs[i] = save(t, store, fmt.Sprintf("https://example.com/%d", i), owner, models.ShortenOptions{})
+	}
+
+	// Deletions race with lookups and with listing the user's URLs.
+	var wg sync.WaitGroup
+	for i, shortURL := range shortURLs {
+		wg.Add(3)
+		go func(shortURL string) {
+			defer wg.Done()
+			assert.NoError(t, store.MarkURLsAsDeleted(ctx, owner, []string{shortURL}))
+		}(shortURL)
+		go func(shortURL string) {
+			defer wg.Done()
+			_, err := store.GetOriginalLink(ctx, shortURL)
+			if err != nil {
+				assert.ErrorIs(t, err, config.ErrGone)
+			}
+		}(shortURL)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.GetAllURLS(ctx, owner, config.BaseURL)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, shortURL := range shortURLs {
+		_, err := store.GetOriginalLink(ctx, shortURL)
+		assert.ErrorIs(t, err, config.ErrGone)
+	}
+	count, err := store.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
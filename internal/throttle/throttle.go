@@ -0,0 +1,84 @@
+// Package throttle limits repeated failed attempts, such as wrong passwords, per key.
+// Each key may fail a fixed number of times within a window; once the limit is reached,
+// further attempts are refused until the window that started with the first failure ends.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// entry counts the failures of a key within the current window.
+type entry struct {
+	failures int       // failures is the number of failed attempts in the window.
+	resetAt  time.Time // resetAt is the moment the window ends and the failures are forgotten.
+}
+
+// Limiter counts failed attempts per key. It is safe for concurrent use.
+type Limiter struct {
+	max       int               // max is the number of failures allowed per window.
+	window    time.Duration     // window is the period over which failures are counted.
+	entries   map[string]*entry // entries holds the keys with failures in their current window.
+	lastSweep time.Time         // lastSweep is the last time entries past their window were dropped.
+	mu        sync.Mutex        // mu protects entries and lastSweep.
+}
+
+// NewLimiter creates a Limiter that allows max failures per key within window.
+func NewLimiter(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:     max,
+		window:  window,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Blocked reports whether key has used up its failures in the current window
+// and, if so, how long it has to wait before the next attempt.
+func (l *Limiter) Blocked(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		return 0, false
+	}
+	if e.failures < l.max {
+		return 0, false
+	}
+	return e.resetAt.Sub(now), true
+}
+
+// Fail records a failed attempt for key. The first failure starts a new window.
+func (l *Limiter) Fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		e = &entry{resetAt: now.Add(l.window)}
+		l.entries[key] = e
+	}
+	e.failures++
+}
+
+// Reset forgets the failures of key, typically after a successful attempt.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// sweep drops the entries whose window has ended, at most once per window,
+// so that keys which stop failing do not accumulate. The caller must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, e := range l.entries {
+		if !now.Before(e.resetAt) {
+			delete(l.entries, key)
+		}
+	}
+	l.lastSweep = now
+}
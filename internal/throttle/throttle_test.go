@@ -0,0 +1,101 @@
+package throttle_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/throttle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		run         func(l *throttle.Limiter)
+		at          time.Time
+		wantBlocked bool
+		wantWait    time.Duration
+	}{
+		{
+			name:        "No Failures",
+			run:         func(l *throttle.Limiter) {},
+			at:          now,
+			wantBlocked: false,
+		},
+		{
+			name: "Below Limit",
+			run: func(l *throttle.Limiter) {
+				l.Fail("abc", now)
+				l.Fail("abc", now)
+			},
+			at:          now,
+			wantBlocked: false,
+		},
+		{
+			name: "Limit Reached",
+			run: func(l *throttle.Limiter) {
+				for i := 0; i < 3; i++ {
+					l.Fail("abc", now.Add(time.Duration(i)*time.Second))
+				}
+			},
+			at:          now.Add(10 * time.Second),
+			wantBlocked: true,
+			wantWait:    50 * time.Second,
+		},
+		{
+			name: "Window Ended",
+			run: func(l *throttle.Limiter) {
+				for i := 0; i < 3; i++ {
+					l.Fail("abc", now)
+				}
+			},
+			at:          now.Add(time.Minute),
+			wantBlocked: false,
+		},
+		{
+			name: "Reset After Success",
+			run: func(l *throttle.Limiter) {
+				for i := 0; i < 3; i++ {
+					l.Fail("abc", now)
+				}
+				l.Reset("abc")
+			},
+			at:          now,
+			wantBlocked: false,
+		},
+		{
+			name: "Other Key Unaffected",
+			run: func(l *throttle.Limiter) {
+				for i := 0; i < 3; i++ {
+					l.Fail("xyz", now)
+				}
+			},
+			at:          now,
+			wantBlocked: false,
+		},
+		{
+			name: "New Window After Expiry",
+			run: func(l *throttle.Limiter) {
+				for i := 0; i < 3; i++ {
+					l.Fail("abc", now)
+				}
+				l.Fail("abc", now.Add(2*time.Minute))
+			},
+			at:          now.Add(2 * time.Minute),
+			wantBlocked: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := throttle.NewLimiter(3, time.Minute)
+			tt.run(l)
+
+			wait, blocked := l.Blocked("abc", tt.at)
+			assert.Equal(t, tt.wantBlocked, blocked)
+			assert.Equal(t, tt.wantWait, wait)
+		})
+	}
+}
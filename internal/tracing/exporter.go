@@ -0,0 +1,232 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FileExporter appends spans to a file as JSON Lines, one object per span.
+// It is meant for local debugging and tests.
+type FileExporter struct {
+	file *os.File
+}
+
+// fileSpan is the JSON form of a span written by FileExporter.
+type fileSpan struct {
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Name         string         `json:"name"`
+	Kind         Kind           `json:"kind"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	DurationMS   float64        `json:"duration_ms"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// NewFileExporter opens path for appending, creating it if needed.
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{file: file}, nil
+}
+
+// Export appends the spans to the file.
+func (e *FileExporter) Export(_ context.Context, spans []SpanData) error {
+	w := bufio.NewWriter(e.file)
+	enc := json.NewEncoder(w)
+	for _, span := range spans {
+		record := fileSpan{
+			TraceID:    span.TraceID.String(),
+			SpanID:     span.SpanID.String(),
+			Name:       span.Name,
+			Kind:       span.Kind,
+			Start:      span.Start,
+			End:        span.End,
+			DurationMS: float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+			Attributes: span.Attributes,
+			Error:      span.Error,
+		}
+		if span.ParentSpanID.IsValid() {
+			record.ParentSpanID = span.ParentSpanID.String()
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Close closes the file.
+func (e *FileExporter) Close() error {
+	return e.file.Close()
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP over HTTP with JSON encoding.
+type OTLPExporter struct {
+	endpoint string       // endpoint is the URL of the traces endpoint, such as http://localhost:4318/v1/traces.
+	service  string       // service is the value of the service.name resource attribute.
+	client   *http.Client // client sends the export requests.
+}
+
+// NewOTLPExporter creates an exporter posting to endpoint on behalf of the named service.
+func NewOTLPExporter(endpoint, service string) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{},
+	}
+}
+
+// The OTLP/JSON request types; see opentelemetry-proto's trace_service.proto.
+// IDs are hex encoded and 64-bit integers are strings, as OTLP/JSON requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              Kind           `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// otlpStatusError is the OTLP status code of a failed span.
+const otlpStatusError = 2
+
+// Export posts the spans to the collector.
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Close releases idle connections to the collector.
+func (e *OTLPExporter) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// request converts spans into an OTLP export request.
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		if span.ParentSpanID.IsValid() {
+			s.ParentSpanID = span.ParentSpanID.String()
+		}
+		if span.Error != "" {
+			s.Status = &otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+		out = append(out, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]any{"service.name": e.service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: e.service}, Spans: out}},
+	}}}
+}
+
+// otlpAttributes converts attributes into OTLP key-values, ordered by key.
+// Values of unsupported types are formatted as strings.
+func otlpAttributes(attrs map[string]any) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		var value otlpValue
+		switch v := attrs[key].(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		out = append(out, otlpKeyValue{Key: key, Value: value})
+	}
+	return out
+}
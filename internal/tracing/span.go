@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+// SpanData is the recorded state of an ended span, as handed to an Exporter.
+type SpanData struct {
+	Name         string         // Name describes the operation.
+	TraceID      TraceID        // TraceID is the trace the span belongs to.
+	SpanID       SpanID         // SpanID identifies the span.
+	ParentSpanID SpanID         // ParentSpanID is the parent's ID, zero for a root span.
+	Kind         Kind           // Kind is the role of the span.
+	Start        time.Time      // Start is the moment the span started.
+	End          time.Time      // End is the moment the span ended.
+	Attributes   map[string]any // Attributes describe the operation.
+	Error        string         // Error is the error the operation failed with, if any.
+}
+
+// setAttribute sets an attribute, allocating the map on first use.
+func (d *SpanData) setAttribute(key string, value any) {
+	if d.Attributes == nil {
+		d.Attributes = make(map[string]any)
+	}
+	d.Attributes[key] = value
+}
+
+// Span is an operation being traced. A nil *Span is valid and ignores every call,
+// which is what Start returns when tracing is disabled.
+type Span struct {
+	tracer *Tracer    // tracer receives the span when it ends.
+	data   SpanData   // data is the state of the span.
+	ended  bool       // ended reports whether End was called.
+	mu     sync.Mutex // mu protects data and ended.
+}
+
+// SpanContext returns the span context to propagate to the span's children.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID, Sampled: true}
+}
+
+// SetName replaces the name of the span, for example once the route of a request is known.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttribute sets an attribute of the span. Values should be strings, integers, floats or booleans.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.setAttribute(key, value)
+}
+
+// RecordError marks the span as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End ends the span and hands it to the tracer for export. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.enqueue(data)
+}
@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// Exporter sends batches of ended spans to a tracing backend.
+type Exporter interface {
+	// Export sends a batch of spans. It is never called concurrently.
+	Export(ctx context.Context, spans []SpanData) error
+	// Close releases the resources of the exporter after the last Export.
+	Close() error
+}
+
+// Tracer creates spans and exports them in batches from a background goroutine, so that
+// ending a span never waits for the exporter. Spans ended while the buffer is full are dropped.
+type Tracer struct {
+	exporter      Exporter      // exporter receives the batches of ended spans.
+	spans         chan SpanData // spans buffers ended spans until they are exported.
+	batchSize     int           // batchSize is the number of buffered spans that triggers an export.
+	flushInterval time.Duration // flushInterval is the longest time a span stays buffered.
+	exportTimeout time.Duration // exportTimeout bounds each call to the exporter.
+	stop          chan struct{} // stop is closed by Shutdown to make run flush and return.
+	done          chan struct{} // done is closed when run has returned.
+	stopOnce      sync.Once     // stopOnce guards closing stop.
+	stopped       atomic.Bool   // stopped is set by Shutdown; later spans are dropped.
+	dropped       atomic.Uint64 // dropped counts the spans that could not be buffered.
+}
+
+// NewTracer creates a Tracer that buffers up to bufferSize ended spans and exports them through
+// exporter whenever batchSize spans are collected or flushInterval elapses, allowing each export
+// exportTimeout. It starts the export goroutine, which Shutdown stops.
+func NewTracer(exporter Exporter, bufferSize, batchSize int, flushInterval, exportTimeout time.Duration) *Tracer {
+	t := &Tracer{
+		exporter:      exporter,
+		spans:         make(chan SpanData, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		exportTimeout: exportTimeout,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// newSpan creates a recording span that is a child of parent, or a root span if parent is invalid.
+func (t *Tracer) newSpan(name string, parent SpanContext) *Span {
+	data := SpanData{
+		Name:   name,
+		SpanID: newSpanID(),
+		Kind:   KindInternal,
+		Start:  time.Now(),
+	}
+	if parent.IsValid() {
+		data.TraceID = parent.TraceID
+		data.ParentSpanID = parent.SpanID
+	} else {
+		data.TraceID = newTraceID()
+	}
+	return &Span{tracer: t, data: data}
+}
+
+// enqueue buffers an ended span without blocking.
+func (t *Tracer) enqueue(data SpanData) {
+	if t.stopped.Load() {
+		return
+	}
+	select {
+	case t.spans <- data:
+	default:
+		if t.dropped.Add(1) == 1 {
+			logger.Errorf("Span buffer is full, dropping spans")
+		}
+	}
+}
+
+// run collects buffered spans and exports them until Shutdown is called, then exports
+// the spans that are still buffered and closes the exporter.
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, t.batchSize)
+	for {
+		select {
+		case <-t.stop:
+			for {
+				select {
+				case data := <-t.spans:
+					batch = append(batch, data)
+				default:
+					t.export(batch)
+					if err := t.exporter.Close(); err != nil {
+						logger.Errorf("Error closing span exporter: %v", err)
+					}
+					return
+				}
+			}
+		case data := <-t.spans:
+			batch = append(batch, data)
+			if len(batch) >= t.batchSize {
+				t.export(batch)
+				batch = make([]SpanData, 0, t.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				t.export(batch)
+				batch = make([]SpanData, 0, t.batchSize)
+			}
+		}
+	}
+}
+
+// export hands a batch to the exporter, logging failures.
+func (t *Tracer) export(batch []SpanData) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), t.exportTimeout)
+	defer cancel()
+	if err := t.exporter.Export(ctx, batch); err != nil {
+		logger.Errorf("Error exporting %d spans: %v", len(batch), err)
+	}
+}
+
+// Shutdown stops accepting spans, exports the buffered ones and closes the exporter.
+// It returns ctx.Err() if ctx is done before that finishes.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() {
+		t.stopped.Store(true)
+		close(t.stop)
+	})
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
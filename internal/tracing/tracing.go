@@ -0,0 +1,219 @@
+// Package tracing implements a small distributed tracing layer in the spirit of OpenTelemetry.
+// Spans are started from a context, linked to their parent through it, propagated between
+// services with the W3C traceparent header and handed in batches to an Exporter, such as the
+// OTLP/HTTP exporter or the JSON file exporter.
+//
+// Until SetTracer installs a Tracer, Start records nothing and returns a nil *Span, whose
+// methods are no-ops, so instrumented code does not need to check whether tracing is enabled.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+)
+
+// TraceparentHeader is the name of the W3C Trace Context header carrying the parent span.
+const TraceparentHeader = "traceparent"
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether the trace ID is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// String returns the trace ID as 32 lowercase hex digits.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the span ID is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// String returns the span ID as 16 lowercase hex digits.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext is the part of a span that is propagated to its children, locally or remotely.
+type SpanContext struct {
+	TraceID TraceID // TraceID is the trace the span belongs to.
+	SpanID  SpanID  // SpanID identifies the span.
+	Sampled bool    // Sampled reports whether the span is recorded.
+}
+
+// IsValid reports whether both IDs of the span context are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a version 00 W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value. It reports false if the value is
+// malformed, uses the forbidden version ff, or carries an all-zero trace or span ID.
+// Values of future versions are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 {
+		return sc, false
+	}
+	if !decodeLower(parts[1], sc.TraceID[:]) || !decodeLower(parts[2], sc.SpanID[:]) {
+		return sc, false
+	}
+	var flags [1]byte
+	if !decodeLower(parts[3], flags[:]) {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	if !sc.IsValid() {
+		return sc, false
+	}
+	return sc, true
+}
+
+// decodeLower decodes s, which must consist of exactly 2*len(dst) lowercase hex digits, into dst.
+func decodeLower(s string, dst []byte) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// contextKey is the type of the context keys of this package.
+type contextKey int
+
+const (
+	// spanKey holds the current *Span of a context.
+	spanKey contextKey = iota
+	// remoteKey holds the SpanContext of a parent received from another service.
+	remoteKey
+)
+
+// ContextWithRemoteParent returns a copy of ctx in which spans started by Start become children
+// of sc, a span context received from another service.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// SpanFromContext returns the current span of ctx, or nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the span context that a span started from ctx would have as
+// its parent: the current span's, or else the remote parent's. It is invalid if there is neither.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey).(SpanContext)
+	return sc
+}
+
+// globalTracer is the Tracer used by Start; nil disables tracing.
+var globalTracer atomic.Pointer[Tracer]
+
+// SetTracer installs the Tracer used by Start. Passing nil disables tracing.
+func SetTracer(t *Tracer) {
+	globalTracer.Store(t)
+}
+
+// Kind describes the role of a span, using the numbering of OTLP.
+type Kind int
+
+// Span kinds.
+const (
+	KindInternal Kind = 1 // KindInternal is an operation within the service.
+	KindServer   Kind = 2 // KindServer handles a request received from a client.
+	KindClient   Kind = 3 // KindClient is a request sent to another service, such as the database.
+)
+
+// Option configures a span started by Start.
+type Option func(*SpanData)
+
+// WithKind sets the kind of the span; spans are KindInternal by default.
+func WithKind(kind Kind) Option {
+	return func(d *SpanData) {
+		d.Kind = kind
+	}
+}
+
+// WithAttributes sets attributes of the span as alternating keys and values.
+func WithAttributes(keyValues ...any) Option {
+	return func(d *SpanData) {
+		for i := 0; i+1 < len(keyValues); i += 2 {
+			if key, ok := keyValues[i].(string); ok {
+				d.setAttribute(key, keyValues[i+1])
+			}
+		}
+	}
+}
+
+// Start starts a span named name as a child of the span or remote parent of ctx, or as the root
+// of a new trace if there is none. It returns a context carrying the new span and the span itself,
+// which the caller must End. If tracing is disabled or the parent is not sampled, ctx is returned
+// unchanged with a nil span.
+func Start(ctx context.Context, name string, opts ...Option) (context.Context, *Span) {
+	tracer := globalTracer.Load()
+	if tracer == nil {
+		return ctx, nil
+	}
+	parent := SpanContextFromContext(ctx)
+	if parent.IsValid() && !parent.Sampled {
+		return ctx, nil
+	}
+
+	span := tracer.newSpan(name, parent)
+	for _, opt := range opts {
+		opt(&span.data)
+	}
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// newTraceID returns a random, valid trace ID.
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		for i := range id {
+			id[i] = byte(rand.Uint32())
+		}
+	}
+	return id
+}
+
+// newSpanID returns a random, valid span ID.
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		for i := range id {
+			id[i] = byte(rand.Uint32())
+		}
+	}
+	return id
+}
@@ -0,0 +1,263 @@
+package tracing_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingExporter keeps the exported spans in memory.
+type recordingExporter struct {
+	mu     sync.Mutex
+	spans  []tracing.SpanData
+	closed bool
+}
+
+func (e *recordingExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+	return nil
+}
+
+// installTracer installs a tracer exporting through exporter for the duration of the test.
+func installTracer(t *testing.T, exporter tracing.Exporter) *tracing.Tracer {
+	tracer := tracing.NewTracer(exporter, 16, 4, time.Hour, time.Second)
+	tracing.SetTracer(tracer)
+	t.Cleanup(func() {
+		tracing.SetTracer(nil)
+		tracer.Shutdown(context.Background())
+	})
+	return tracer
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{
+			name:    "Sampled",
+			value:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			ok:      true,
+			sampled: true,
+		},
+		{
+			name:  "Not Sampled",
+			value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			ok:    true,
+		},
+		{
+			name:    "Future Version",
+			value:   "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			ok:      true,
+			sampled: true,
+		},
+		{
+			name:  "Forbidden Version",
+			value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:  "Zero Trace ID",
+			value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+		{
+			name:  "Zero Span ID",
+			value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		},
+		{
+			name:  "Uppercase Hex",
+			value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01",
+		},
+		{
+			name:  "Too Short",
+			value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		},
+		{
+			name: "Empty",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sc, ok := tracing.ParseTraceparent(test.value)
+			assert.Equal(t, test.ok, ok)
+			if !test.ok {
+				return
+			}
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+			assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+			assert.Equal(t, test.sampled, sc.Sampled)
+		})
+	}
+
+	sc, ok := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+}
+
+func TestStartDisabled(t *testing.T) {
+	ctx := context.Background()
+	got, span := tracing.Start(ctx, "noop")
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, got)
+
+	// The methods of a nil span are no-ops.
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("failed"))
+	span.End()
+	assert.False(t, span.SpanContext().IsValid())
+}
+
+func TestSpanHierarchy(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := installTracer(t, exporter)
+
+	remote, ok := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	ctx := tracing.ContextWithRemoteParent(context.Background(), remote)
+
+	ctx, parent := tracing.Start(ctx, "parent", tracing.WithKind(tracing.KindServer))
+	require.NotNil(t, parent)
+	_, child := tracing.Start(ctx, "child", tracing.WithAttributes("db.system", "postgresql"))
+	child.RecordError(errors.New("failed"))
+	child.End()
+	parent.End()
+	parent.End()
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	assert.True(t, exporter.closed)
+	require.Len(t, exporter.spans, 2)
+
+	childData, parentData := exporter.spans[0], exporter.spans[1]
+	assert.Equal(t, "parent", parentData.Name)
+	assert.Equal(t, tracing.KindServer, parentData.Kind)
+	assert.Equal(t, remote.TraceID, parentData.TraceID)
+	assert.Equal(t, remote.SpanID, parentData.ParentSpanID)
+
+	assert.Equal(t, "child", childData.Name)
+	assert.Equal(t, tracing.KindInternal, childData.Kind)
+	assert.Equal(t, remote.TraceID, childData.TraceID)
+	assert.Equal(t, parentData.SpanID, childData.ParentSpanID)
+	assert.Equal(t, "postgresql", childData.Attributes["db.system"])
+	assert.Equal(t, "failed", childData.Error)
+	assert.False(t, childData.End.Before(childData.Start))
+}
+
+func TestStartNotSampled(t *testing.T) {
+	installTracer(t, &recordingExporter{})
+
+	remote, ok := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.True(t, ok)
+	_, span := tracing.Start(tracing.ContextWithRemoteParent(context.Background(), remote), "ignored")
+	assert.Nil(t, span)
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := tracing.NewFileExporter(path)
+	require.NoError(t, err)
+	tracer := installTracer(t, exporter)
+
+	ctx, root := tracing.Start(context.Background(), "root")
+	_, child := tracing.Start(ctx, "child")
+	child.End()
+	root.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "child", records[0]["name"])
+	assert.Equal(t, records[1]["span_id"], records[0]["parent_span_id"])
+	assert.Equal(t, records[1]["trace_id"], records[0]["trace_id"])
+	assert.NotContains(t, records[1], "parent_span_id")
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	exporter := tracing.NewOTLPExporter(server.URL, "shortener")
+	start := time.Unix(1700000000, 0)
+	span := tracing.SpanData{
+		Name:       "GET /{id}",
+		TraceID:    tracing.TraceID{1},
+		SpanID:     tracing.SpanID{2},
+		Kind:       tracing.KindServer,
+		Start:      start,
+		End:        start.Add(time.Millisecond),
+		Attributes: map[string]any{"http.status_code": 500},
+		Error:      "Internal Server Error",
+	}
+	require.NoError(t, exporter.Export(context.Background(), []tracing.SpanData{span}))
+	require.NoError(t, exporter.Close())
+
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID           string `json:"traceId"`
+					ParentSpanID      string `json:"parentSpanId"`
+					StartTimeUnixNano string `json:"startTimeUnixNano"`
+					Attributes        []struct {
+						Key   string `json:"key"`
+						Value struct {
+							IntValue string `json:"intValue"`
+						} `json:"value"`
+					} `json:"attributes"`
+					Status struct {
+						Code int `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(body, &req))
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans[0].Spans, 1)
+
+	got := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "01000000000000000000000000000000", got.TraceID)
+	assert.Empty(t, got.ParentSpanID)
+	assert.Equal(t, "1700000000000000000", got.StartTimeUnixNano)
+	require.Len(t, got.Attributes, 1)
+	assert.Equal(t, "500", got.Attributes[0].Value.IntValue)
+	assert.Equal(t, 2, got.Status.Code)
+}
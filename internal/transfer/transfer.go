@@ -0,0 +1,148 @@
+// Package transfer moves URL records between storage backends.
+// Records are streamed as JSON Lines, one models.URLData object per line, so that a dump
+// produced by Export on one backend can be loaded by Import into any other.
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+)
+
+// maxLineSize is the longest JSON line Import accepts.
+const maxLineSize = 1024 * 1024
+
+// Conflict describes a record that Import did not store.
+type Conflict struct {
+	Line        int    // Line is the number of the line in the input, starting at 1.
+	ShortURL    string // ShortURL is the short URL of the record.
+	OriginalURL string // OriginalURL is the original URL of the record.
+	Reason      error  // Reason is config.ErrAliasTaken or config.ErrExists.
+}
+
+// Summary reports the outcome of an Import.
+type Summary struct {
+	Read      int        // Read is the number of records read from the input.
+	Imported  int        // Imported is the number of records stored, or that would be stored in a dry run.
+	Conflicts []Conflict // Conflicts lists the records that were skipped.
+}
+
+// Export writes every record of store to w as JSON Lines.
+// It returns the number of records written.
+func Export(ctx context.Context, store storage.Storage, w io.Writer) (int, error) {
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+
+	count := 0
+	err := store.ExportURLs(ctx, func(data models.URLData) error {
+		if err := encoder.Encode(data); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, writer.Flush()
+}
+
+// Import reads JSON Lines records from r and stores them in store, preserving short URLs and owners.
+// Records whose short URL or original URL is already stored are skipped and reported as conflicts.
+//
+// With dryRun set nothing is written: conflicts are detected against the records already in store
+// and those read earlier from r, which is what a real import would run into.
+// A malformed line aborts the import; records stored before it are kept.
+func Import(ctx context.Context, store storage.Storage, r io.Reader, dryRun bool) (Summary, error) {
+	var summary Summary
+
+	importURL := store.ImportURL
+	if dryRun {
+		sim, err := newSimulation(ctx, store)
+		if err != nil {
+			return summary, err
+		}
+		importURL = sim.importURL
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var data models.URLData
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			return summary, fmt.Errorf("line %d: %w", line, err)
+		}
+		if data.ShortURL == "" || data.OriginalURL == "" {
+			return summary, fmt.Errorf("line %d: short and original URLs are required", line)
+		}
+		summary.Read++
+
+		err := importURL(ctx, data)
+		switch {
+		case err == nil:
+			summary.Imported++
+		case errors.Is(err, config.ErrAliasTaken), errors.Is(err, config.ErrExists):
+			summary.Conflicts = append(summary.Conflicts, Conflict{
+				Line:        line,
+				ShortURL:    data.ShortURL,
+				OriginalURL: data.OriginalURL,
+				Reason:      err,
+			})
+		default:
+			return summary, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// simulation mirrors the conflict rules of storage.Storage.ImportURL without writing anything.
+type simulation struct {
+	shortURLs    map[string]struct{} // shortURLs holds the short URLs stored or accepted so far.
+	originalURLs map[string]struct{} // originalURLs holds the original URLs stored or accepted so far.
+}
+
+// newSimulation loads the short and original URLs already present in store.
+func newSimulation(ctx context.Context, store storage.Storage) (*simulation, error) {
+	sim := &simulation{
+		shortURLs:    make(map[string]struct{}),
+		originalURLs: make(map[string]struct{}),
+	}
+	err := store.ExportURLs(ctx, func(data models.URLData) error {
+		sim.shortURLs[data.ShortURL] = struct{}{}
+		sim.originalURLs[data.OriginalURL] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sim, nil
+}
+
+// importURL accepts a record unless its short or original URL has been seen before.
+func (s *simulation) importURL(_ context.Context, data models.URLData) error {
+	if _, ok := s.shortURLs[data.ShortURL]; ok {
+		return config.ErrAliasTaken
+	}
+	if _, ok := s.originalURLs[data.OriginalURL]; ok {
+		return config.ErrExists
+	}
+	s.shortURLs[data.ShortURL] = struct{}{}
+	s.originalURLs[data.OriginalURL] = struct{}{}
+	return nil
+}
@@ -0,0 +1,87 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage/inmemory"
+	"github.com/gleb-korostelev/short-url.git/internal/transfer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	owner := uuid.New()
+	source := inmemory.NewMemoryStorage(map[string]models.URLData{
+		"abc": {UUID: owner, ShortURL: "abc", OriginalURL: "http://example.com/a"},
+		"def": {UUID: owner, ShortURL: "def", OriginalURL: "http://example.com/d", DeletedFlag: true},
+	})
+
+	var dump bytes.Buffer
+	count, err := transfer.Export(ctx, source, &dump)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	target := inmemory.NewMemoryStorage(map[string]models.URLData{
+		"def": {UUID: uuid.New(), ShortURL: "def", OriginalURL: "http://example.com/other"},
+	})
+
+	summary, err := transfer.Import(ctx, target, bytes.NewReader(dump.Bytes()), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, summary.Read)
+	assert.Equal(t, 1, summary.Imported)
+	if assert.Len(t, summary.Conflicts, 1) {
+		assert.Equal(t, "def", summary.Conflicts[0].ShortURL)
+		assert.ErrorIs(t, summary.Conflicts[0].Reason, config.ErrAliasTaken)
+	}
+
+	var imported []models.URLData
+	assert.NoError(t, target.ExportURLs(ctx, func(data models.URLData) error {
+		if data.ShortURL == "abc" {
+			imported = append(imported, data)
+		}
+		return nil
+	}))
+	if assert.Len(t, imported, 1) {
+		assert.Equal(t, owner, imported[0].UUID)
+		assert.Equal(t, "http://example.com/a", imported[0].OriginalURL)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	ctx := context.Background()
+	target := inmemory.NewMemoryStorage(map[string]models.URLData{
+		"abc": {UUID: uuid.New(), ShortURL: "abc", OriginalURL: "http://example.com/a"},
+	})
+	owner := uuid.New().String()
+	input := strings.Join([]string{
+		`{"UUID":"` + owner + `","ShortURL":"new","OriginalURL":"http://example.com/new"}`,
+		`{"UUID":"` + owner + `","ShortURL":"abc","OriginalURL":"http://example.com/b"}`,
+		`{"UUID":"` + owner + `","ShortURL":"dup","OriginalURL":"http://example.com/new"}`,
+	}, "\n")
+
+	summary, err := transfer.Import(ctx, target, strings.NewReader(input), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, summary.Read)
+	assert.Equal(t, 1, summary.Imported)
+	if assert.Len(t, summary.Conflicts, 2) {
+		assert.ErrorIs(t, summary.Conflicts[0].Reason, config.ErrAliasTaken)
+		assert.ErrorIs(t, summary.Conflicts[1].Reason, config.ErrExists)
+		assert.Equal(t, 3, summary.Conflicts[1].Line)
+	}
+
+	urls, err := target.CountURLs(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, urls)
+}
+
+func TestImportMalformedLine(t *testing.T) {
+	target := inmemory.NewMemoryStorage(map[string]models.URLData{})
+	_, err := transfer.Import(context.Background(), target, strings.NewReader("{not json}\n"), false)
+	assert.ErrorContains(t, err, "line 1")
+}
@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// ClickRecorder buffers redirect events and persists them in batches through a DBWorkerPool,
+// so that recording a redirect never waits for the storage.
+type ClickRecorder struct {
+	pool          *DBWorkerPool     // pool executes the flush tasks.
+	store         storage.Storage   // store receives the batches of redirect events.
+	clicks        chan models.Click // clicks buffers events between Record and Run.
+	batchSize     int               // batchSize is the number of buffered events that triggers a flush.
+	flushInterval time.Duration     // flushInterval is the longest time an event stays buffered.
+}
+
+// NewClickRecorder creates a ClickRecorder that buffers up to bufferSize events and flushes them
+// to store through pool whenever batchSize events are collected or flushInterval elapses.
+func NewClickRecorder(pool *DBWorkerPool, store storage.Storage, bufferSize, batchSize int, flushInterval time.Duration) *ClickRecorder {
+	return &ClickRecorder{
+		pool:          pool,
+		store:         store,
+		clicks:        make(chan models.Click, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// Record enqueues a redirect event without blocking.
+// It returns false if the buffer is full and the event was dropped.
+func (r *ClickRecorder) Record(click models.Click) bool {
+	select {
+	case r.clicks <- click:
+		return true
+	default:
+		logger.Errorf("Click buffer is full, dropping click for %s", click.ShortURL)
+		return false
+	}
+}
+
+// Run collects buffered events and flushes them until ctx is cancelled.
+// On cancellation it flushes the events that are still buffered and waits for that flush to finish.
+// It blocks, so it is typically started in its own goroutine.
+func (r *ClickRecorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.Click, 0, r.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case click := <-r.clicks:
+					batch = append(batch, click)
+				default:
+					r.flush(batch, true)
+					return
+				}
+			}
+		case click := <-r.clicks:
+			batch = append(batch, click)
+			if len(batch) >= r.batchSize {
+				r.flush(batch, false)
+				batch = make([]models.Click, 0, r.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				r.flush(batch, false)
+				batch = make([]models.Click, 0, r.batchSize)
+			}
+		}
+	}
+}
+
+// flush submits a batch of events to the worker pool. If wait is true it blocks until
+// the batch has been stored.
+func (r *ClickRecorder) flush(batch []models.Click, wait bool) {
+	if len(batch) == 0 {
+		return
+	}
+
+	task := Task{
+		Action: func(ctx context.Context) error {
+			if err := r.store.SaveClicks(ctx, batch); err != nil {
+				logger.Errorf("Error saving %d clicks: %v", len(batch), err)
+				return err
+			}
+			return nil
+		},
+	}
+	if wait {
+		task.Done = make(chan struct{})
+	}
+
+	r.pool.AddTask(task)
+	if wait {
+		<-task.Done
+	}
+}
@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// deleteRequest is a call to BatchDeleter.MarkURLsAsDeleted waiting to be flushed.
+type deleteRequest struct {
+	deletions []models.URLDeletion // deletions lists the URLs to delete.
+	result    chan error           // result receives the outcome of the flush that included the request.
+}
+
+// BatchDeleter coalesces the URL deletions of concurrent callers and applies them to the storage
+// in bulk, so that many small deletions cost a single update. Deletions are flushed whenever
+// batchSize of them are collected or flushInterval elapses, whichever comes first.
+type BatchDeleter struct {
+	store         storage.Storage    // store receives the bulk deletions.
+	requests      chan deleteRequest // requests buffers the calls waiting to be flushed.
+	batchSize     int                // batchSize is the number of buffered deletions that triggers a flush.
+	flushInterval time.Duration      // flushInterval is the longest time a deletion stays buffered.
+	mu            sync.RWMutex       // mu guards closed against concurrent sends to requests.
+	closed        bool               // closed is set by Shutdown once requests is closed.
+	done          chan struct{}      // done is closed once the final flush has finished.
+}
+
+// NewBatchDeleter creates a BatchDeleter that buffers up to bufferSize calls and flushes their
+// deletions to store whenever batchSize deletions are collected or flushInterval elapses.
+// It starts flushing at once; Shutdown must be called to flush the remaining deletions and stop it.
+func NewBatchDeleter(store storage.Storage, bufferSize, batchSize int, flushInterval time.Duration) *BatchDeleter {
+	d := &BatchDeleter{
+		store:         store,
+		requests:      make(chan deleteRequest, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// MarkURLsAsDeleted deletes shortURLs of the user together with the deletions of other callers and
+// returns the outcome of the bulk update that included them. While the buffer is full it waits for
+// a flush, which applies backpressure to the callers. It returns ctx's error if ctx is done first,
+// and config.ErrDeleterClosed after Shutdown.
+func (d *BatchDeleter) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
+	if len(shortURLs) == 0 {
+		return nil
+	}
+	req := deleteRequest{
+		deletions: make([]models.URLDeletion, len(shortURLs)),
+		result:    make(chan error, 1),
+	}
+	for i, shortURL := range shortURLs {
+		req.deletions[i] = models.URLDeletion{UserID: userID, ShortURL: shortURL}
+	}
+
+	if err := d.enqueue(ctx, req); err != nil {
+		return err
+	}
+	select {
+	case err := <-req.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue adds req to the buffer, waiting while it is full.
+func (d *BatchDeleter) enqueue(ctx context.Context, req deleteRequest) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return config.ErrDeleterClosed
+	}
+	select {
+	case d.requests <- req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects the buffered calls and flushes them until the buffer is closed by Shutdown,
+// then flushes the remaining ones.
+func (d *BatchDeleter) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.flushInterval)
+	defer ticker.Stop()
+
+	var pending []deleteRequest
+	size := 0
+	for {
+		select {
+		case req, ok := <-d.requests:
+			if !ok {
+				d.flush(pending, size)
+				return
+			}
+			pending = append(pending, req)
+			size += len(req.deletions)
+			if size >= d.batchSize {
+				d.flush(pending, size)
+				pending, size = nil, 0
+			}
+		case <-ticker.C:
+			if len(pending) > 0 {
+				d.flush(pending, size)
+				pending, size = nil, 0
+			}
+		}
+	}
+}
+
+// flush applies the deletions of the pending calls in a single bulk update and reports its
+// outcome to every call.
+func (d *BatchDeleter) flush(pending []deleteRequest, size int) {
+	if len(pending) == 0 {
+		return
+	}
+
+	deletions := make([]models.URLDeletion, 0, size)
+	for _, req := range pending {
+		deletions = append(deletions, req.deletions...)
+	}
+	ctx, span := tracing.Start(context.Background(), "worker.delete_batch")
+	span.SetAttribute("delete.batch_size", len(deletions))
+	err := d.store.MarkURLsAsDeletedBatch(ctx, deletions)
+	if err != nil {
+		span.RecordError(err)
+		logger.Errorf("Error deleting a batch of %d URLs: %v", len(deletions), err)
+	}
+	span.End()
+
+	for _, req := range pending {
+		req.result <- err
+	}
+}
+
+// Shutdown stops accepting deletions, flushes the buffered ones and waits for that flush to finish.
+func (d *BatchDeleter) Shutdown() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.requests)
+	}
+	d.mu.Unlock()
+	<-d.done
+}
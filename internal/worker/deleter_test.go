@@ -0,0 +1,151 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deleteConcurrently deletes one short URL per user through deleter from separate goroutines
+// and returns the error of every call.
+func deleteConcurrently(deleter *worker.BatchDeleter, users ...string) []error {
+	errs := make([]error, len(users))
+	var wg sync.WaitGroup
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = deleter.MarkURLsAsDeleted(context.Background(), user, []string{"url-" + user})
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestBatchDeleter(t *testing.T) {
+	tests := []struct {
+		name          string
+		batchSize     int
+		flushInterval time.Duration
+		storeErr      error
+		expectedCalls int
+	}{
+		{
+			name:          "Flushed When The Batch Is Full",
+			batchSize:     3,
+			flushInterval: time.Hour,
+			expectedCalls: 1,
+		},
+		{
+			name:          "Flushed When The Interval Elapses",
+			batchSize:     100,
+			flushInterval: 50 * time.Millisecond,
+			expectedCalls: 1,
+		},
+		{
+			name:          "Storage Error Reported To Every Caller",
+			batchSize:     3,
+			flushInterval: time.Hour,
+			storeErr:      errors.New("database is down"),
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var flushed []models.URLDeletion
+			mockStore := mock_db.NewMockStorage(ctrl)
+			mockStore.EXPECT().MarkURLsAsDeletedBatch(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, deletions []models.URLDeletion) error {
+					flushed = append(flushed, deletions...)
+					return tt.storeErr
+				}).Times(tt.expectedCalls)
+
+			deleter := worker.NewBatchDeleter(mockStore, 10, tt.batchSize, tt.flushInterval)
+			defer deleter.Shutdown()
+
+			errs := deleteConcurrently(deleter, "a", "b", "c")
+			for _, err := range errs {
+				assert.Equal(t, tt.storeErr, err)
+			}
+			assert.ElementsMatch(t, []models.URLDeletion{
+				{UserID: "a", ShortURL: "url-a"},
+				{UserID: "b", ShortURL: "url-b"},
+				{UserID: "c", ShortURL: "url-c"},
+			}, flushed)
+		})
+	}
+}
+
+func TestBatchDeleterShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_db.NewMockStorage(ctrl)
+	mockStore.EXPECT().MarkURLsAsDeletedBatch(gomock.Any(),
+		[]models.URLDeletion{{UserID: "user", ShortURL: "abc"}}).Return(nil)
+
+	deleter := worker.NewBatchDeleter(mockStore, 10, 100, time.Hour)
+	result := make(chan error, 1)
+	go func() {
+		result <- deleter.MarkURLsAsDeleted(context.Background(), "user", []string{"abc"})
+	}()
+
+	// Give the call time to be buffered; it is only flushed by Shutdown.
+	time.Sleep(20 * time.Millisecond)
+	deleter.Shutdown()
+	require.NoError(t, <-result)
+
+	err := deleter.MarkURLsAsDeleted(context.Background(), "user", []string{"def"})
+	assert.ErrorIs(t, err, config.ErrDeleterClosed)
+}
+
+func TestBatchDeleterBackpressure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	mockStore := mock_db.NewMockStorage(ctrl)
+	mockStore.EXPECT().MarkURLsAsDeletedBatch(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, []models.URLDeletion) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}).AnyTimes()
+
+	deleter := worker.NewBatchDeleter(mockStore, 1, 1, time.Hour)
+	defer deleter.Shutdown()
+
+	// The first call blocks the flush and the second one fills the buffer.
+	var wg sync.WaitGroup
+	for _, user := range []string{"a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, deleter.MarkURLsAsDeleted(context.Background(), user, []string{"abc"}))
+		}()
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := deleter.MarkURLsAsDeleted(ctx, "c", []string{"abc"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	wg.Wait()
+}
@@ -0,0 +1,158 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/jobs"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+	"github.com/google/uuid"
+)
+
+// URLDeleter deletes short URLs of a user. It is implemented by storage.Storage and by BatchDeleter.
+type URLDeleter interface {
+	MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error
+}
+
+// DeletionJobs accepts requests to delete short URLs as jobs of a persistent queue and drains the
+// queue through a DBWorkerPool. A failed job is retried with exponential backoff and dead-lettered
+// after a maximum number of attempts.
+type DeletionJobs struct {
+	pool         *DBWorkerPool // pool executes the jobs.
+	queue        jobs.Queue    // queue persists the jobs until they succeed or are dead-lettered.
+	deleter      URLDeleter    // deleter deletes the URLs of the jobs.
+	pollInterval time.Duration // pollInterval is how often the queue is checked for due jobs.
+	maxAttempts  int           // maxAttempts is the number of attempts after which a job is dead-lettered.
+	baseDelay    time.Duration // baseDelay is the delay before the first retry.
+	maxDelay     time.Duration // maxDelay caps the delay between two attempts.
+	wake         chan struct{} // wake signals Run that a job was submitted.
+}
+
+// NewDeletionJobs creates a DeletionJobs that deletes URLs through deleter by running the jobs of queue
+// through pool. The queue is checked every pollInterval and whenever a job is submitted. A failed job
+// is retried after baseDelay, doubled for every further attempt up to maxDelay, and dead-lettered
+// once it has failed maxAttempts times.
+func NewDeletionJobs(pool *DBWorkerPool, queue jobs.Queue, deleter URLDeleter, pollInterval time.Duration,
+	maxAttempts int, baseDelay, maxDelay time.Duration) *DeletionJobs {
+	return &DeletionJobs{
+		pool:         pool,
+		queue:        queue,
+		deleter:      deleter,
+		pollInterval: pollInterval,
+		maxAttempts:  maxAttempts,
+		baseDelay:    baseDelay,
+		maxDelay:     maxDelay,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Submit enqueues a job deleting shortURLs of the user and returns it. Once Submit returns,
+// the job survives a restart of the service in database and file mode.
+func (d *DeletionJobs) Submit(ctx context.Context, userID string, shortURLs []string) (models.Job, error) {
+	now := time.Now().UTC()
+	job := models.Job{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		ShortURLs:     shortURLs,
+		Status:        config.JobStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := d.queue.Enqueue(ctx, job); err != nil {
+		return models.Job{}, err
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Job retrieves a job by its ID. It returns config.ErrNotFound if the job does not exist.
+func (d *DeletionJobs) Job(ctx context.Context, id string) (models.Job, error) {
+	return d.queue.Get(ctx, id)
+}
+
+// Run starts the due jobs until ctx is cancelled, and prunes the jobs finished more than
+// config.JobRetention ago. It blocks, so it is typically started in its own goroutine.
+// Jobs still running when it returns are finished by the pool; jobs interrupted by a crash
+// are started again once their lease runs out.
+func (d *DeletionJobs) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(config.JobRetention / 24)
+	defer pruneTicker.Stop()
+
+	d.drain(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.drain(ctx)
+		case <-d.wake:
+			d.drain(ctx)
+		case <-pruneTicker.C:
+			removed, err := d.queue.Prune(ctx, time.Now().Add(-config.JobRetention))
+			if err != nil {
+				logger.Errorf("Error pruning finished jobs: %v", err)
+			} else if removed > 0 {
+				logger.Infof("%d finished jobs were pruned.", removed)
+			}
+		}
+	}
+}
+
+// drain claims the due jobs one by one and submits them to the pool, which blocks while all
+// workers are busy.
+func (d *DeletionJobs) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, ok, err := d.queue.Claim(ctx, time.Now())
+		if err != nil {
+			logger.Errorf("Error claiming job: %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		d.pool.AddTask(Task{Action: d.execute(job)})
+	}
+}
+
+// execute returns the action running a claimed job and recording its outcome in the queue.
+func (d *DeletionJobs) execute(job models.Job) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		err := d.deleter.MarkURLsAsDeleted(ctx, job.UserID, job.ShortURLs)
+
+		// The outcome is recorded even if the attempt was cancelled, so that the job is retried.
+		ctx = context.WithoutCancel(ctx)
+		var recordErr error
+		switch {
+		case err == nil:
+			recordErr = d.queue.Complete(ctx, job.ID)
+		case job.Attempts >= d.maxAttempts:
+			logger.Errorf("Job %s failed %d times and was dead-lettered: %v", job.ID, job.Attempts, err)
+			recordErr = d.queue.Bury(ctx, job.ID, err.Error())
+		default:
+			recordErr = d.queue.Retry(ctx, job.ID, err.Error(), time.Now().Add(d.retryDelay(job.Attempts)))
+		}
+		if recordErr != nil {
+			logger.Errorf("Error recording the outcome of job %s: %v", job.ID, recordErr)
+		}
+		return err
+	}
+}
+
+// retryDelay returns the delay before the attempt following the given failed attempt:
+// baseDelay after the first one, doubling with every further attempt up to maxDelay.
+func (d *DeletionJobs) retryDelay(attempt int) time.Duration {
+	delay := d.baseDelay
+	for i := 1; i < attempt && delay < d.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxDelay)
+}
@@ -0,0 +1,96 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/jobs"
+	"github.com/gleb-korostelev/short-url.git/internal/models"
+	"github.com/gleb-korostelev/short-url.git/internal/worker"
+	mock_db "github.com/gleb-korostelev/short-url.git/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForStatus polls the job until it reaches status or the test times out.
+func waitForStatus(t *testing.T, deletionJobs *worker.DeletionJobs, id, status string) models.Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := deletionJobs.Job(context.Background(), id)
+		require.NoError(t, err)
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeletionJobs(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		expectedStatus   string
+		expectedAttempts int
+		expectedError    string
+	}{
+		{
+			name:             "First Attempt Succeeds",
+			expectedStatus:   config.JobStatusDone,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Retried Until It Succeeds",
+			failures:         2,
+			expectedStatus:   config.JobStatusDone,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "Dead-Lettered",
+			failures:         3,
+			expectedStatus:   config.JobStatusDead,
+			expectedAttempts: 3,
+			expectedError:    "database is down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mock_db.NewMockStorage(ctrl)
+			if tt.failures > 0 {
+				mockStore.EXPECT().MarkURLsAsDeleted(gomock.Any(), "user", []string{"abc"}).
+					Return(errors.New("database is down")).Times(tt.failures)
+			}
+			if tt.expectedStatus == config.JobStatusDone {
+				mockStore.EXPECT().MarkURLsAsDeleted(gomock.Any(), "user", []string{"abc"}).Return(nil)
+			}
+
+			pool := worker.NewDBWorkerPool(1)
+			defer pool.Shutdown()
+			deletionJobs := worker.NewDeletionJobs(pool, jobs.NewMemoryQueue(time.Minute), mockStore,
+				time.Millisecond, 3, time.Millisecond, 4*time.Millisecond)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go deletionJobs.Run(ctx)
+
+			job, err := deletionJobs.Submit(context.Background(), "user", []string{"abc"})
+			require.NoError(t, err)
+			assert.Equal(t, config.JobStatusPending, job.Status)
+
+			job = waitForStatus(t, deletionJobs, job.ID, tt.expectedStatus)
+			assert.Equal(t, tt.expectedAttempts, job.Attempts)
+			assert.Equal(t, tt.expectedError, job.LastError)
+		})
+	}
+}
@@ -7,6 +7,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/config"
+	"github.com/gleb-korostelev/short-url.git/internal/metrics"
+	"github.com/gleb-korostelev/short-url.git/internal/tracing"
 )
 
 // Task represents a unit of work to be executed by the worker pool.
@@ -14,21 +20,40 @@ import (
 type Task struct {
 	Action func(ctx context.Context) error // Action is the function that performs the task.
 	Done   chan struct{}                   // Done is used to signal the completion of the task.
+	Ctx    context.Context                 // Ctx is the context of the submitter, such as the request; nil means context.Background().
 }
 
 // DBWorkerPool manages a pool of worker goroutines that execute Tasks.
 type DBWorkerPool struct {
-	taskQueue  chan Task      // taskQueue is a channel that holds tasks to be processed by the workers.
-	wg         sync.WaitGroup // wg is used to wait for all workers to finish processing before shutdown.
-	maxWorkers int            // maxWorkers defines the maximum number of worker goroutines.
+	taskQueue       chan Task          // taskQueue is a channel that holds tasks to be processed by the workers.
+	wg              sync.WaitGroup     // wg is used to wait for all workers to finish processing before shutdown.
+	maxWorkers      int                // maxWorkers defines the maximum number of worker goroutines.
+	ctx             context.Context    // ctx is cancelled once Shutdown gives up waiting, cancelling the running tasks.
+	cancel          context.CancelFunc // cancel cancels ctx.
+	shutdownTimeout time.Duration      // shutdownTimeout is how long Shutdown waits for tasks before cancelling them.
+	queued          atomic.Int64       // queued is the number of AddTask calls waiting for a free worker.
+	busy            atomic.Int64       // busy is the number of workers executing a task.
+	failures        atomic.Uint64      // failures is the number of tasks whose Action returned an error.
+}
+
+// PoolStats is a snapshot of the activity of a DBWorkerPool.
+type PoolStats struct {
+	Workers  int    // Workers is the number of worker goroutines.
+	Queued   int    // Queued is the number of submitted tasks waiting for a free worker.
+	Busy     int    // Busy is the number of workers executing a task.
+	Failures uint64 // Failures is the number of tasks that returned an error since the pool started.
 }
 
 // NewDBWorkerPool initializes a new DBWorkerPool with a specified number of workers.
 // maxWorkers specifies the maximum number of concurrent workers in the pool.
 func NewDBWorkerPool(maxWorkers int) *DBWorkerPool {
+	ctx, cancel := context.WithCancel(context.Background())
 	pool := &DBWorkerPool{
-		taskQueue:  make(chan Task),
-		maxWorkers: maxWorkers,
+		taskQueue:       make(chan Task),
+		maxWorkers:      maxWorkers,
+		ctx:             ctx,
+		cancel:          cancel,
+		shutdownTimeout: config.WorkerShutdownTimeout,
 	}
 
 	pool.wg.Add(maxWorkers)
@@ -44,22 +69,100 @@ func NewDBWorkerPool(maxWorkers int) *DBWorkerPool {
 func (p *DBWorkerPool) worker() {
 	defer p.wg.Done()
 	for task := range p.taskQueue {
-		if err := task.Action(context.Background()); err != nil {
+		p.busy.Add(1)
+		ctx, cancel := p.taskContext(task.Ctx)
+		ctx, span := tracing.Start(ctx, "worker.task")
+		if err := task.Action(ctx); err != nil {
+			p.failures.Add(1)
+			span.RecordError(err)
 			fmt.Printf("Error executing task: %v\n", err)
 		}
+		span.End()
+		cancel()
+		p.busy.Add(-1)
 		if task.Done != nil {
 			close(task.Done)
 		}
 	}
 }
 
-// AddTask submits a new Task to the pool. It adds the Task to the taskQueue.
-func (p *DBWorkerPool) AddTask(task Task) {
-	p.taskQueue <- task
+// taskContext derives the context passed to a Task's Action from the Task's Ctx.
+// It is cancelled when parent is, or when Shutdown cancels the running tasks.
+// The returned function releases its resources and must be called once the Action returns.
+func (p *DBWorkerPool) taskContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(p.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// AddTask submits a new Task to the pool. It adds the Task to the taskQueue,
+// blocking until a worker is free to take it. The wait is traced as a span of the Task's Ctx.
+// If the Task's Ctx is done before a worker takes the Task, the Task is dropped and the
+// context's error is returned; its Done channel is then never closed.
+func (p *DBWorkerPool) AddTask(task Task) error {
+	p.queued.Add(1)
+	defer p.queued.Add(-1)
+	if task.Ctx == nil {
+		p.taskQueue <- task
+		return nil
+	}
+
+	_, span := tracing.Start(task.Ctx, "worker.wait")
+	defer span.End()
+	select {
+	case p.taskQueue <- task:
+		return nil
+	case <-task.Ctx.Done():
+		span.RecordError(task.Ctx.Err())
+		return task.Ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the pool's activity.
+func (p *DBWorkerPool) Stats() PoolStats {
+	return PoolStats{
+		Workers:  p.maxWorkers,
+		Queued:   int(p.queued.Load()),
+		Busy:     int(p.busy.Load()),
+		Failures: p.failures.Load(),
+	}
+}
+
+// RegisterMetrics exposes the pool's queue depth, busy workers, size and task failures in reg.
+func (p *DBWorkerPool) RegisterMetrics(reg *metrics.Registry) {
+	reg.NewGaugeFunc("worker_pool_queue_depth", "Number of submitted tasks waiting for a free worker.",
+		func() float64 { return float64(p.queued.Load()) })
+	reg.NewGaugeFunc("worker_pool_busy_workers", "Number of workers executing a task.",
+		func() float64 { return float64(p.busy.Load()) })
+	reg.NewGaugeFunc("worker_pool_workers", "Number of workers in the pool.",
+		func() float64 { return float64(p.maxWorkers) })
+	reg.NewCounterFunc("worker_pool_task_failures_total", "Number of tasks that returned an error.",
+		func() float64 { return float64(p.failures.Load()) })
 }
 
 // Shutdown gracefully stops the worker pool. It closes the taskQueue and waits for all workers to finish.
+// Tasks still running after config.WorkerShutdownTimeout have their context cancelled.
 func (p *DBWorkerPool) Shutdown() {
 	close(p.taskQueue)
-	p.wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.shutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		p.cancel()
+		<-done
+	}
+	p.cancel()
 }
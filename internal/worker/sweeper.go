@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/gleb-korostelev/short-url.git/internal/storage"
+	"github.com/gleb-korostelev/short-url.git/tools/logger"
+)
+
+// ExpirySweeper periodically marks links whose expiration time has passed as deleted.
+// Each sweep is submitted to a DBWorkerPool so it shares the concurrency limits of other storage tasks.
+type ExpirySweeper struct {
+	pool     *DBWorkerPool   // pool executes the sweep tasks.
+	store    storage.Storage // store is the storage whose expired links are swept.
+	interval time.Duration   // interval is the time between two consecutive sweeps.
+}
+
+// NewExpirySweeper creates an ExpirySweeper that sweeps store every interval using pool.
+func NewExpirySweeper(pool *DBWorkerPool, store storage.Storage, interval time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{
+		pool:     pool,
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Run sweeps expired links every interval until ctx is cancelled.
+// It blocks, so it is typically started in its own goroutine.
+func (s *ExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.pool.AddTask(Task{Action: s.sweep, Ctx: ctx})
+		}
+	}
+}
+
+// sweep marks expired links as deleted once and logs the outcome.
+func (s *ExpirySweeper) sweep(ctx context.Context) error {
+	marked, err := s.store.MarkExpiredURLsAsDeleted(ctx)
+	if err != nil {
+		logger.Errorf("Error sweeping expired URLs: %v", err)
+		return err
+	}
+	if marked > 0 {
+		logger.Infof("%d expired URLs were marked as deleted.", marked)
+	}
+	return nil
+}
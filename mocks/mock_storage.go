@@ -7,6 +7,7 @@ package mock_db
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/gleb-korostelev/short-url.git/internal/models"
 	gomock "github.com/golang/mock/gomock"
@@ -49,6 +50,50 @@ func (mr *MockStorageMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStorage)(nil).Close))
 }
 
+// CountURLs mocks base method.
+func (m *MockStorage) CountURLs(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountURLs", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountURLs indicates an expected call of CountURLs.
+func (mr *MockStorageMockRecorder) CountURLs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountURLs", reflect.TypeOf((*MockStorage)(nil).CountURLs), ctx)
+}
+
+// CountUsers mocks base method.
+func (m *MockStorage) CountUsers(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsers indicates an expected call of CountUsers.
+func (mr *MockStorageMockRecorder) CountUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockStorage)(nil).CountUsers), ctx)
+}
+
+// ExportURLs mocks base method.
+func (m *MockStorage) ExportURLs(ctx context.Context, fn func(models.URLData) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportURLs", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportURLs indicates an expected call of ExportURLs.
+func (mr *MockStorageMockRecorder) ExportURLs(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportURLs", reflect.TypeOf((*MockStorage)(nil).ExportURLs), ctx, fn)
+}
+
 // GetAllURLS mocks base method.
 func (m *MockStorage) GetAllURLS(ctx context.Context, userID, baseURL string) ([]models.UserURLs, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +109,22 @@ func (mr *MockStorageMockRecorder) GetAllURLS(ctx, userID, baseURL interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllURLS", reflect.TypeOf((*MockStorage)(nil).GetAllURLS), ctx, userID, baseURL)
 }
 
+// GetExpiringLink mocks base method.
+func (m *MockStorage) GetExpiringLink(ctx context.Context, shortURL string) (string, *time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiringLink", ctx, shortURL)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetExpiringLink indicates an expected call of GetExpiringLink.
+func (mr *MockStorageMockRecorder) GetExpiringLink(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiringLink", reflect.TypeOf((*MockStorage)(nil).GetExpiringLink), ctx, shortURL)
+}
+
 // GetOriginalLink mocks base method.
 func (m *MockStorage) GetOriginalLink(ctx context.Context, shortURL string) (string, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +140,66 @@ func (mr *MockStorageMockRecorder) GetOriginalLink(ctx, shortURL interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOriginalLink", reflect.TypeOf((*MockStorage)(nil).GetOriginalLink), ctx, shortURL)
 }
 
+// GetProtectedLink mocks base method.
+func (m *MockStorage) GetProtectedLink(ctx context.Context, shortURL string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProtectedLink", ctx, shortURL)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetProtectedLink indicates an expected call of GetProtectedLink.
+func (mr *MockStorageMockRecorder) GetProtectedLink(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProtectedLink", reflect.TypeOf((*MockStorage)(nil).GetProtectedLink), ctx, shortURL)
+}
+
+// GetURLStats mocks base method.
+func (m *MockStorage) GetURLStats(ctx context.Context, userID, shortURL string) (models.URLStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURLStats", ctx, userID, shortURL)
+	ret0, _ := ret[0].(models.URLStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetURLStats indicates an expected call of GetURLStats.
+func (mr *MockStorageMockRecorder) GetURLStats(ctx, userID, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLStats", reflect.TypeOf((*MockStorage)(nil).GetURLStats), ctx, userID, shortURL)
+}
+
+// ImportURL mocks base method.
+func (m *MockStorage) ImportURL(ctx context.Context, data models.URLData) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportURL", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportURL indicates an expected call of ImportURL.
+func (mr *MockStorageMockRecorder) ImportURL(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportURL", reflect.TypeOf((*MockStorage)(nil).ImportURL), ctx, data)
+}
+
+// MarkExpiredURLsAsDeleted mocks base method.
+func (m *MockStorage) MarkExpiredURLsAsDeleted(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkExpiredURLsAsDeleted", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkExpiredURLsAsDeleted indicates an expected call of MarkExpiredURLsAsDeleted.
+func (mr *MockStorageMockRecorder) MarkExpiredURLsAsDeleted(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkExpiredURLsAsDeleted", reflect.TypeOf((*MockStorage)(nil).MarkExpiredURLsAsDeleted), ctx)
+}
+
 // MarkURLsAsDeleted mocks base method.
 func (m *MockStorage) MarkURLsAsDeleted(ctx context.Context, userID string, shortURLs []string) error {
 	m.ctrl.T.Helper()
@@ -93,6 +214,20 @@ func (mr *MockStorageMockRecorder) MarkURLsAsDeleted(ctx, userID, shortURLs inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkURLsAsDeleted", reflect.TypeOf((*MockStorage)(nil).MarkURLsAsDeleted), ctx, userID, shortURLs)
 }
 
+// MarkURLsAsDeletedBatch mocks base method.
+func (m *MockStorage) MarkURLsAsDeletedBatch(ctx context.Context, deletions []models.URLDeletion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkURLsAsDeletedBatch", ctx, deletions)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkURLsAsDeletedBatch indicates an expected call of MarkURLsAsDeletedBatch.
+func (mr *MockStorageMockRecorder) MarkURLsAsDeletedBatch(ctx, deletions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkURLsAsDeletedBatch", reflect.TypeOf((*MockStorage)(nil).MarkURLsAsDeletedBatch), ctx, deletions)
+}
+
 // Ping mocks base method.
 func (m *MockStorage) Ping(ctx context.Context) (int, error) {
 	m.ctrl.T.Helper()
@@ -108,25 +243,54 @@ func (mr *MockStorageMockRecorder) Ping(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping), ctx)
 }
 
+// SaveClicks mocks base method.
+func (m *MockStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveClicks", ctx, clicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveClicks indicates an expected call of SaveClicks.
+func (mr *MockStorageMockRecorder) SaveClicks(ctx, clicks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveClicks", reflect.TypeOf((*MockStorage)(nil).SaveClicks), ctx, clicks)
+}
+
 // SaveURL mocks base method.
-func (m *MockStorage) SaveURL(ctx context.Context, originalURL, userID string) (string, error) {
+func (m *MockStorage) SaveURL(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveURL", ctx, originalURL, userID)
+	ret := m.ctrl.Call(m, "SaveURL", ctx, originalURL, userID, opts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveURL indicates an expected call of SaveURL.
-func (mr *MockStorageMockRecorder) SaveURL(ctx, originalURL, userID interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) SaveURL(ctx, originalURL, userID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockStorage)(nil).SaveURL), ctx, originalURL, userID, opts)
+}
+
+// SaveURLsBatch mocks base method.
+func (m *MockStorage) SaveURLsBatch(ctx context.Context, userID string, items []models.BatchURL, atomic bool) ([]models.BatchURLResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveURLsBatch", ctx, userID, items, atomic)
+	ret0, _ := ret[0].([]models.BatchURLResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveURLsBatch indicates an expected call of SaveURLsBatch.
+func (mr *MockStorageMockRecorder) SaveURLsBatch(ctx, userID, items, atomic interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockStorage)(nil).SaveURL), ctx, originalURL, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURLsBatch", reflect.TypeOf((*MockStorage)(nil).SaveURLsBatch), ctx, userID, items, atomic)
 }
 
 // SaveUniqueURL mocks base method.
-func (m *MockStorage) SaveUniqueURL(ctx context.Context, originalURL, userID string) (string, int, error) {
+func (m *MockStorage) SaveUniqueURL(ctx context.Context, originalURL, userID string, opts models.ShortenOptions) (string, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveUniqueURL", ctx, originalURL, userID)
+	ret := m.ctrl.Call(m, "SaveUniqueURL", ctx, originalURL, userID, opts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(int)
 	ret2, _ := ret[2].(error)
@@ -134,7 +298,7 @@ func (m *MockStorage) SaveUniqueURL(ctx context.Context, originalURL, userID str
 }
 
 // SaveUniqueURL indicates an expected call of SaveUniqueURL.
-func (mr *MockStorageMockRecorder) SaveUniqueURL(ctx, originalURL, userID interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) SaveUniqueURL(ctx, originalURL, userID, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUniqueURL", reflect.TypeOf((*MockStorage)(nil).SaveUniqueURL), ctx, originalURL, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUniqueURL", reflect.TypeOf((*MockStorage)(nil).SaveUniqueURL), ctx, originalURL, userID, opts)
 }